@@ -17,6 +17,8 @@ limitations under the License.
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
+	nodev1 "k8s.io/api/node/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -29,6 +31,10 @@ type ShimSpec struct {
 	// ContainerdRuntimeOptions is a map of containerd runtime options for the shim plugin.
 	// See an example of configuring cgroup driver via runtime options: https://github.com/containerd/containerd/blob/main/docs/cri/config.md#cgroup-driver
 	ContainerdRuntimeOptions map[string]string `json:"containerdRuntimeOptions,omitempty"`
+	// UninstallPolicy decides how to uninstall the shim from a node on which
+	// pods still run with its RuntimeClass. Defaults to wait.
+	// +optional
+	UninstallPolicy UninstallPolicy `json:"uninstallPolicy,omitempty"`
 }
 
 type FetchStrategy struct {
@@ -42,20 +48,64 @@ type FetchStrategy struct {
 
 	// AnonHTTP fetches a binary from a public HTTP(S) URL.
 	// For backward compatibility with single-architecture deployments.
-	// When Platforms is also specified, Platforms takes precedence.
+	// When Platforms or OCI is also specified, they take precedence.
 	// +optional
 	AnonHTTP *AnonHTTPSpec `json:"anonHttp,omitempty"`
 
 	// Platforms lists per-OS/architecture artifact sources.
 	// The controller selects the matching entry for each target node.
-	// When specified, this takes precedence over AnonHTTP.
+	// When specified, this takes precedence over OCI and AnonHTTP.
 	// +optional
 	Platforms []PlatformArtifact `json:"platforms,omitempty"`
+
+	// OCI fetches the shim from an image or artifact in an OCI registry.
+	// When specified, this takes precedence over AnonHTTP.
+	// +optional
+	OCI *OCISpec `json:"oci,omitempty"`
+
+	// HTTPAuth configures authentication for fetching AnonHTTP and Platforms
+	// artifacts.
+	// +optional
+	HTTPAuth *HTTPAuthSpec `json:"httpAuth,omitempty"`
+}
+
+// HTTPAuthSpec references the credentials used to fetch artifacts over HTTP(S).
+type HTTPAuthSpec struct {
+	// SecretRef references a Secret in the namespace of runtime-class-manager.
+	// It is mounted into the downloader, which uses the following keys if present:
+	//   - "username" and "password" for basic authentication
+	//   - "token" for bearer token authentication
+	//   - "headers" for custom request headers, one "Name: value" per line
+	//   - "tls.crt" and "tls.key" for a client TLS certificate
+	//   - "ca.crt" for a CA bundle used to verify the server certificate
+	SecretRef corev1.LocalObjectReference `json:"secretRef"`
+}
+
+// OCISpec fetches the shim binary from an image or artifact in an OCI registry.
+// If the reference points to a multi-platform index, the manifest matching the
+// OS and architecture of each node is used. The shim binary is taken from the
+// layer titled "containerd-shim-*" (org.opencontainers.image.title annotation),
+// or from the only layer of the manifest. Image layers are searched for a file
+// named "containerd-shim-*".
+type OCISpec struct {
+	// Reference is the image or artifact reference, e.g.
+	// "registry.example.com/shims/spin:v0.23.0". It can be pinned by digest,
+	// e.g. "registry.example.com/shims/spin@sha256:...", in which case the
+	// fetched index or manifest is verified against the digest. The reference
+	// is not resolved to a digest before it is compared with the one installed
+	// on a node, so only changes to the reference itself are rolled out. Pin
+	// references by digest to roll out new versions; pushing a new image to a
+	// tag that is already installed, e.g. "latest", has no effect.
+	Reference string `json:"reference"`
+	// PlainHTTP connects to the registry over HTTP instead of HTTPS.
+	// +optional
+	PlainHTTP bool `json:"plainHttp,omitempty"`
 }
 
 // AnonHTTPSpec defines a simple anonymous HTTP fetch (single URL, single architecture).
 type AnonHTTPSpec struct {
 	// Location is the direct URL to the artifact archive.
+	// It must be publicly accessible unless FetchStrategy.HTTPAuth is set.
 	Location string `json:"location"`
 }
 
@@ -68,7 +118,8 @@ type PlatformArtifact struct {
 	// Accepts Go-style ("amd64", "arm64") or uname-style ("x86_64", "aarch64").
 	// +kubebuilder:validation:Enum=amd64;arm64;x86_64;aarch64
 	Arch string `json:"arch"`
-	// Location is the URL to the artifact archive for this platform. Must be publicly accessible.
+	// Location is the URL to the artifact archive for this platform.
+	// It must be publicly accessible unless FetchStrategy.HTTPAuth is set.
 	Location string `json:"location"`
 	// SHA256 is the optional hex-encoded SHA-256 digest for verification.
 	// +optional
@@ -78,8 +129,40 @@ type PlatformArtifact struct {
 type RuntimeClassSpec struct {
 	Name    string `json:"name"`
 	Handler string `json:"handler"`
+	// NodeSelection decides onto which nodes pods using the RuntimeClass are
+	// scheduled. Defaults to nodeSelector.
+	// +optional
+	NodeSelection NodeSelection `json:"nodeSelection,omitempty"`
+	// Overhead is the resource overhead of running a pod with the
+	// RuntimeClass, which is added to the resource requests of the pod for
+	// scheduling and quota accounting.
+	// +optional
+	Overhead *nodev1.Overhead `json:"overhead,omitempty"`
+	// Tolerations are added to pods using the RuntimeClass, e.g. to schedule
+	// them onto nodes that are tainted for Wasm workloads.
+	// +optional
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+	// Labels are added to the RuntimeClass.
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+	// Annotations are added to the RuntimeClass.
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
 }
 
+// +kubebuilder:validation:Enum=nodeSelector;provisioned
+type NodeSelection string
+
+const (
+	// NodeSelectionNodeSelector schedules pods onto all nodes matching the
+	// node selector of the Shim, whether the shim is installed on them or not.
+	NodeSelectionNodeSelector NodeSelection = "nodeSelector"
+	// NodeSelectionProvisioned only schedules pods onto nodes matching the
+	// node selector of the Shim on which the shim has been provisioned, i.e.
+	// whose "<shim-name>" label is "provisioned".
+	NodeSelectionProvisioned NodeSelection = "provisioned"
+)
+
 // +kubebuilder:validation:Enum=rolling;recreate
 type RolloutStrategyType string
 
@@ -94,22 +177,96 @@ type RolloutStrategy struct {
 }
 
 type RollingSpec struct {
+	// MaxUpdate is the maximum number of nodes the shim is installed on at a time.
+	// The next batch of nodes is only started once all nodes of the current batch
+	// have been provisioned. Must be at least 1 with the rolling strategy; when
+	// the validating webhook is disabled, values below 1 are treated as 1.
 	MaxUpdate int `json:"maxUpdate"`
 }
 
+// +kubebuilder:validation:Enum=wait;evict;force
+type UninstallPolicy string
+
+const (
+	// UninstallPolicyWait waits until no pod on the node uses the RuntimeClass anymore.
+	UninstallPolicyWait UninstallPolicy = "wait"
+	// UninstallPolicyEvict evicts the pods on the node that use the RuntimeClass,
+	// and waits until they are gone.
+	UninstallPolicyEvict UninstallPolicy = "evict"
+	// UninstallPolicyForce uninstalls the shim regardless of the pods on the node.
+	UninstallPolicyForce UninstallPolicy = "force"
+)
+
+// Condition types reported in ShimStatus.Conditions.
+const (
+	// ConditionTypeReady is True when the shim is provisioned with its current
+	// configuration on all nodes it targets.
+	ConditionTypeReady = "Ready"
+	// ConditionTypeProgressing is True while the shim is being rolled out to or
+	// removed from nodes.
+	ConditionTypeProgressing = "Progressing"
+	// ConditionTypeDegraded is True when installing the shim failed on at least
+	// one node.
+	ConditionTypeDegraded = "Degraded"
+	// ConditionTypeBlocked is True while uninstalling the shim from at least
+	// one node waits for pods that still use its RuntimeClass.
+	ConditionTypeBlocked = "Blocked"
+)
+
+// +kubebuilder:validation:Enum=pending;provisioned;failed;uninstalling
+type NodePhase string
+
+const (
+	NodePhasePending      NodePhase = "pending"
+	NodePhaseProvisioned  NodePhase = "provisioned"
+	NodePhaseFailed       NodePhase = "failed"
+	NodePhaseUninstalling NodePhase = "uninstalling"
+)
+
+// NodeStatus describes the installation status of a shim on a single node.
+type NodeStatus struct {
+	// Name is the name of the node.
+	Name string `json:"name"`
+	// Phase is the installation phase of the shim on the node.
+	Phase NodePhase `json:"phase"`
+	// ArtifactDigest is the SHA-256 digest of the artifact last installed on
+	// the node, if known.
+	// +optional
+	ArtifactDigest string `json:"artifactDigest,omitempty"`
+	// LastJobName is the name of the last Job deployed to the node for this shim.
+	// +optional
+	LastJobName string `json:"lastJobName,omitempty"`
+	// LastTransitionTime is the last time the phase changed.
+	// +optional
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+	// Reason is a machine-readable reason why the last operation on the node
+	// failed, e.g. ChecksumMismatch, ConfigNotFound, RestartFailed or
+	// NoPlatformMatch. It is cleared when a new Job is deployed to the node.
+	// +optional
+	Reason string `json:"reason,omitempty"`
+	// Message is a human-readable description of the failure.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
 // ShimStatus defines the observed state of Shim
 // +operator-sdk:csv:customresourcedefinitions:type=status
 type ShimStatus struct {
 	Conditions     []metav1.Condition `json:"conditions,omitempty"`
 	NodeCount      int                `json:"nodes"`
 	NodeReadyCount int                `json:"nodesReady"`
+	// NodeStatuses lists the installation status of the shim on each node it targets.
+	// +optional
+	NodeStatuses []NodeStatus `json:"nodeStatuses,omitempty"`
 }
 
 // +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
 // +kubebuilder:resource:path=shims,scope=Cluster
 // +kubebuilder:printcolumn:JSONPath=".spec.runtimeClass.name",name=RuntimeClass,type=string
 // +kubebuilder:printcolumn:JSONPath=".status.nodesReady",name=Ready,type=integer
 // +kubebuilder:printcolumn:JSONPath=".status.nodes",name=Nodes,type=integer
+// +kubebuilder:printcolumn:JSONPath=".status.conditions[?(@.type==\"Ready\")].reason",name=Status,type=string
 // Shim is the Schema for the shims API
 type Shim struct {
 	metav1.TypeMeta   `json:",inline"`
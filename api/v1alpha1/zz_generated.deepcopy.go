@@ -21,7 +21,9 @@ limitations under the License.
 package v1alpha1
 
 import (
-	"k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/api/node/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
@@ -53,6 +55,16 @@ func (in *FetchStrategy) DeepCopyInto(out *FetchStrategy) {
 		*out = make([]PlatformArtifact, len(*in))
 		copy(*out, *in)
 	}
+	if in.OCI != nil {
+		in, out := &in.OCI, &out.OCI
+		*out = new(OCISpec)
+		**out = **in
+	}
+	if in.HTTPAuth != nil {
+		in, out := &in.HTTPAuth, &out.HTTPAuth
+		*out = new(HTTPAuthSpec)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FetchStrategy.
@@ -65,6 +77,53 @@ func (in *FetchStrategy) DeepCopy() *FetchStrategy {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HTTPAuthSpec) DeepCopyInto(out *HTTPAuthSpec) {
+	*out = *in
+	out.SecretRef = in.SecretRef
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HTTPAuthSpec.
+func (in *HTTPAuthSpec) DeepCopy() *HTTPAuthSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(HTTPAuthSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeStatus) DeepCopyInto(out *NodeStatus) {
+	*out = *in
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeStatus.
+func (in *NodeStatus) DeepCopy() *NodeStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OCISpec) DeepCopyInto(out *OCISpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OCISpec.
+func (in *OCISpec) DeepCopy() *OCISpec {
+	if in == nil {
+		return nil
+	}
+	out := new(OCISpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PlatformArtifact) DeepCopyInto(out *PlatformArtifact) {
 	*out = *in
@@ -114,6 +173,32 @@ func (in *RolloutStrategy) DeepCopy() *RolloutStrategy {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RuntimeClassSpec) DeepCopyInto(out *RuntimeClassSpec) {
 	*out = *in
+	if in.Overhead != nil {
+		in, out := &in.Overhead, &out.Overhead
+		*out = new(v1.Overhead)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Tolerations != nil {
+		in, out := &in.Tolerations, &out.Tolerations
+		*out = make([]corev1.Toleration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RuntimeClassSpec.
@@ -196,7 +281,7 @@ func (in *ShimSpec) DeepCopyInto(out *ShimSpec) {
 		}
 	}
 	in.FetchStrategy.DeepCopyInto(&out.FetchStrategy)
-	out.RuntimeClass = in.RuntimeClass
+	in.RuntimeClass.DeepCopyInto(&out.RuntimeClass)
 	out.RolloutStrategy = in.RolloutStrategy
 	if in.ContainerdRuntimeOptions != nil {
 		in, out := &in.ContainerdRuntimeOptions, &out.ContainerdRuntimeOptions
@@ -222,7 +307,14 @@ func (in *ShimStatus) DeepCopyInto(out *ShimStatus) {
 	*out = *in
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
-		*out = make([]v1.Condition, len(*in))
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.NodeStatuses != nil {
+		in, out := &in.NodeStatuses, &out.NodeStatuses
+		*out = make([]NodeStatus, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
@@ -16,6 +16,8 @@
 
 package main
 
+import "time"
+
 type Config struct {
 	Runtime struct {
 		Name       string
@@ -24,6 +26,19 @@ type Config struct {
 		// See an example of the cgroup drive option here:
 		// https://github.com/containerd/containerd/blob/main/docs/cri/config.md#cgroup-driver
 		Options map[string]string
+		// Type is the container runtime to configure, which is detected if
+		// left empty.
+		Type string
+		// ConfigMode is how runtimes are added to the containerd config,
+		// either inline or in drop-in files in DropInDir.
+		ConfigMode string
+		DropInDir  string
+		// SocketPath is the path the socket of the container runtime is
+		// reachable at. After a restart, it is probed for HealthTimeout and
+		// the runtime handlers of the shims are verified over CRI. Both are
+		// disabled if either is unset.
+		SocketPath    string
+		HealthTimeout time.Duration
 	}
 	RCM struct {
 		Path      string
@@ -32,4 +47,14 @@ type Config struct {
 	Host struct {
 		RootPath string
 	}
+	// DryRun makes install and uninstall report the changes they would make
+	// to the host instead of making them.
+	DryRun bool
+	// GarbageCollect makes uninstall also remove the previous versions of
+	// the shim that are kept for rollbacks.
+	GarbageCollect bool
+	// SkipHandlerVerification makes install skip verifying the runtime
+	// handlers of the shims over CRI, for container runtimes that don't
+	// report them.
+	SkipHandlerVerification bool
 }
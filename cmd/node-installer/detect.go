@@ -25,7 +25,10 @@ import (
 	"github.com/spinframework/runtime-class-manager/internal/preset"
 )
 
-const defaultContainerdConfigLocation = "/etc/containerd/config.toml"
+const (
+	defaultContainerdConfigLocation = "/etc/containerd/config.toml"
+	crioConfigLocation              = "/etc/crio"
+)
 
 var containerdConfigLocations = map[string]preset.Settings{
 	// Microk8s
@@ -39,6 +42,17 @@ var containerdConfigLocations = map[string]preset.Settings{
 }
 
 func DetectDistro(config Config, hostFs afero.Fs) (preset.Settings, error) {
+	switch config.Runtime.Type {
+	case "", preset.ContainerRuntimeContainerd:
+	case preset.ContainerRuntimeCRIO:
+		if config.Runtime.ConfigPath != "" {
+			return preset.CRIO.WithConfigPath(config.Runtime.ConfigPath), nil
+		}
+		return preset.CRIO, nil
+	default:
+		return preset.Settings{}, fmt.Errorf("unsupported container runtime %q", config.Runtime.Type)
+	}
+
 	if config.Runtime.ConfigPath != "" {
 		// containerd config path has been set explicitly
 		if distro, ok := containerdConfigLocations[config.Runtime.ConfigPath]; ok {
@@ -68,5 +82,14 @@ func DetectDistro(config Config, hostFs afero.Fs) (preset.Settings, error) {
 	}
 	errs = append(errs, err)
 
-	return preset.Settings{}, fmt.Errorf("failed to detect containerd config path: %w", errors.Join(errs...))
+	// Fall back to CRI-O if the container runtime hasn't been set explicitly.
+	if config.Runtime.Type == "" {
+		_, err := hostFs.Stat(crioConfigLocation)
+		if err == nil {
+			return preset.CRIO, nil
+		}
+		errs = append(errs, err)
+	}
+
+	return preset.Settings{}, fmt.Errorf("failed to detect container runtime config path: %w", errors.Join(errs...))
 }
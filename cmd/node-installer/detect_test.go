@@ -19,6 +19,7 @@ package main_test
 import (
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/spf13/afero"
 	main "github.com/spinframework/runtime-class-manager/cmd/node-installer"
@@ -43,15 +44,23 @@ func Test_DetectDistro(t *testing.T) {
 			args{
 				main.Config{
 					struct {
-						Name       string
-						ConfigPath string
-						Options    map[string]string
-					}{"containerd", preset.MicroK8s.ConfigPath, nil},
+						Name          string
+						ConfigPath    string
+						Options       map[string]string
+						Type          string
+						ConfigMode    string
+						DropInDir     string
+						SocketPath    string
+						HealthTimeout time.Duration
+					}{"containerd", preset.MicroK8s.ConfigPath, nil, "", "", "", "", 0},
 					struct {
 						Path      string
 						AssetPath string
 					}{"/opt/rcm", "/assets"},
 					struct{ RootPath string }{""},
+					false,
+					false,
+					false,
 				},
 				tests.FixtureFs("../../testdata/node-installer/distros/default"),
 			},
@@ -63,15 +72,23 @@ func Test_DetectDistro(t *testing.T) {
 			args{
 				main.Config{
 					struct {
-						Name       string
-						ConfigPath string
-						Options    map[string]string
-					}{"containerd", "/etc/containerd/not_found.toml", nil},
+						Name          string
+						ConfigPath    string
+						Options       map[string]string
+						Type          string
+						ConfigMode    string
+						DropInDir     string
+						SocketPath    string
+						HealthTimeout time.Duration
+					}{"containerd", "/etc/containerd/not_found.toml", nil, "", "", "", "", 0},
 					struct {
 						Path      string
 						AssetPath string
 					}{"/opt/rcm", "/assets"},
 					struct{ RootPath string }{""},
+					false,
+					false,
+					false,
 				},
 				tests.FixtureFs("../../testdata/node-installer/distros/default"),
 			},
@@ -83,15 +100,23 @@ func Test_DetectDistro(t *testing.T) {
 			args{
 				main.Config{
 					struct {
-						Name       string
-						ConfigPath string
-						Options    map[string]string
-					}{"containerd", "", nil},
+						Name          string
+						ConfigPath    string
+						Options       map[string]string
+						Type          string
+						ConfigMode    string
+						DropInDir     string
+						SocketPath    string
+						HealthTimeout time.Duration
+					}{"containerd", "", nil, "", "", "", "", 0},
 					struct {
 						Path      string
 						AssetPath string
 					}{"/opt/rcm", "/assets"},
 					struct{ RootPath string }{""},
+					false,
+					false,
+					false,
 				},
 				tests.FixtureFs("../../testdata/node-installer/containerd/default-and-k0s-configs"),
 			},
@@ -103,15 +128,23 @@ func Test_DetectDistro(t *testing.T) {
 			args{
 				main.Config{
 					struct {
-						Name       string
-						ConfigPath string
-						Options    map[string]string
-					}{"containerd", "", nil},
+						Name          string
+						ConfigPath    string
+						Options       map[string]string
+						Type          string
+						ConfigMode    string
+						DropInDir     string
+						SocketPath    string
+						HealthTimeout time.Duration
+					}{"containerd", "", nil, "", "", "", "", 0},
 					struct {
 						Path      string
 						AssetPath string
 					}{"/opt/rcm", "/assets"},
 					struct{ RootPath string }{""},
+					false,
+					false,
+					false,
 				},
 				tests.FixtureFs("../../testdata/node-installer/distros/unsupported"),
 			},
@@ -123,15 +156,23 @@ func Test_DetectDistro(t *testing.T) {
 			args{
 				main.Config{
 					struct {
-						Name       string
-						ConfigPath string
-						Options    map[string]string
-					}{"containerd", "", nil},
+						Name          string
+						ConfigPath    string
+						Options       map[string]string
+						Type          string
+						ConfigMode    string
+						DropInDir     string
+						SocketPath    string
+						HealthTimeout time.Duration
+					}{"containerd", "", nil, "", "", "", "", 0},
 					struct {
 						Path      string
 						AssetPath string
 					}{"/opt/rcm", "/assets"},
 					struct{ RootPath string }{""},
+					false,
+					false,
+					false,
 				},
 				tests.FixtureFs("../../testdata/node-installer/distros/microk8s"),
 			},
@@ -143,15 +184,23 @@ func Test_DetectDistro(t *testing.T) {
 			args{
 				main.Config{
 					struct {
-						Name       string
-						ConfigPath string
-						Options    map[string]string
-					}{"containerd", "", nil},
+						Name          string
+						ConfigPath    string
+						Options       map[string]string
+						Type          string
+						ConfigMode    string
+						DropInDir     string
+						SocketPath    string
+						HealthTimeout time.Duration
+					}{"containerd", "", nil, "", "", "", "", 0},
 					struct {
 						Path      string
 						AssetPath string
 					}{"/opt/rcm", "/assets"},
 					struct{ RootPath string }{""},
+					false,
+					false,
+					false,
 				},
 				tests.FixtureFs("../../testdata/node-installer/distros/k0s"),
 			},
@@ -163,15 +212,23 @@ func Test_DetectDistro(t *testing.T) {
 			args{
 				main.Config{
 					struct {
-						Name       string
-						ConfigPath string
-						Options    map[string]string
-					}{"containerd", "", nil},
+						Name          string
+						ConfigPath    string
+						Options       map[string]string
+						Type          string
+						ConfigMode    string
+						DropInDir     string
+						SocketPath    string
+						HealthTimeout time.Duration
+					}{"containerd", "", nil, "", "", "", "", 0},
 					struct {
 						Path      string
 						AssetPath string
 					}{"/opt/rcm", "/assets"},
 					struct{ RootPath string }{""},
+					false,
+					false,
+					false,
 				},
 				tests.FixtureFs("../../testdata/node-installer/distros/k3s"),
 			},
@@ -183,21 +240,169 @@ func Test_DetectDistro(t *testing.T) {
 			args{
 				main.Config{
 					struct {
-						Name       string
-						ConfigPath string
-						Options    map[string]string
-					}{"containerd", "", nil},
+						Name          string
+						ConfigPath    string
+						Options       map[string]string
+						Type          string
+						ConfigMode    string
+						DropInDir     string
+						SocketPath    string
+						HealthTimeout time.Duration
+					}{"containerd", "", nil, "", "", "", "", 0},
 					struct {
 						Path      string
 						AssetPath string
 					}{"/opt/rcm", "/assets"},
 					struct{ RootPath string }{""},
+					false,
+					false,
+					false,
 				},
 				tests.FixtureFs("../../testdata/node-installer/distros/rke2"),
 			},
 			false,
 			preset.RKE2,
 		},
+		{
+			"crio",
+			args{
+				main.Config{
+					struct {
+						Name          string
+						ConfigPath    string
+						Options       map[string]string
+						Type          string
+						ConfigMode    string
+						DropInDir     string
+						SocketPath    string
+						HealthTimeout time.Duration
+					}{"containerd", "", nil, "", "", "", "", 0},
+					struct {
+						Path      string
+						AssetPath string
+					}{"/opt/rcm", "/assets"},
+					struct{ RootPath string }{""},
+					false,
+					false,
+					false,
+				},
+				tests.FixtureFs("../../testdata/node-installer/distros/crio"),
+			},
+			false,
+			preset.CRIO,
+		},
+		{
+			"crio_explicit",
+			args{
+				main.Config{
+					struct {
+						Name          string
+						ConfigPath    string
+						Options       map[string]string
+						Type          string
+						ConfigMode    string
+						DropInDir     string
+						SocketPath    string
+						HealthTimeout time.Duration
+					}{"containerd", "", nil, "cri-o", "", "", "", 0},
+					struct {
+						Path      string
+						AssetPath string
+					}{"/opt/rcm", "/assets"},
+					struct{ RootPath string }{""},
+					false,
+					false,
+					false,
+				},
+				tests.FixtureFs("../../testdata/node-installer/distros/default"),
+			},
+			false,
+			preset.CRIO,
+		},
+		{
+			"crio_config_override",
+			args{
+				main.Config{
+					struct {
+						Name          string
+						ConfigPath    string
+						Options       map[string]string
+						Type          string
+						ConfigMode    string
+						DropInDir     string
+						SocketPath    string
+						HealthTimeout time.Duration
+					}{"containerd", "/etc/crio/custom.d", nil, "cri-o", "", "", "", 0},
+					struct {
+						Path      string
+						AssetPath string
+					}{"/opt/rcm", "/assets"},
+					struct{ RootPath string }{""},
+					false,
+					false,
+					false,
+				},
+				tests.FixtureFs("../../testdata/node-installer/distros/default"),
+			},
+			false,
+			preset.CRIO.WithConfigPath("/etc/crio/custom.d"),
+		},
+		{
+			"containerd_explicit_no_crio_fallback",
+			args{
+				main.Config{
+					struct {
+						Name          string
+						ConfigPath    string
+						Options       map[string]string
+						Type          string
+						ConfigMode    string
+						DropInDir     string
+						SocketPath    string
+						HealthTimeout time.Duration
+					}{"containerd", "", nil, "containerd", "", "", "", 0},
+					struct {
+						Path      string
+						AssetPath string
+					}{"/opt/rcm", "/assets"},
+					struct{ RootPath string }{""},
+					false,
+					false,
+					false,
+				},
+				tests.FixtureFs("../../testdata/node-installer/distros/crio"),
+			},
+			true,
+			preset.Default,
+		},
+		{
+			"unsupported_container_runtime",
+			args{
+				main.Config{
+					struct {
+						Name          string
+						ConfigPath    string
+						Options       map[string]string
+						Type          string
+						ConfigMode    string
+						DropInDir     string
+						SocketPath    string
+						HealthTimeout time.Duration
+					}{"containerd", "", nil, "docker", "", "", "", 0},
+					struct {
+						Path      string
+						AssetPath string
+					}{"/opt/rcm", "/assets"},
+					struct{ RootPath string }{""},
+					false,
+					false,
+					false,
+				},
+				tests.FixtureFs("../../testdata/node-installer/distros/default"),
+			},
+			true,
+			preset.Default,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -206,6 +411,7 @@ func Test_DetectDistro(t *testing.T) {
 				require.Error(t, err)
 			} else {
 				require.NoError(t, err)
+				require.Equal(t, tt.wantPreset.ContainerRuntime, preset.ContainerRuntime)
 				require.Equal(t, tt.wantPreset.ConfigPath, preset.ConfigPath)
 				require.Equal(t, reflect.ValueOf(tt.wantPreset.Setup), reflect.ValueOf(preset.Setup))
 				require.Equal(t, reflect.ValueOf(tt.wantPreset.Restarter), reflect.ValueOf(preset.Restarter))
@@ -0,0 +1,140 @@
+/*
+   Copyright The KWasm Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spinframework/runtime-class-manager/internal/download"
+	"github.com/spinframework/runtime-class-manager/internal/termination"
+)
+
+// Exit codes of the download command, which tell apart why a download failed.
+const (
+	exitCodeDownloadFailed = 1
+	exitCodeNetworkError   = 2
+	exitCodeChecksumError  = 3
+	exitCodeArchiveError   = 4
+)
+
+type DownloadOptions struct {
+	Location   string
+	SHA256     string
+	Output     string
+	AuthDir    string
+	Retries    int
+	RetryDelay time.Duration
+}
+
+var downloadOptions DownloadOptions
+
+// downloadCmd represents the download command.
+var downloadCmd = &cobra.Command{
+	Use:   "download",
+	Short: "Download a containerd shim over HTTP(S)",
+	Long: `Download a containerd shim over HTTP(S) and verify its SHA-256 digest.
+
+The artifact may be the shim binary itself or a tar, tar.gz, tar.zst or zip
+archive containing it. The flags default to the environment variables set on
+the downloader container of install Jobs.`,
+	Run: func(cmd *cobra.Command, _ []string) {
+		if err := RunDownload(cmd.Context(), downloadOptions); err != nil {
+			code, reason := downloadFailure(err)
+			fail(code, reason, "failed to download shim", err, "location", downloadOptions.Location)
+		}
+	},
+}
+
+func init() {
+	output := ""
+	if name := os.Getenv("SHIM_NAME"); name != "" {
+		output = "/assets/containerd-shim-" + name
+	}
+	retries, err := strconv.Atoi(os.Getenv("NUM_RETRY"))
+	if err != nil {
+		retries = 3
+	}
+	retryDelay := 2 * time.Second //nolint:mnd // default delay
+	if seconds, err := strconv.Atoi(os.Getenv("SLEEP_DURATION")); err == nil {
+		retryDelay = time.Duration(seconds) * time.Second
+	}
+
+	downloadCmd.Flags().StringVar(&downloadOptions.Location, "location", os.Getenv("SHIM_LOCATION"), "URL of the shim artifact")
+	downloadCmd.Flags().StringVar(&downloadOptions.SHA256, "sha256", os.Getenv("SHIM_SHA256"), "Expected hex-encoded SHA-256 digest of the artifact")
+	downloadCmd.Flags().StringVarP(&downloadOptions.Output, "output", "o", output, "Path to write the shim binary to")
+	downloadCmd.Flags().StringVar(&downloadOptions.AuthDir, "auth-dir", os.Getenv("SHIM_AUTH_DIR"), "Directory containing credentials for the download")
+	downloadCmd.Flags().IntVar(&downloadOptions.Retries, "retries", retries, "Number of times a failed download is retried")
+	downloadCmd.Flags().DurationVar(&downloadOptions.RetryDelay, "retry-delay", retryDelay, "Delay before the first retry, doubled with every further retry")
+	rootCmd.AddCommand(downloadCmd)
+}
+
+func RunDownload(ctx context.Context, opts DownloadOptions) error {
+	if opts.Location == "" {
+		return errors.New("no location given")
+	}
+	if opts.Output == "" {
+		return errors.New("no output path given")
+	}
+
+	var auth *download.Auth
+	if opts.AuthDir != "" {
+		var err error
+		if auth, err = download.LoadAuth(opts.AuthDir); err != nil {
+			return err
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(opts.Output), 0o755); err != nil { //nolint:mnd // file permissions
+		return err
+	}
+
+	downloader := &download.Downloader{
+		HTTPClient: auth.HTTPClient(),
+		Auth:       auth,
+		Retries:    opts.Retries,
+		Delay:      opts.RetryDelay,
+	}
+
+	slog.Info("downloading shim", "location", opts.Location, "verify", opts.SHA256 != "")
+	result, err := downloader.Download(ctx, opts.Location, opts.SHA256, opts.Output)
+	if err != nil {
+		return err
+	}
+	slog.Info("shim downloaded", "path", opts.Output, "size", result.Size, "sha256", result.SHA256, "format", result.Format)
+	return nil
+}
+
+// downloadFailure returns the exit code and termination reason of a failed download.
+func downloadFailure(err error) (int, termination.Reason) {
+	switch {
+	case errors.Is(err, download.ErrChecksum):
+		return exitCodeChecksumError, termination.ReasonChecksumMismatch
+	case errors.Is(err, download.ErrArchive):
+		return exitCodeArchiveError, termination.ReasonInvalidArtifact
+	case errors.Is(err, download.ErrNetwork):
+		return exitCodeNetworkError, termination.ReasonDownloadFailed
+	default:
+		return exitCodeDownloadFailed, termination.ReasonDownloadFailed
+	}
+}
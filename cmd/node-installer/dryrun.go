@@ -0,0 +1,44 @@
+/*
+   Copyright The KWasm Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package main
+
+import (
+	"io"
+
+	"github.com/spf13/afero"
+	"github.com/spinframework/runtime-class-manager/internal/dryrun"
+)
+
+// newHostFs returns the filesystem of the host. In dry-run mode, changes are
+// only recorded by the returned dryrun.Fs, which is nil otherwise.
+func newHostFs(config Config, rootFs afero.Fs) (afero.Fs, *dryrun.Fs) {
+	hostFs := afero.NewBasePathFs(rootFs, config.Host.RootPath)
+	if !config.DryRun {
+		return hostFs, nil
+	}
+	dryRunFs := dryrun.New(hostFs)
+	return dryRunFs, dryRunFs
+}
+
+// printDryRun writes the changes that would have been made to the host.
+func printDryRun(w io.Writer, dryRunFs *dryrun.Fs) error {
+	changes, err := dryRunFs.Changes()
+	if err != nil {
+		return err
+	}
+	return dryrun.Print(w, changes)
+}
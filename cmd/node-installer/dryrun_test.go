@@ -0,0 +1,109 @@
+/*
+   Copyright The KWasm Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package main_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	main "github.com/spinframework/runtime-class-manager/cmd/node-installer"
+	"github.com/spinframework/runtime-class-manager/internal/dryrun"
+	tests "github.com/spinframework/runtime-class-manager/tests/node-installer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func dryRunConfig(name string) main.Config {
+	return main.Config{
+		struct {
+			Name          string
+			ConfigPath    string
+			Options       map[string]string
+			Type          string
+			ConfigMode    string
+			DropInDir     string
+			SocketPath    string
+			HealthTimeout time.Duration
+		}{name, "/etc/containerd/config.toml", nil, "", "", "", "/run/containerd/containerd.sock", time.Minute},
+		struct {
+			Path      string
+			AssetPath string
+		}{"/opt/rcm", "/assets"},
+		struct{ RootPath string }{""},
+		true,
+		false,
+		false,
+	}
+}
+
+func Test_RunInstallDryRun(t *testing.T) {
+	hostFs := tests.FixtureFs("../../testdata/node-installer/containerd/missing-containerd-shim-config")
+	originalConfig, err := afero.ReadFile(hostFs, "/etc/containerd/config.toml")
+	require.NoError(t, err)
+	dryRunFs := dryrun.New(hostFs)
+
+	// Neither the restarter nor the health check must be used
+	err = main.RunInstall(dryRunConfig("containerd"), tests.FixtureFs("../../testdata/node-installer"), dryRunFs, failingRestarter{})
+	require.NoError(t, err)
+
+	gotConfig, err := afero.ReadFile(hostFs, "/etc/containerd/config.toml")
+	require.NoError(t, err)
+	assert.Equal(t, string(originalConfig), string(gotConfig))
+	_, err = hostFs.Stat("/opt/rcm")
+	require.ErrorIs(t, err, afero.ErrFileNotFound)
+
+	changes, err := dryRunFs.Changes()
+	require.NoError(t, err)
+	var out bytes.Buffer
+	require.NoError(t, dryrun.Print(&out, changes))
+	assert.Contains(t, out.String(), `update /etc/containerd/config.toml
+create /opt/rcm/bin/spin-v1/6da5e8f17a9bfa9cb04cf22c87b6475394ecec3af4fdc337f72d6dbf3319ea52/containerd-shim-spin-v1
+create /opt/rcm/bin/wasmtime-v1/5064503673b33772f86aa61d085e01aabcebf319cff1697a8e0c968d8ef35934/containerd-shim-wasmtime-v1
+create /opt/rcm/rcm-lock.json
+`)
+	assert.Contains(t, out.String(), `+[plugins."io.containerd.grpc.v1.cri".containerd.runtimes.spin-v1]
++runtime_type = "/opt/rcm/bin/spin-v1/6da5e8f17a9bfa9cb04cf22c87b6475394ecec3af4fdc337f72d6dbf3319ea52/containerd-shim-spin-v1"
+`)
+}
+
+func Test_RunUninstallDryRun(t *testing.T) {
+	hostFs := tests.FixtureFs("../../testdata/node-installer/containerd/existing-containerd-shim-config")
+	originalConfig, err := afero.ReadFile(hostFs, "/etc/containerd/config.toml")
+	require.NoError(t, err)
+	dryRunFs := dryrun.New(hostFs)
+
+	err = main.RunUninstall(dryRunConfig("spin-v1"), tests.FixtureFs("../../testdata/node-installer"), dryRunFs, failingRestarter{})
+	require.NoError(t, err)
+
+	gotConfig, err := afero.ReadFile(hostFs, "/etc/containerd/config.toml")
+	require.NoError(t, err)
+	assert.Equal(t, string(originalConfig), string(gotConfig))
+
+	changes, err := dryRunFs.Changes()
+	require.NoError(t, err)
+	var out bytes.Buffer
+	require.NoError(t, dryrun.Print(&out, changes))
+	assert.Contains(t, out.String(), `update /etc/containerd/config.toml
+update /opt/rcm/rcm-lock.json
+`)
+	assert.Contains(t, out.String(), `-# RCM runtime config for spin-v1
+-[plugins."io.containerd.grpc.v1.cri".containerd.runtimes.spin-v1]
+-runtime_type = "/opt/rcm/bin/containerd-shim-spin-v1"
+`)
+}
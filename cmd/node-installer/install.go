@@ -17,63 +17,82 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/fs"
 	"log/slog"
 	"os"
 	"path"
+	"time"
 
 	"github.com/spf13/afero"
 	"github.com/spf13/cobra"
+	"github.com/spinframework/runtime-class-manager/internal/backup"
 	"github.com/spinframework/runtime-class-manager/internal/containerd"
+	"github.com/spinframework/runtime-class-manager/internal/cri"
+	"github.com/spinframework/runtime-class-manager/internal/health"
 	"github.com/spinframework/runtime-class-manager/internal/preset"
 	"github.com/spinframework/runtime-class-manager/internal/shim"
+	"github.com/spinframework/runtime-class-manager/internal/state"
+	"github.com/spinframework/runtime-class-manager/internal/termination"
 )
 
+// hostMountNamespaceRoot is the root directory of the host mount namespace,
+// as seen from the installer running in the host PID namespace.
+const hostMountNamespaceRoot = "/proc/1/root"
+
 // installCmd represents the install command.
 var installCmd = &cobra.Command{
 	Use:   "install",
-	Short: "Install containerd shims",
-	Run: func(_ *cobra.Command, _ []string) {
+	Short: "Install shims",
+	Run: func(cmd *cobra.Command, _ []string) {
 		rootFs := afero.NewOsFs()
-		hostFs := afero.NewBasePathFs(rootFs, config.Host.RootPath)
+		hostFs, dryRunFs := newHostFs(config, rootFs)
 
 		distro, err := DetectDistro(config, hostFs)
 		if err != nil {
-			slog.Error("failed to detect containerd config", "error", err)
-			os.Exit(1)
+			fail(1, termination.ReasonConfigNotFound, "failed to detect container runtime config", err)
 		}
 
 		config.Runtime.ConfigPath = distro.ConfigPath
+		config.Runtime.Type = distro.ContainerRuntime
+		config.Runtime.SocketPath = HostSocketPath(config.Runtime.SocketPath, distro)
 		if err = distro.Setup(preset.Env{ConfigPath: distro.ConfigPath, HostFs: hostFs}); err != nil {
-			slog.Error("failed to run distro setup", "error", err)
-			os.Exit(1)
+			fail(1, termination.ReasonConfigUpdateFailed, "failed to run distro setup", err)
 		}
 
 		config.Runtime.Options, err = RuntimeOptions()
 		if err != nil {
-			slog.Error("failed to get runtime options", "error", err)
-			os.Exit(1)
+			fail(1, termination.ReasonInvalidConfig, "failed to get runtime options", err)
 		}
 
 		if err := RunInstall(config, rootFs, hostFs, distro.Restarter); err != nil {
-			slog.Error("failed to install", "error", err)
-			os.Exit(1)
+			fail(1, termination.ReasonOf(err), "failed to install", err)
+		}
+		if dryRunFs != nil {
+			if err := printDryRun(cmd.OutOrStdout(), dryRunFs); err != nil {
+				fail(1, termination.ReasonUnknown, "failed to print changes", err)
+			}
 		}
 	},
 }
 
 func init() {
 	installCmd.Flags().StringVarP(&config.RCM.AssetPath, "asset-path", "a", "/assets", "Path to the asset to install")
+	installCmd.Flags().StringVar(&config.Runtime.SocketPath, "runtime-socket", "", "Path to the socket of the container runtime on the host. Will use the default of the detected distro if left empty")
+	installCmd.Flags().BoolVar(&config.DryRun, "dry-run", false, "Print the changes to the shims and the container runtime config instead of making them, without restarting the container runtime")
+	installCmd.Flags().BoolVar(&config.SkipHandlerVerification, "skip-handler-verification", false, "Don't verify over CRI that the container runtime registered the shims, for container runtimes that report neither their runtime handlers nor their config")
+	installCmd.Flags().DurationVar(&config.Runtime.HealthTimeout, "health-timeout", 2*time.Minute, "How long to wait for the container runtime to become healthy and register the shims after a restart. 0 disables the checks")
 	rootCmd.AddCommand(installCmd)
 }
 
-func RunInstall(config Config, rootFs, hostFs afero.Fs, restarter containerd.Restarter) error {
+func RunInstall(config Config, rootFs, hostFs afero.Fs, restarter Restarter) error {
 	// Get file or directory information.
 	info, err := rootFs.Stat(config.RCM.AssetPath)
 	if err != nil {
-		return err
+		return termination.WithReason(termination.ReasonShimInstallFailed, err)
 	}
 
 	var files []fs.FileInfo
@@ -81,7 +100,7 @@ func RunInstall(config Config, rootFs, hostFs afero.Fs, restarter containerd.Res
 	if info.IsDir() {
 		files, err = afero.ReadDir(rootFs, config.RCM.AssetPath)
 		if err != nil {
-			return err
+			return termination.WithReason(termination.ReasonShimInstallFailed, err)
 		}
 	} else {
 		// If the path is not a directory, add the file to the list of files.
@@ -89,50 +108,185 @@ func RunInstall(config Config, rootFs, hostFs afero.Fs, restarter containerd.Res
 		config.RCM.AssetPath = path.Dir(config.RCM.AssetPath)
 	}
 
-	containerdConfig := containerd.NewConfig(hostFs, config.Runtime.ConfigPath, restarter, config.Runtime.Options)
+	runtimeConfig, err := NewRuntimeConfig(config, hostFs, restarter)
+	if err != nil {
+		return termination.WithReason(termination.ReasonInvalidConfig, err)
+	}
 	shimConfig := shim.NewConfig(rootFs, hostFs, config.RCM.AssetPath, config.RCM.Path)
 
+	// Installers of other shims on the same node change the runtime config
+	// and restart the runtime one after the other, so that restoring a
+	// backup doesn't undo the changes of another installer.
+	unlock, err := state.LockRuntimeConfig(hostFs, config.RCM.Path)
+	if err != nil {
+		return termination.WithReason(termination.ReasonConfigUpdateFailed, err)
+	}
+	defer unlock() //nolint:errcheck // the lock is released when the file is closed in any case
+
+	// Snapshot everything that is changed, to restore it if the container
+	// runtime doesn't come back after the restart. Of the lock file, only the
+	// entries of the installed shims are restored. Each installation keeps
+	// its backups in a directory of its own.
+	backupDir, err := afero.TempDir(hostFs, state.BackupPath(config.RCM.Path), "install-")
+	if err != nil {
+		return termination.WithReason(termination.ReasonConfigUpdateFailed, fmt.Errorf("failed to create backup directory: %w", err))
+	}
+	snapshot := backup.New(hostFs, backupDir)
+	defer func() {
+		if err := snapshot.Discard(); err != nil {
+			slog.Warn("failed to remove backup", "error", err)
+		}
+	}()
+	undo := installBackup{snapshot: snapshot, checkpoint: state.NewCheckpoint(hostFs, config.RCM.Path)}
+
 	anythingChanged := false
+	runtimeNames := make([]string, 0, len(files))
 	for _, file := range files {
 		fileName := file.Name()
 		runtimeName := shim.RuntimeName(fileName)
+		runtimeNames = append(runtimeNames, runtimeName)
 
+		if err := undo.checkpoint.Add(runtimeName); err != nil {
+			return restoreOnError(undo, termination.WithReason(termination.ReasonShimInstallFailed, fmt.Errorf("failed to back up lock file: %w", err)))
+		}
+		digest, err := shimConfig.AssetDigest(fileName)
+		if err != nil {
+			return restoreOnError(undo, termination.WithReason(termination.ReasonShimInstallFailed, fmt.Errorf("failed to read shim '%s': %w", runtimeName, err)))
+		}
+		if err := snapshot.Add(shimConfig.BinPath(fileName, digest)); err != nil {
+			return restoreOnError(undo, termination.WithReason(termination.ReasonShimInstallFailed, err))
+		}
 		binPath, changed, err := shimConfig.Install(fileName)
 		if err != nil {
-			return fmt.Errorf("failed to install shim '%s': %w", runtimeName, err)
+			return restoreOnError(undo, termination.WithReason(termination.ReasonShimInstallFailed, fmt.Errorf("failed to install shim '%s': %w", runtimeName, err)))
 		}
 		anythingChanged = anythingChanged || changed
 		slog.Info("shim installed", "shim", runtimeName, "path", binPath, "new-version", changed)
 
-		err = containerdConfig.AddRuntime(binPath)
+		for _, configFile := range runtimeConfig.ConfigFiles(binPath) {
+			if err := snapshot.Add(configFile); err != nil {
+				return restoreOnError(undo, termination.WithReason(termination.ReasonConfigUpdateFailed, err))
+			}
+		}
+		configChanged, err := runtimeConfig.AddRuntime(binPath)
 		if err != nil {
-			return fmt.Errorf("failed to write containerd config: %w", err)
+			return restoreOnError(undo, termination.WithReason(termination.ReasonConfigUpdateFailed, fmt.Errorf("failed to write runtime config: %w", err)))
 		}
-		slog.Info("shim configured", "shim", runtimeName, "path", config.Runtime.ConfigPath)
+		anythingChanged = anythingChanged || configChanged
+		slog.Info("shim configured", "shim", runtimeName, "path", config.Runtime.ConfigPath, "config-changed", configChanged)
 	}
 
 	if !anythingChanged {
 		slog.Info("nothing changed, nothing more to do")
 		return nil
 	}
+	if config.DryRun {
+		slog.Info("dry run, not restarting container runtime", "runtime", config.Runtime.Type)
+		return nil
+	}
 
 	// Ensure D-Bus is installed and running if using systemd
 	if _, err := containerd.ListSystemdUnits(); err == nil {
 		err = containerd.InstallDbus()
 		if err != nil {
-			return fmt.Errorf("failed to install D-Bus: %w", err)
+			return restoreOnError(undo, termination.WithReason(termination.ReasonRestartFailed, fmt.Errorf("failed to install D-Bus: %w", err)))
 		}
 	}
 
-	slog.Info("restarting containerd")
-	err = containerdConfig.RestartRuntime()
-	if err != nil {
-		return fmt.Errorf("failed to restart containerd: %w", err)
+	slog.Info("restarting container runtime", "runtime", config.Runtime.Type)
+	if err := runtimeConfig.RestartRuntime(); err != nil {
+		return rollback(config, undo, runtimeConfig, termination.WithReason(termination.ReasonRestartFailed, fmt.Errorf("failed to restart container runtime: %w", err)))
+	}
+	if err := waitForRuntime(config); err != nil {
+		return rollback(config, undo, runtimeConfig, termination.WithReason(termination.ReasonRuntimeUnhealthy, fmt.Errorf("container runtime did not become healthy within %s: %w", config.Runtime.HealthTimeout, err)))
+	}
+	if err := verifyHandlers(config, runtimeNames); err != nil {
+		return rollback(config, undo, runtimeConfig, termination.WithReason(termination.ReasonHandlerNotRegistered, err))
 	}
 
 	return nil
 }
 
+// installBackup holds what is needed to undo a failed installation.
+type installBackup struct {
+	snapshot   *backup.Snapshot
+	checkpoint *state.Checkpoint
+}
+
+// Restore restores the backed up files and the entries of the installed shims
+// in the lock file.
+func (b installBackup) Restore() error {
+	return errors.Join(b.snapshot.Restore(), b.checkpoint.Restore())
+}
+
+// restoreOnError restores the backup after a failure that happened before
+// the container runtime was restarted.
+func restoreOnError(undo installBackup, err error) error {
+	if restoreErr := undo.Restore(); restoreErr != nil {
+		return termination.WithReason(termination.ReasonOf(err), fmt.Errorf("%w; failed to restore the previous state: %w", err, restoreErr))
+	}
+	return err
+}
+
+// rollback restores the backup after the container runtime failed to come
+// back with the new config or didn't register the runtime handlers, and
+// restarts it again.
+func rollback(config Config, undo installBackup, runtimeConfig RuntimeConfig, err error) error {
+	slog.Error("restoring the previous config and shims", "error", err)
+	if restoreErr := undo.Restore(); restoreErr != nil {
+		return termination.WithReason(termination.ReasonOf(err), fmt.Errorf("%w; failed to restore the previous state: %w", err, restoreErr))
+	}
+
+	slog.Info("restarting container runtime with the previous config", "runtime", config.Runtime.Type)
+	if restartErr := runtimeConfig.RestartRuntime(); restartErr != nil {
+		return termination.WithReason(termination.ReasonOf(err), fmt.Errorf("%w; restored the previous state, but failed to restart the container runtime: %w", err, restartErr))
+	}
+	if healthErr := waitForRuntime(config); healthErr != nil {
+		return termination.WithReason(termination.ReasonOf(err), fmt.Errorf("%w; restored the previous state, but the container runtime is still unhealthy: %w", err, healthErr))
+	}
+
+	return termination.WithReason(termination.ReasonOf(err), fmt.Errorf("%w; restored the previous state", err))
+}
+
+// HostSocketPath returns the path of the socket of the container runtime in
+// the mount namespace of the host, taking the default of the distro if
+// socketPath is empty. It returns an empty path if neither is set, which
+// disables the health checks.
+func HostSocketPath(socketPath string, distro preset.Settings) string {
+	if socketPath == "" {
+		socketPath = distro.SocketPath
+	}
+	if socketPath == "" {
+		return ""
+	}
+	return path.Join(hostMountNamespaceRoot, socketPath)
+}
+
+// waitForRuntime waits until the container runtime serves on its socket. It
+// returns immediately if no socket or timeout is configured.
+func waitForRuntime(config Config) error {
+	if config.Runtime.SocketPath == "" || config.Runtime.HealthTimeout <= 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), config.Runtime.HealthTimeout)
+	defer cancel()
+	return health.WaitForSocket(ctx, config.Runtime.SocketPath, health.DefaultInterval)
+}
+
+// verifyHandlers verifies over CRI that the container runtime registered the
+// runtime handlers of the shims. It returns immediately if no socket or
+// timeout is configured, or the verification is skipped.
+func verifyHandlers(config Config, runtimeNames []string) error {
+	if config.Runtime.SocketPath == "" || config.Runtime.HealthTimeout <= 0 || config.SkipHandlerVerification {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), config.Runtime.HealthTimeout)
+	defer cancel()
+	return cri.VerifyHandlers(ctx, config.Runtime.SocketPath, runtimeNames, health.DefaultInterval)
+}
+
 func RuntimeOptions() (map[string]string, error) {
 	runtimeOptions := make(map[string]string)
 	optionsJSON := os.Getenv("RUNTIME_OPTIONS")
@@ -17,12 +17,25 @@
 package main_test
 
 import (
+	"context"
+	"errors"
+	"net"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/spf13/afero"
 	main "github.com/spinframework/runtime-class-manager/cmd/node-installer"
+	"github.com/spinframework/runtime-class-manager/internal/containerd"
+	"github.com/spinframework/runtime-class-manager/internal/cri"
+	"github.com/spinframework/runtime-class-manager/internal/preset"
+	"github.com/spinframework/runtime-class-manager/internal/state"
+	"github.com/spinframework/runtime-class-manager/internal/termination"
 	tests "github.com/spinframework/runtime-class-manager/tests/node-installer"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1"
 )
 
 type nullRestarter struct{}
@@ -31,6 +44,12 @@ func (n nullRestarter) Restart() error {
 	return nil
 }
 
+type failingRestarter struct{}
+
+func (f failingRestarter) Restart() error {
+	return errors.New("containerd not found")
+}
+
 func Test_RunInstall(t *testing.T) {
 	type args struct {
 		config main.Config
@@ -47,15 +66,23 @@ func Test_RunInstall(t *testing.T) {
 			args{
 				main.Config{
 					struct {
-						Name       string
-						ConfigPath string
-						Options    map[string]string
-					}{"containerd", "/etc/containerd/config.toml", nil},
+						Name          string
+						ConfigPath    string
+						Options       map[string]string
+						Type          string
+						ConfigMode    string
+						DropInDir     string
+						SocketPath    string
+						HealthTimeout time.Duration
+					}{"containerd", "/etc/containerd/config.toml", nil, "", "", "", "", 0},
 					struct {
 						Path      string
 						AssetPath string
 					}{"/opt/rcm", "/assets"},
 					struct{ RootPath string }{"/containerd/missing-containerd-shim-config"},
+					false,
+					false,
+					false,
 				},
 				tests.FixtureFs("../../testdata/node-installer"),
 				tests.FixtureFs("../../testdata/node-installer/containerd/missing-containerd-shim-config"),
@@ -67,15 +94,23 @@ func Test_RunInstall(t *testing.T) {
 			args{
 				main.Config{
 					struct {
-						Name       string
-						ConfigPath string
-						Options    map[string]string
-					}{"containerd", "/etc/containerd/config.toml", nil},
+						Name          string
+						ConfigPath    string
+						Options       map[string]string
+						Type          string
+						ConfigMode    string
+						DropInDir     string
+						SocketPath    string
+						HealthTimeout time.Duration
+					}{"containerd", "/etc/containerd/config.toml", nil, "", "", "", "", 0},
 					struct {
 						Path      string
 						AssetPath string
 					}{"/opt/rcm", "/assets"},
 					struct{ RootPath string }{"/containerd/existing-containerd-shim-config"},
+					false,
+					false,
+					false,
 				},
 				tests.FixtureFs("../../testdata/node-installer"),
 				tests.FixtureFs("../../testdata/node-installer/containerd/existing-containerd-shim-config"),
@@ -88,15 +123,23 @@ func Test_RunInstall(t *testing.T) {
 			args{
 				main.Config{
 					struct {
-						Name       string
-						ConfigPath string
-						Options    map[string]string
-					}{"containerd", "/etc/containerd/config.toml", map[string]string{"SystemdCgroup": "true"}},
+						Name          string
+						ConfigPath    string
+						Options       map[string]string
+						Type          string
+						ConfigMode    string
+						DropInDir     string
+						SocketPath    string
+						HealthTimeout time.Duration
+					}{"containerd", "/etc/containerd/config.toml", map[string]string{"SystemdCgroup": "true"}, "", "", "", "", 0},
 					struct {
 						Path      string
 						AssetPath string
 					}{"/opt/rcm", "/assets"},
 					struct{ RootPath string }{"/containerd/missing-containerd-shim-config"},
+					false,
+					false,
+					false,
 				},
 				tests.FixtureFs("../../testdata/node-installer"),
 				tests.FixtureFs("../../testdata/node-installer/containerd/missing-containerd-shim-config"),
@@ -115,3 +158,271 @@ func Test_RunInstall(t *testing.T) {
 		})
 	}
 }
+
+func Test_RunInstallFailureReason(t *testing.T) {
+	config := func(assetPath string) main.Config {
+		return main.Config{
+			struct {
+				Name          string
+				ConfigPath    string
+				Options       map[string]string
+				Type          string
+				ConfigMode    string
+				DropInDir     string
+				SocketPath    string
+				HealthTimeout time.Duration
+			}{"containerd", "/etc/containerd/config.toml", nil, "", "", "", "", 0},
+			struct {
+				Path      string
+				AssetPath string
+			}{"/opt/rcm", assetPath},
+			struct{ RootPath string }{"/containerd/missing-containerd-shim-config"},
+			false,
+			false,
+			false,
+		}
+	}
+
+	cases := []struct {
+		name      string
+		config    main.Config
+		restarter containerd.Restarter
+		want      termination.Reason
+	}{
+		{"missing assets", config("/missing"), nullRestarter{}, termination.ReasonShimInstallFailed},
+		{"restart fails", config("/assets"), failingRestarter{}, termination.ReasonRestartFailed},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			err := main.RunInstall(tt.config,
+				tests.FixtureFs("../../testdata/node-installer"),
+				tests.FixtureFs("../../testdata/node-installer/containerd/missing-containerd-shim-config"),
+				tt.restarter)
+			require.Error(t, err)
+			assert.Equal(t, tt.want, termination.ReasonOf(err))
+		})
+	}
+}
+
+func Test_RunInstallCRIO(t *testing.T) {
+	config := main.Config{
+		struct {
+			Name          string
+			ConfigPath    string
+			Options       map[string]string
+			Type          string
+			ConfigMode    string
+			DropInDir     string
+			SocketPath    string
+			HealthTimeout time.Duration
+		}{"containerd", "/etc/crio/crio.conf.d", nil, "cri-o", "", "", "", 0},
+		struct {
+			Path      string
+			AssetPath string
+		}{"/opt/rcm", "/assets"},
+		struct{ RootPath string }{"/distros/crio"},
+		false,
+		false,
+		false,
+	}
+	hostFs := tests.FixtureFs("../../testdata/node-installer/distros/crio")
+
+	err := main.RunInstall(config, tests.FixtureFs("../../testdata/node-installer"), hostFs, nullRestarter{})
+	require.NoError(t, err)
+
+	dropIn, err := afero.ReadFile(hostFs, "/etc/crio/crio.conf.d/99-rcm-spin-v1.conf")
+	require.NoError(t, err)
+	assert.Contains(t, string(dropIn), "[crio.runtime.runtimes.spin-v1]\nruntime_path = \"/opt/rcm/bin/spin-v1/6da5e8f17a9bfa9cb04cf22c87b6475394ecec3af4fdc337f72d6dbf3319ea52/containerd-shim-spin-v1\"\n")
+
+	// The main config and other drop-ins are left untouched
+	mainConfig, err := afero.ReadFile(hostFs, "/etc/crio/crio.conf")
+	require.NoError(t, err)
+	assert.Equal(t, "[crio.runtime]\ndefault_runtime = \"crun\"\n", string(mainConfig))
+}
+
+func Test_RunInstallContainerdDropIn(t *testing.T) {
+	config := main.Config{
+		struct {
+			Name          string
+			ConfigPath    string
+			Options       map[string]string
+			Type          string
+			ConfigMode    string
+			DropInDir     string
+			SocketPath    string
+			HealthTimeout time.Duration
+		}{"containerd", "/etc/containerd/config.toml", nil, "containerd", "drop-in", "/etc/containerd/conf.d", "", 0},
+		struct {
+			Path      string
+			AssetPath string
+		}{"/opt/rcm", "/assets"},
+		struct{ RootPath string }{"/containerd/missing-containerd-shim-config"},
+		false,
+		false,
+		false,
+	}
+	hostFs := tests.FixtureFs("../../testdata/node-installer/containerd/missing-containerd-shim-config")
+
+	err := main.RunInstall(config, tests.FixtureFs("../../testdata/node-installer"), hostFs, nullRestarter{})
+	require.NoError(t, err)
+
+	for runtime, digest := range map[string]string{
+		"spin-v1":     "6da5e8f17a9bfa9cb04cf22c87b6475394ecec3af4fdc337f72d6dbf3319ea52",
+		"wasmtime-v1": "5064503673b33772f86aa61d085e01aabcebf319cff1697a8e0c968d8ef35934",
+	} {
+		dropIn, err := afero.ReadFile(hostFs, "/etc/containerd/conf.d/rcm-"+runtime+".toml")
+		require.NoError(t, err)
+		assert.Contains(t, string(dropIn), "runtime_type = \"/opt/rcm/bin/"+runtime+"/"+digest+"/containerd-shim-"+runtime+"\"\n")
+	}
+	mainConfig, err := afero.ReadFile(hostFs, "/etc/containerd/config.toml")
+	require.NoError(t, err)
+	assert.Contains(t, string(mainConfig), "imports = [\"/etc/containerd/conf.d/*.toml\"]\n")
+	assert.NotContains(t, string(mainConfig), "runtime_type")
+}
+
+func Test_RunInstallRollback(t *testing.T) {
+	config := main.Config{
+		struct {
+			Name          string
+			ConfigPath    string
+			Options       map[string]string
+			Type          string
+			ConfigMode    string
+			DropInDir     string
+			SocketPath    string
+			HealthTimeout time.Duration
+		}{"containerd", "/etc/containerd/config.toml", nil, "", "", "", "/run/containerd/containerd.sock", 50 * time.Millisecond},
+		struct {
+			Path      string
+			AssetPath string
+		}{"/opt/rcm", "/assets"},
+		struct{ RootPath string }{t.TempDir()},
+		false,
+		false,
+		false,
+	}
+	hostFs := tests.FixtureFs("../../testdata/node-installer/containerd/missing-containerd-shim-config")
+	originalConfig, err := afero.ReadFile(hostFs, "/etc/containerd/config.toml")
+	require.NoError(t, err)
+
+	err = main.RunInstall(config, tests.FixtureFs("../../testdata/node-installer"), hostFs, nullRestarter{})
+	require.Error(t, err)
+	assert.Equal(t, termination.ReasonRuntimeUnhealthy, termination.ReasonOf(err))
+
+	gotConfig, err := afero.ReadFile(hostFs, "/etc/containerd/config.toml")
+	require.NoError(t, err)
+	assert.Equal(t, string(originalConfig), string(gotConfig))
+	for _, path := range []string{
+		"/opt/rcm/bin/spin-v1/6da5e8f17a9bfa9cb04cf22c87b6475394ecec3af4fdc337f72d6dbf3319ea52/containerd-shim-spin-v1",
+		"/opt/rcm/bin/wasmtime-v1/5064503673b33772f86aa61d085e01aabcebf319cff1697a8e0c968d8ef35934/containerd-shim-wasmtime-v1",
+	} {
+		_, err := hostFs.Stat(path)
+		require.ErrorIs(t, err, afero.ErrFileNotFound, path)
+	}
+	backups, err := afero.ReadDir(hostFs, "/opt/rcm/backup")
+	require.NoError(t, err)
+	assert.Empty(t, backups)
+	st, err := state.Get(hostFs, "/opt/rcm")
+	require.NoError(t, err)
+	assert.Empty(t, st.Shims)
+	assert.Empty(t, st.History)
+}
+
+// fakeRuntimeService is a CRI runtime service reporting a fixed set of runtime handlers.
+type fakeRuntimeService struct {
+	runtimeapi.UnimplementedRuntimeServiceServer
+	handlers []string
+}
+
+func (f *fakeRuntimeService) Status(_ context.Context, _ *runtimeapi.StatusRequest) (*runtimeapi.StatusResponse, error) {
+	resp := &runtimeapi.StatusResponse{}
+	for _, handler := range f.handlers {
+		resp.RuntimeHandlers = append(resp.RuntimeHandlers, &runtimeapi.RuntimeHandler{Name: handler})
+	}
+	return resp, nil
+}
+
+func Test_HostSocketPath(t *testing.T) {
+	tests := []struct {
+		name       string
+		socketPath string
+		distro     preset.Settings
+		want       string
+	}{
+		{"distro default", "", preset.Default, "/proc/1/root/run/containerd/containerd.sock"},
+		{"flag overrides distro default", "/run/custom.sock", preset.Default, "/proc/1/root/run/custom.sock"},
+		{"preset without a socket", "", preset.Default.WithSocketPath(""), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, main.HostSocketPath(tt.socketPath, tt.distro))
+		})
+	}
+}
+
+func Test_RunInstallVerifyHandlers(t *testing.T) {
+	cases := []struct {
+		name     string
+		handlers []string
+		skip     bool
+		want     termination.Reason
+	}{
+		{"handlers registered", []string{"", "runc", "spin-v1", "wasmtime-v1"}, false, ""},
+		{"handler not registered", []string{"", "runc", "spin-v1"}, false, termination.ReasonHandlerNotRegistered},
+		{"handlers not reported", nil, false, termination.ReasonHandlerNotRegistered},
+		{"verification skipped", nil, true, ""},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			socketPath := filepath.Join(t.TempDir(), "containerd.sock")
+			listener, err := net.Listen("unix", socketPath)
+			require.NoError(t, err)
+			server := grpc.NewServer()
+			runtimeapi.RegisterRuntimeServiceServer(server, &fakeRuntimeService{handlers: tt.handlers})
+			go func() {
+				_ = server.Serve(listener)
+			}()
+			defer server.Stop()
+
+			config := main.Config{
+				struct {
+					Name          string
+					ConfigPath    string
+					Options       map[string]string
+					Type          string
+					ConfigMode    string
+					DropInDir     string
+					SocketPath    string
+					HealthTimeout time.Duration
+				}{"containerd", "/etc/containerd/config.toml", nil, "", "", "", socketPath, 3 * time.Second},
+				struct {
+					Path      string
+					AssetPath string
+				}{"/opt/rcm", "/assets"},
+				struct{ RootPath string }{"/containerd/missing-containerd-shim-config"},
+				false,
+				false,
+				tt.skip,
+			}
+
+			hostFs := tests.FixtureFs("../../testdata/node-installer/containerd/missing-containerd-shim-config")
+			originalConfig, err := afero.ReadFile(hostFs, "/etc/containerd/config.toml")
+			require.NoError(t, err)
+
+			err = main.RunInstall(config, tests.FixtureFs("../../testdata/node-installer"), hostFs, nullRestarter{})
+			if tt.want == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, cri.ErrHandlerNotRegistered)
+			assert.Equal(t, tt.want, termination.ReasonOf(err))
+
+			gotConfig, err := afero.ReadFile(hostFs, "/etc/containerd/config.toml")
+			require.NoError(t, err)
+			assert.Equal(t, string(originalConfig), string(gotConfig))
+			st, err := state.Get(hostFs, "/opt/rcm")
+			require.NoError(t, err)
+			assert.Empty(t, st.Shims)
+		})
+	}
+}
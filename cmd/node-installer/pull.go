@@ -0,0 +1,101 @@
+/*
+   Copyright The KWasm Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/spf13/cobra"
+	"github.com/spinframework/runtime-class-manager/internal/oci"
+	"github.com/spinframework/runtime-class-manager/internal/termination"
+)
+
+type PullOptions struct {
+	Reference string
+	Platform  string
+	Output    string
+	PlainHTTP bool
+}
+
+var pullOptions PullOptions
+
+// pullCmd represents the pull command.
+var pullCmd = &cobra.Command{
+	Use:   "pull",
+	Short: "Pull a containerd shim from an OCI registry",
+	Run: func(cmd *cobra.Command, _ []string) {
+		if err := RunPull(cmd.Context(), pullOptions, &oci.Client{PlainHTTP: pullOptions.PlainHTTP}); err != nil {
+			fail(1, pullFailureReason(err), "failed to pull shim", err, "reference", pullOptions.Reference)
+		}
+	},
+}
+
+func init() {
+	pullCmd.Flags().StringVar(&pullOptions.Reference, "reference", "", "Reference of the image or artifact containing the shim, optionally pinned by digest")
+	pullCmd.Flags().StringVar(&pullOptions.Platform, "platform", runtime.GOOS+"/"+runtime.GOARCH, "Platform (os/arch) to select from a multi-platform index")
+	pullCmd.Flags().StringVarP(&pullOptions.Output, "output", "o", "", "Path to write the shim binary to")
+	pullCmd.Flags().BoolVar(&pullOptions.PlainHTTP, "plain-http", false, "Connect to the registry over HTTP instead of HTTPS")
+	rootCmd.AddCommand(pullCmd)
+}
+
+func RunPull(ctx context.Context, opts PullOptions, client *oci.Client) error {
+	if opts.Output == "" {
+		return errors.New("no output path given")
+	}
+	ref, err := oci.ParseReference(opts.Reference)
+	if err != nil {
+		return err
+	}
+	osName, arch, ok := strings.Cut(opts.Platform, "/")
+	if !ok {
+		return fmt.Errorf("invalid platform %q, expected os/arch", opts.Platform)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(opts.Output), 0o755); err != nil { //nolint:mnd // file permissions
+		return err
+	}
+
+	slog.Info("pulling shim", "reference", ref.String(), "platform", opts.Platform)
+	layer, err := client.Pull(ctx, ref, ocispec.Platform{OS: osName, Architecture: arch}, opts.Output)
+	if err != nil {
+		return err
+	}
+	slog.Info("shim pulled", "path", opts.Output, "layer", layer.Digest)
+	return nil
+}
+
+// pullFailureReason returns the termination reason of a failed pull.
+func pullFailureReason(err error) termination.Reason {
+	switch {
+	case errors.Is(err, oci.ErrNoPlatformMatch):
+		return termination.ReasonNoPlatformMatch
+	case errors.Is(err, oci.ErrDigestMismatch):
+		return termination.ReasonChecksumMismatch
+	case errors.Is(err, oci.ErrInvalidLayer):
+		return termination.ReasonInvalidArtifact
+	default:
+		return termination.ReasonDownloadFailed
+	}
+}
@@ -25,6 +25,7 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
+	"github.com/spinframework/runtime-class-manager/internal/containerd"
 )
 
 var config Config
@@ -48,8 +49,11 @@ func Execute() {
 }
 
 func init() {
-	rootCmd.PersistentFlags().StringVarP(&config.Runtime.Name, "runtime", "r", "containerd", "Set the container runtime to configure (containerd, cri-o)")
-	rootCmd.PersistentFlags().StringVarP(&config.Runtime.ConfigPath, "runtime-config", "c", "", "Path to the runtime config file. Will try to autodetect if left empty")
+	rootCmd.PersistentFlags().StringVarP(&config.Runtime.Name, "runtime", "r", "containerd", "Name of the shim to configure as a runtime")
+	rootCmd.PersistentFlags().StringVar(&config.Runtime.Type, "container-runtime", "", "Set the container runtime to configure (containerd, cri-o). Will try to autodetect if left empty")
+	rootCmd.PersistentFlags().StringVar(&config.Runtime.ConfigMode, "containerd-config-mode", containerd.ConfigModeInline, "How runtimes are added to the containerd config (inline, drop-in)")
+	rootCmd.PersistentFlags().StringVar(&config.Runtime.DropInDir, "containerd-drop-in-dir", containerd.DefaultDropInDir, "Directory of the containerd drop-in files for the drop-in config mode")
+	rootCmd.PersistentFlags().StringVarP(&config.Runtime.ConfigPath, "runtime-config", "c", "", "Path to the runtime config file, or the drop-in directory for cri-o. Will try to autodetect if left empty")
 	rootCmd.PersistentFlags().StringVarP(&config.RCM.Path, "rcm-path", "k", "/opt/rcm", "Working directory for the RuntimeClassManager on the host")
 	rootCmd.PersistentFlags().StringVarP(&config.Host.RootPath, "host-root", "H", "/", "Path to the host root path")
 }
@@ -0,0 +1,70 @@
+/*
+   Copyright The KWasm Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/spf13/afero"
+	"github.com/spinframework/runtime-class-manager/internal/containerd"
+	"github.com/spinframework/runtime-class-manager/internal/crio"
+	"github.com/spinframework/runtime-class-manager/internal/preset"
+)
+
+// RuntimeConfig configures shims as runtimes of the container runtime of a node.
+type RuntimeConfig interface {
+	// AddRuntime adds or updates the runtime of a shim. It returns whether the
+	// config was changed.
+	AddRuntime(shimPath string) (bool, error)
+	// RemoveRuntime removes the runtime of a shim. It returns whether the
+	// config was changed.
+	RemoveRuntime(shimPath string) (bool, error)
+	// HasRuntime returns whether the runtime of a shim is configured.
+	HasRuntime(shimPath string) (bool, error)
+	// ConfigFiles returns the files AddRuntime and RemoveRuntime may change
+	// for the runtime of a shim.
+	ConfigFiles(shimPath string) []string
+	// RestartRuntime restarts the container runtime to apply the config.
+	RestartRuntime() error
+}
+
+// Restarter restarts the container runtime of a node. It is shared by the
+// configs of all container runtimes and the distro presets.
+type Restarter = containerd.Restarter
+
+// NewRuntimeConfig returns the RuntimeConfig of the container runtime set in config.
+func NewRuntimeConfig(config Config, hostFs afero.Fs, restarter Restarter) (RuntimeConfig, error) {
+	switch config.Runtime.Type {
+	case "", preset.ContainerRuntimeContainerd:
+		switch config.Runtime.ConfigMode {
+		case "", containerd.ConfigModeInline:
+			return containerd.NewConfig(hostFs, config.Runtime.ConfigPath, restarter, config.Runtime.Options), nil
+		case containerd.ConfigModeDropIn:
+			return containerd.NewDropInConfig(hostFs, config.Runtime.ConfigPath, config.Runtime.DropInDir, restarter, config.Runtime.Options), nil
+		default:
+			return nil, fmt.Errorf("unsupported containerd config mode %q", config.Runtime.ConfigMode)
+		}
+	case preset.ContainerRuntimeCRIO:
+		if len(config.Runtime.Options) > 0 {
+			slog.Warn("runtime options are only supported for containerd, ignoring them", "runtime", config.Runtime.Type)
+		}
+		return crio.NewConfig(hostFs, config.Runtime.ConfigPath, restarter), nil
+	default:
+		return nil, fmt.Errorf("unsupported container runtime %q", config.Runtime.Type)
+	}
+}
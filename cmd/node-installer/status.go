@@ -0,0 +1,177 @@
+/*
+   Copyright The KWasm Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"text/tabwriter"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"github.com/spinframework/runtime-class-manager/internal/preset"
+	"github.com/spinframework/runtime-class-manager/internal/state"
+	"github.com/spinframework/runtime-class-manager/internal/termination"
+)
+
+// States of an installed shim binary.
+const (
+	BinaryOK       = "ok"
+	BinaryModified = "modified"
+	BinaryMissing  = "missing"
+)
+
+// Status is the state of the shims installed on a node.
+type Status struct {
+	Distro           string       `json:"distro"`
+	ContainerRuntime string       `json:"containerRuntime"`
+	ConfigPath       string       `json:"configPath"`
+	Shims            []ShimStatus `json:"shims"`
+}
+
+// ShimStatus is the state of a shim recorded in the lock file.
+type ShimStatus struct {
+	Name   string `json:"name"`
+	Path   string `json:"path"`
+	SHA256 string `json:"sha256"`
+	// Binary tells whether the binary on disk still matches SHA256.
+	Binary string `json:"binary"`
+	// Configured tells whether the runtime is present in the runtime config.
+	Configured bool `json:"configured"`
+}
+
+var statusOutput string
+
+// statusCmd represents the status command.
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the shims installed on the node",
+	Run: func(cmd *cobra.Command, _ []string) {
+		hostFs := afero.NewBasePathFs(afero.NewOsFs(), config.Host.RootPath)
+
+		distro, err := DetectDistro(config, hostFs)
+		if err != nil {
+			fail(1, termination.ReasonConfigNotFound, "failed to detect container runtime config", err)
+		}
+		config.Runtime.ConfigPath = distro.ConfigPath
+		config.Runtime.Type = distro.ContainerRuntime
+
+		status, err := RunStatus(config, hostFs, distro)
+		if err != nil {
+			fail(1, termination.ReasonUnknown, "failed to get status", err)
+		}
+		if err := PrintStatus(cmd.OutOrStdout(), status, statusOutput); err != nil {
+			fail(1, termination.ReasonUnknown, "failed to print status", err)
+		}
+	},
+}
+
+func init() {
+	statusCmd.Flags().StringVarP(&statusOutput, "output", "o", "table", "Output format (table, json)")
+	rootCmd.AddCommand(statusCmd)
+}
+
+// RunStatus collects the state of the shims recorded in the lock file. It
+// doesn't change anything on the node.
+func RunStatus(config Config, hostFs afero.Fs, distro preset.Settings) (Status, error) {
+	status := Status{
+		Distro:           distro.Name,
+		ContainerRuntime: config.Runtime.Type,
+		ConfigPath:       config.Runtime.ConfigPath,
+		Shims:            []ShimStatus{},
+	}
+
+	st, err := state.Get(hostFs, config.RCM.Path)
+	if err != nil {
+		return Status{}, fmt.Errorf("failed to read lock file: %w", err)
+	}
+	runtimeConfig, err := NewRuntimeConfig(config, hostFs, nil)
+	if err != nil {
+		return Status{}, err
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(st.Shims)) {
+		shim := st.Shims[name]
+		binary, err := binaryState(hostFs, shim.Path, shim.Sha256)
+		if err != nil {
+			return Status{}, fmt.Errorf("failed to check binary of shim %s: %w", name, err)
+		}
+		configured, err := runtimeConfig.HasRuntime(shim.Path)
+		if err != nil {
+			return Status{}, fmt.Errorf("failed to check runtime config of shim %s: %w", name, err)
+		}
+		status.Shims = append(status.Shims, ShimStatus{
+			Name:       name,
+			Path:       shim.Path,
+			SHA256:     hex.EncodeToString(shim.Sha256),
+			Binary:     binary,
+			Configured: configured,
+		})
+	}
+	return status, nil
+}
+
+// binaryState compares the binary at binPath with its recorded digest.
+func binaryState(hostFs afero.Fs, binPath string, digest []byte) (string, error) {
+	f, err := hostFs.Open(binPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return BinaryMissing, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	if !slices.Equal(h.Sum(nil), digest) {
+		return BinaryModified, nil
+	}
+	return BinaryOK, nil
+}
+
+// PrintStatus writes the status in the given format.
+func PrintStatus(w io.Writer, status Status, format string) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(status)
+	case "table":
+		fmt.Fprintf(w, "Distro:            %s\n", status.Distro)
+		fmt.Fprintf(w, "Container runtime: %s\n", status.ContainerRuntime)
+		fmt.Fprintf(w, "Config:            %s\n\n", status.ConfigPath)
+
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0) //nolint:mnd // padding
+		fmt.Fprintln(tw, "SHIM\tPATH\tSHA256\tBINARY\tCONFIGURED")
+		for _, shim := range status.Shims {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%t\n", shim.Name, shim.Path, shim.SHA256, shim.Binary, shim.Configured)
+		}
+		return tw.Flush()
+	default:
+		return fmt.Errorf("unsupported output format %q", format)
+	}
+}
@@ -0,0 +1,134 @@
+/*
+   Copyright The KWasm Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package main_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	main "github.com/spinframework/runtime-class-manager/cmd/node-installer"
+	"github.com/spinframework/runtime-class-manager/internal/containerd"
+	"github.com/spinframework/runtime-class-manager/internal/preset"
+	tests "github.com/spinframework/runtime-class-manager/tests/node-installer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_RunStatus(t *testing.T) {
+	config := main.Config{
+		struct {
+			Name          string
+			ConfigPath    string
+			Options       map[string]string
+			Type          string
+			ConfigMode    string
+			DropInDir     string
+			SocketPath    string
+			HealthTimeout time.Duration
+		}{"containerd", "/etc/containerd/config.toml", nil, "containerd", "", "", "", 0},
+		struct {
+			Path      string
+			AssetPath string
+		}{"/opt/rcm", "/assets"},
+		struct{ RootPath string }{"/containerd/missing-containerd-shim-config"},
+		false,
+		false,
+		false,
+	}
+	hostFs := tests.FixtureFs("../../testdata/node-installer/containerd/missing-containerd-shim-config")
+	require.NoError(t, main.RunInstall(config, tests.FixtureFs("../../testdata/node-installer"), hostFs, nullRestarter{}))
+
+	// Drift from the installed state
+	require.NoError(t, afero.WriteFile(hostFs, "/opt/rcm/bin/wasmtime-v1/5064503673b33772f86aa61d085e01aabcebf319cff1697a8e0c968d8ef35934/containerd-shim-wasmtime-v1", []byte("modified"), 0o755))
+	_, err := containerd.NewConfig(hostFs, "/etc/containerd/config.toml", nil, nil).RemoveRuntime("/opt/rcm/bin/wasmtime-v1/5064503673b33772f86aa61d085e01aabcebf319cff1697a8e0c968d8ef35934/containerd-shim-wasmtime-v1")
+	require.NoError(t, err)
+
+	status, err := main.RunStatus(config, hostFs, preset.Default)
+	require.NoError(t, err)
+
+	assert.Equal(t, "default", status.Distro)
+	assert.Equal(t, "containerd", status.ContainerRuntime)
+	require.Len(t, status.Shims, 2)
+	assert.Equal(t, "spin-v1", status.Shims[0].Name)
+	assert.Equal(t, "/opt/rcm/bin/spin-v1/6da5e8f17a9bfa9cb04cf22c87b6475394ecec3af4fdc337f72d6dbf3319ea52/containerd-shim-spin-v1", status.Shims[0].Path)
+	assert.Equal(t, main.BinaryOK, status.Shims[0].Binary)
+	assert.True(t, status.Shims[0].Configured)
+	assert.Equal(t, "wasmtime-v1", status.Shims[1].Name)
+	assert.Equal(t, main.BinaryModified, status.Shims[1].Binary)
+	assert.False(t, status.Shims[1].Configured)
+
+	// A removed binary is reported as missing
+	require.NoError(t, hostFs.Remove("/opt/rcm/bin/spin-v1/6da5e8f17a9bfa9cb04cf22c87b6475394ecec3af4fdc337f72d6dbf3319ea52/containerd-shim-spin-v1"))
+	status, err = main.RunStatus(config, hostFs, preset.Default)
+	require.NoError(t, err)
+	assert.Equal(t, main.BinaryMissing, status.Shims[0].Binary)
+}
+
+func Test_PrintStatus(t *testing.T) {
+	status := main.Status{
+		Distro:           "k3s",
+		ContainerRuntime: "containerd",
+		ConfigPath:       "/var/lib/rancher/k3s/agent/etc/containerd/config.toml.tmpl",
+		Shims: []main.ShimStatus{
+			{Name: "spin-v1", Path: "/opt/rcm/bin/containerd-shim-spin-v1", SHA256: "abc123", Binary: main.BinaryOK, Configured: true},
+		},
+	}
+
+	tests := []struct {
+		format  string
+		want    string
+		wantErr bool
+	}{
+		{"table", `Distro:            k3s
+Container runtime: containerd
+Config:            /var/lib/rancher/k3s/agent/etc/containerd/config.toml.tmpl
+
+SHIM     PATH                                  SHA256  BINARY  CONFIGURED
+spin-v1  /opt/rcm/bin/containerd-shim-spin-v1  abc123  ok      true
+`, false},
+		{"json", `{
+  "distro": "k3s",
+  "containerRuntime": "containerd",
+  "configPath": "/var/lib/rancher/k3s/agent/etc/containerd/config.toml.tmpl",
+  "shims": [
+    {
+      "name": "spin-v1",
+      "path": "/opt/rcm/bin/containerd-shim-spin-v1",
+      "sha256": "abc123",
+      "binary": "ok",
+      "configured": true
+    }
+  ]
+}
+`, false},
+		{"yaml", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var out bytes.Buffer
+			err := main.PrintStatus(&out, status, tt.format)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, out.String())
+		})
+	}
+}
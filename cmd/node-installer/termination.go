@@ -0,0 +1,36 @@
+/*
+   Copyright The KWasm Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/spinframework/runtime-class-manager/internal/termination"
+)
+
+// fail logs err, writes a termination message with the reason of the failure,
+// so that the controller can report it, and exits with the given code.
+func fail(code int, reason termination.Reason, msg string, err error, args ...any) {
+	slog.Error(msg, append(args, "reason", reason, "error", err)...)
+	if writeErr := termination.Write(termination.DefaultPath, reason, fmt.Errorf("%s: %w", msg, err)); writeErr != nil {
+		// Not running in a Kubernetes container
+		slog.Debug("failed to write termination message", "error", writeErr)
+	}
+	os.Exit(code)
+}
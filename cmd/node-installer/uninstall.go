@@ -19,76 +19,105 @@ package main
 import (
 	"fmt"
 	"log/slog"
-	"os"
 	"path"
 
 	"github.com/spf13/afero"
 	"github.com/spf13/cobra"
 
-	"github.com/spinframework/runtime-class-manager/internal/containerd"
 	"github.com/spinframework/runtime-class-manager/internal/shim"
+	"github.com/spinframework/runtime-class-manager/internal/state"
+	"github.com/spinframework/runtime-class-manager/internal/termination"
 )
 
 // uninstallCmd represents the uninstall command.
 var uninstallCmd = &cobra.Command{
 	Use:   "uninstall",
-	Short: "Uninstall containerd shims",
-	Run: func(_ *cobra.Command, _ []string) {
+	Short: "Uninstall shims",
+	Run: func(cmd *cobra.Command, _ []string) {
 		rootFs := afero.NewOsFs()
-		hostFs := afero.NewBasePathFs(rootFs, config.Host.RootPath)
+		hostFs, dryRunFs := newHostFs(config, rootFs)
 
 		distro, err := DetectDistro(config, hostFs)
 		if err != nil {
-			slog.Error("failed to detect containerd config", "error", err)
-			os.Exit(1)
+			fail(1, termination.ReasonConfigNotFound, "failed to detect container runtime config", err)
 		}
 
 		config.Runtime.ConfigPath = distro.ConfigPath
+		config.Runtime.Type = distro.ContainerRuntime
 
 		config.Runtime.Options, err = RuntimeOptions()
 		if err != nil {
-			slog.Error("failed to get runtime options", "error", err)
-			os.Exit(1)
+			fail(1, termination.ReasonInvalidConfig, "failed to get runtime options", err)
 		}
 
 		if err := RunUninstall(config, rootFs, hostFs, distro.Restarter); err != nil {
-			slog.Error("failed to uninstall", "error", err)
-			os.Exit(1)
+			fail(1, termination.ReasonOf(err), "failed to uninstall", err)
+		}
+		if dryRunFs != nil {
+			if err := printDryRun(cmd.OutOrStdout(), dryRunFs); err != nil {
+				fail(1, termination.ReasonUnknown, "failed to print changes", err)
+			}
 		}
 	},
 }
 
 func init() {
+	uninstallCmd.Flags().BoolVar(&config.GarbageCollect, "gc", false, "Also remove the previous versions of the shim that are kept for rollbacks")
+	uninstallCmd.Flags().BoolVar(&config.DryRun, "dry-run", false, "Print the changes to the shims and the container runtime config instead of making them, without restarting the container runtime")
 	rootCmd.AddCommand(uninstallCmd)
 }
 
-func RunUninstall(config Config, rootFs, hostFs afero.Fs, restarter containerd.Restarter) error {
+func RunUninstall(config Config, rootFs, hostFs afero.Fs, restarter Restarter) error {
 	slog.Info("uninstall called", "shim", config.Runtime.Name)
 	shimName := config.Runtime.Name
 	runtimeName := path.Join(config.RCM.Path, "bin", shimName)
 
-	containerdConfig := containerd.NewConfig(hostFs, config.Runtime.ConfigPath, restarter, config.Runtime.Options)
+	runtimeConfig, err := NewRuntimeConfig(config, hostFs, restarter)
+	if err != nil {
+		return termination.WithReason(termination.ReasonInvalidConfig, err)
+	}
 	shimConfig := shim.NewConfig(rootFs, hostFs, config.RCM.AssetPath, config.RCM.Path)
 
+	// Don't change the runtime config while an installer may restore it
+	unlock, err := state.LockRuntimeConfig(hostFs, config.RCM.Path)
+	if err != nil {
+		return termination.WithReason(termination.ReasonConfigUpdateFailed, err)
+	}
+	defer unlock() //nolint:errcheck // the lock is released when the file is closed in any case
+
 	binPath, err := shimConfig.Uninstall(shimName)
 	if err != nil {
-		return fmt.Errorf("failed to delete shim '%s': %w", runtimeName, err)
+		return termination.WithReason(termination.ReasonShimUninstallFailed, fmt.Errorf("failed to delete shim '%s': %w", runtimeName, err))
 	}
 
-	configChanged, err := containerdConfig.RemoveRuntime(binPath)
+	if config.GarbageCollect {
+		removed, err := shimConfig.GarbageCollect(shimName)
+		if err != nil {
+			return termination.WithReason(termination.ReasonShimUninstallFailed, fmt.Errorf("failed to remove previous versions of shim '%s': %w", runtimeName, err))
+		}
+		for _, p := range removed {
+			slog.Info("removed previous version of shim", "shim", shimName, "path", p)
+		}
+	}
+
+	configChanged, err := runtimeConfig.RemoveRuntime(binPath)
 	if err != nil {
-		return fmt.Errorf("failed to write containerd config for shim '%s': %w", runtimeName, err)
+		return termination.WithReason(termination.ReasonConfigUpdateFailed, fmt.Errorf("failed to write runtime config for shim '%s': %w", runtimeName, err))
 	}
 
 	if !configChanged {
 		slog.Info("nothing changed, nothing more to do")
 		return nil
 	}
+	if config.DryRun {
+		slog.Info("dry run, not restarting container runtime", "runtime", config.Runtime.Type)
+		return nil
+	}
 
-	slog.Info("restarting containerd")
-	err = containerdConfig.RestartRuntime()
+	slog.Info("restarting container runtime", "runtime", config.Runtime.Type)
+	err = runtimeConfig.RestartRuntime()
 	if err != nil {
-		return fmt.Errorf("failed to restart containerd: %w", err)
+		return termination.WithReason(termination.ReasonRestartFailed, fmt.Errorf("failed to restart container runtime: %w", err))
 	}
 
 	return nil
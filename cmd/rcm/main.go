@@ -35,6 +35,7 @@ import (
 
 	runtimev1alpha1 "github.com/spinframework/runtime-class-manager/api/v1alpha1"
 	"github.com/spinframework/runtime-class-manager/internal/controller"
+	webhookv1alpha1 "github.com/spinframework/runtime-class-manager/internal/webhook/v1alpha1"
 	//+kubebuilder:scaffold:imports
 )
 
@@ -91,8 +92,10 @@ func main() {
 	}
 
 	if err = (&controller.ShimReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:    mgr.GetClient(),
+		Scheme:    mgr.GetScheme(),
+		Recorder:  mgr.GetEventRecorder("runtime-class-manager"),
+		APIReader: mgr.GetAPIReader(),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Shim")
 		os.Exit(1)
@@ -105,12 +108,21 @@ func main() {
 	// 	os.Exit(1)
 	// }
 	if err = (&controller.JobReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:    mgr.GetClient(),
+		Scheme:    mgr.GetScheme(),
+		Recorder:  mgr.GetEventRecorder("runtime-class-manager"),
+		APIReader: mgr.GetAPIReader(),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Job")
 		os.Exit(1)
 	}
+	// Webhooks require serving certificates, so they are only enabled on request.
+	if os.Getenv("ENABLE_WEBHOOKS") == "true" {
+		if err = webhookv1alpha1.SetupShimWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "Shim")
+			os.Exit(1)
+		}
+	}
 	//+kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
@@ -0,0 +1,242 @@
+/*
+   Copyright The KWasm Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package archive extracts shim binaries from downloaded artifacts.
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Format is the format of an artifact.
+type Format string
+
+const (
+	FormatRaw     Format = "raw"
+	FormatTar     Format = "tar"
+	FormatTarGzip Format = "tar.gz"
+	FormatTarZstd Format = "tar.zst"
+	FormatZip     Format = "zip"
+)
+
+const shimPrefix = "containerd-shim-"
+
+var (
+	magicGzip = []byte{0x1f, 0x8b}
+	magicZstd = []byte{0x28, 0xb5, 0x2f, 0xfd}
+	magicZip  = []byte("PK\x03\x04")
+	magicTar  = []byte("ustar")
+)
+
+// tarMagicOffset is the offset of the magic field in a tar header.
+const tarMagicOffset = 257
+
+// DetectFormat detects the format of an artifact from its first bytes.
+// Anything that is not a known archive format is considered a raw binary.
+func DetectFormat(r io.ReaderAt) (Format, error) {
+	header := make([]byte, tarMagicOffset+len(magicTar))
+	n, err := r.ReadAt(header, 0)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	header = header[:n]
+
+	switch {
+	case bytes.HasPrefix(header, magicGzip):
+		return FormatTarGzip, nil
+	case bytes.HasPrefix(header, magicZstd):
+		return FormatTarZstd, nil
+	case bytes.HasPrefix(header, magicZip):
+		return FormatZip, nil
+	case len(header) == tarMagicOffset+len(magicTar) && bytes.Equal(header[tarMagicOffset:], magicTar):
+		return FormatTar, nil
+	default:
+		return FormatRaw, nil
+	}
+}
+
+// ExtractShim writes the shim binary contained in an artifact to dest. Raw
+// binaries are copied as is. Archives are searched for a file named like dest,
+// or, if there is none, for the only file named containerd-shim-*. dest is
+// replaced atomically, so it never contains a partially written binary.
+func ExtractShim(src *os.File, dest string) (Format, error) {
+	format, err := DetectFormat(src)
+	if err != nil {
+		return "", err
+	}
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		return format, err
+	}
+
+	binaryName := filepath.Base(dest)
+	var binary io.Reader
+	switch format {
+	case FormatRaw:
+		binary = src
+	case FormatZip:
+		binary, err = findInZip(src, binaryName)
+	case FormatTar, FormatTarGzip, FormatTarZstd:
+		binary, err = findInTar(src, format, binaryName)
+	}
+	if err != nil {
+		return format, fmt.Errorf("failed to extract %s from %s archive: %w", binaryName, format, err)
+	}
+	return format, writeExecutable(dest, binary)
+}
+
+// findInTar returns a reader for the shim binary within a tar archive.
+func findInTar(src io.ReadSeeker, format Format, binaryName string) (io.Reader, error) {
+	open := func() (*tar.Reader, error) {
+		if _, err := src.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		switch format {
+		case FormatTarGzip:
+			gz, err := gzip.NewReader(src)
+			if err != nil {
+				return nil, err
+			}
+			return tar.NewReader(gz), nil
+		case FormatTarZstd:
+			zr, err := zstd.NewReader(src, zstd.WithDecoderConcurrency(1))
+			if err != nil {
+				return nil, err
+			}
+			return tar.NewReader(zr.IOReadCloser()), nil
+		default:
+			return tar.NewReader(src), nil
+		}
+	}
+
+	tr, err := open()
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		if path.Base(hdr.Name) == binaryName {
+			return tr, nil
+		}
+		names = append(names, hdr.Name)
+	}
+
+	name, err := onlyShim(names, binaryName)
+	if err != nil {
+		return nil, err
+	}
+	// Read the archive again up to the only shim binary.
+	if tr, err = open(); err != nil {
+		return nil, err
+	}
+	for {
+		hdr, err := tr.Next()
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Name == name && hdr.Typeflag == tar.TypeReg {
+			return tr, nil
+		}
+	}
+}
+
+// findInZip returns a reader for the shim binary within a zip archive.
+func findInZip(src *os.File, binaryName string) (io.Reader, error) {
+	info, err := src.Stat()
+	if err != nil {
+		return nil, err
+	}
+	zr, err := zip.NewReader(src, info.Size())
+	if err != nil {
+		return nil, err
+	}
+
+	files := map[string]*zip.File{}
+	var names []string
+	for _, f := range zr.File {
+		if !f.Mode().IsRegular() {
+			continue
+		}
+		if path.Base(f.Name) == binaryName {
+			return f.Open()
+		}
+		files[f.Name] = f
+		names = append(names, f.Name)
+	}
+
+	name, err := onlyShim(names, binaryName)
+	if err != nil {
+		return nil, err
+	}
+	return files[name].Open()
+}
+
+// onlyShim returns the only name of a file named containerd-shim-*.
+func onlyShim(names []string, binaryName string) (string, error) {
+	var shims []string
+	for _, name := range names {
+		if strings.HasPrefix(path.Base(name), shimPrefix) {
+			shims = append(shims, name)
+		}
+	}
+	if len(shims) != 1 {
+		return "", fmt.Errorf("found %d files named %s* instead of %s", len(shims), shimPrefix, binaryName)
+	}
+	return shims[0], nil
+}
+
+// writeExecutable writes an executable file by renaming a temporary file.
+func writeExecutable(dest string, r io.Reader) error {
+	tmp, err := os.CreateTemp(filepath.Dir(dest), ".shim-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", dest, err)
+	}
+	if err := tmp.Chmod(0o755); err != nil { //nolint:mnd // file permissions
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), dest)
+}
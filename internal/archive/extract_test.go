@@ -0,0 +1,142 @@
+/*
+   Copyright The KWasm Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package archive_test
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/spinframework/runtime-class-manager/internal/archive"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type file struct {
+	name    string
+	content string
+}
+
+func tarArchive(t *testing.T, files []file) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, f := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: f.name, Mode: 0o755, Size: int64(len(f.content)), Typeflag: tar.TypeReg}))
+		_, err := tw.Write([]byte(f.content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	return buf.Bytes()
+}
+
+func compress(t *testing.T, data []byte, newWriter func(io.Writer) (io.WriteCloser, error)) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := newWriter(&buf)
+	require.NoError(t, err)
+	_, err = w.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func gzipWriter(w io.Writer) (io.WriteCloser, error) { return gzip.NewWriter(w), nil }
+
+func zstdWriter(w io.Writer) (io.WriteCloser, error) { return zstd.NewWriter(w) }
+
+func zipArchive(t *testing.T, files []file) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, f := range files {
+		w, err := zw.Create(f.name)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(f.content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func TestExtractShim(t *testing.T) {
+	files := []file{
+		{"LICENSE", "license"},
+		{"containerd-shim-spin-v2", "shim binary"},
+	}
+	nested := []file{
+		{"dist/README.md", "readme"},
+		{"dist/containerd-shim-spin", "nested shim binary"},
+	}
+
+	tests := []struct {
+		name       string
+		artifact   []byte
+		wantFormat archive.Format
+		want       string
+		wantErr    bool
+	}{
+		{"raw binary", []byte("\x7fELF raw binary"), archive.FormatRaw, "\x7fELF raw binary", false},
+		{"tar", tarArchive(t, files), archive.FormatTar, "shim binary", false},
+		{"tar.gz", compress(t, tarArchive(t, files), gzipWriter), archive.FormatTarGzip, "shim binary", false},
+		{"tar.zst", compress(t, tarArchive(t, files), zstdWriter), archive.FormatTarZstd, "shim binary", false},
+		{"zip", zipArchive(t, files), archive.FormatZip, "shim binary", false},
+		{"tar.gz with the only shim nested", compress(t, tarArchive(t, nested), gzipWriter), archive.FormatTarGzip, "nested shim binary", false},
+		{"zip with the only shim nested", zipArchive(t, nested), archive.FormatZip, "nested shim binary", false},
+		{"tar.gz without shim", compress(t, tarArchive(t, files[:1]), gzipWriter), archive.FormatTarGzip, "", true},
+		{
+			"tar.gz with ambiguous shims",
+			compress(t, tarArchive(t, []file{{"containerd-shim-a", "a"}, {"containerd-shim-b", "b"}}), gzipWriter),
+			archive.FormatTarGzip, "", true,
+		},
+		{"truncated tar.gz", compress(t, tarArchive(t, files), gzipWriter)[:20], archive.FormatTarGzip, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			srcPath := filepath.Join(dir, "artifact")
+			require.NoError(t, os.WriteFile(srcPath, tt.artifact, 0o600))
+			src, err := os.Open(srcPath)
+			require.NoError(t, err)
+			defer src.Close()
+			dest := filepath.Join(dir, "containerd-shim-spin-v2")
+
+			format, err := archive.ExtractShim(src, dest)
+			assert.Equal(t, tt.wantFormat, format)
+			if tt.wantErr {
+				require.Error(t, err)
+				assert.NoFileExists(t, dest)
+				return
+			}
+			require.NoError(t, err)
+
+			content, err := os.ReadFile(dest)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, string(content))
+			info, err := os.Stat(dest)
+			require.NoError(t, err)
+			assert.Equal(t, os.FileMode(0o755), info.Mode().Perm())
+		})
+	}
+}
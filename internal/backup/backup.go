@@ -0,0 +1,140 @@
+/*
+   Copyright The KWasm Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package backup snapshots files on the host before they are changed, so
+// that they can be restored if the change breaks the node.
+package backup
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path"
+	"strconv"
+
+	"github.com/spf13/afero"
+)
+
+// Snapshot holds the previous state of files. Files that didn't exist are
+// removed on restore.
+type Snapshot struct {
+	fs    afero.Fs
+	dir   string
+	files []file
+}
+
+type file struct {
+	path string
+	// backupPath is the path of the copy of the file, or empty if the file
+	// didn't exist.
+	backupPath string
+	mode       os.FileMode
+}
+
+// New returns an empty Snapshot that keeps copies of files in dir.
+func New(fs afero.Fs, dir string) *Snapshot {
+	return &Snapshot{fs: fs, dir: dir}
+}
+
+// Add records the current state of a file. Files that have been added before
+// are ignored, so the snapshot keeps their oldest state.
+func (s *Snapshot) Add(filePath string) error {
+	for _, f := range s.files {
+		if f.path == filePath {
+			return nil
+		}
+	}
+
+	info, err := s.fs.Stat(filePath)
+	if errors.Is(err, os.ErrNotExist) {
+		s.files = append(s.files, file{path: filePath})
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := s.fs.MkdirAll(s.dir, 0o755); err != nil { //nolint:mnd // file permissions
+		return err
+	}
+	backupPath := path.Join(s.dir, strconv.Itoa(len(s.files))+"-"+path.Base(filePath))
+	if err := copyFile(s.fs, filePath, backupPath, info.Mode()); err != nil {
+		return fmt.Errorf("failed to back up %s: %w", filePath, err)
+	}
+	slog.Debug("backed up file", "path", filePath, "backup", backupPath)
+	s.files = append(s.files, file{path: filePath, backupPath: backupPath, mode: info.Mode()})
+	return nil
+}
+
+// Restore restores all files to their recorded state.
+func (s *Snapshot) Restore() error {
+	var errs []error
+	for _, f := range s.files {
+		if f.backupPath == "" {
+			if err := s.fs.Remove(f.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		if err := copyFile(s.fs, f.backupPath, f.path, f.mode); err != nil {
+			errs = append(errs, fmt.Errorf("failed to restore %s: %w", f.path, err))
+			continue
+		}
+		slog.Info("restored file", "path", f.path)
+	}
+	return errors.Join(errs...)
+}
+
+// Discard removes the copies of the files.
+func (s *Snapshot) Discard() error {
+	s.files = nil
+	return s.fs.RemoveAll(s.dir)
+}
+
+// copyFile copies src to dst. It is copied to a temporary file in the
+// directory of dst first, which replaces dst once it is complete, so that dst
+// is never lost or left half-written.
+func copyFile(fs afero.Fs, src, dst string, mode os.FileMode) error {
+	in, err := fs.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := afero.TempFile(fs, path.Dir(dst), "."+path.Base(dst)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer fs.Remove(out.Name()) //nolint:errcheck // the file is gone after it has been renamed
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	if err := fs.Chmod(out.Name(), mode); err != nil {
+		return err
+	}
+	return fs.Rename(out.Name(), dst)
+}
@@ -0,0 +1,66 @@
+/*
+   Copyright The KWasm Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package backup_test
+
+import (
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/spinframework/runtime-class-manager/internal/backup"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshot(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "/etc/containerd/config.toml", []byte("version = 2\n"), 0o644))
+	require.NoError(t, afero.WriteFile(fs, "/opt/rcm/bin/containerd-shim-spin-v1", []byte("v1"), 0o755))
+
+	snapshot := backup.New(fs, "/opt/rcm/backup")
+	for _, path := range []string{
+		"/etc/containerd/config.toml",
+		"/opt/rcm/bin/containerd-shim-spin-v1",
+		"/etc/containerd/conf.d/rcm-spin-v1.toml",
+	} {
+		require.NoError(t, snapshot.Add(path))
+	}
+
+	// Change all files
+	require.NoError(t, afero.WriteFile(fs, "/etc/containerd/config.toml", []byte("broken"), 0o644))
+	require.NoError(t, afero.WriteFile(fs, "/opt/rcm/bin/containerd-shim-spin-v1", []byte("v2"), 0o755))
+	require.NoError(t, afero.WriteFile(fs, "/etc/containerd/conf.d/rcm-spin-v1.toml", []byte("new"), 0o644))
+	// Adding a file again keeps its oldest state
+	require.NoError(t, snapshot.Add("/etc/containerd/config.toml"))
+
+	require.NoError(t, snapshot.Restore())
+
+	config, err := afero.ReadFile(fs, "/etc/containerd/config.toml")
+	require.NoError(t, err)
+	assert.Equal(t, "version = 2\n", string(config))
+	binary, err := afero.ReadFile(fs, "/opt/rcm/bin/containerd-shim-spin-v1")
+	require.NoError(t, err)
+	assert.Equal(t, "v1", string(binary))
+	info, err := fs.Stat("/opt/rcm/bin/containerd-shim-spin-v1")
+	require.NoError(t, err)
+	assert.Equal(t, "-rwxr-xr-x", info.Mode().String())
+	_, err = fs.Stat("/etc/containerd/conf.d/rcm-spin-v1.toml")
+	require.ErrorIs(t, err, afero.ErrFileNotFound)
+
+	require.NoError(t, snapshot.Discard())
+	_, err = fs.Stat("/opt/rcm/backup")
+	require.ErrorIs(t, err, afero.ErrFileNotFound)
+}
@@ -17,16 +17,25 @@
 package containerd
 
 import (
+	"errors"
 	"fmt"
 	"log/slog"
+	"maps"
 	"os"
 	"path"
-	"strings"
+	"slices"
 
 	"github.com/spf13/afero"
 	"github.com/spinframework/runtime-class-manager/internal/shim"
 )
 
+const (
+	// criDomainV2 is the config domain of the CRI plugin for containerd 1.x (config version 2)
+	criDomainV2 = "io.containerd.grpc.v1.cri"
+	// criDomainV3 is the config domain of the CRI plugin for containerd 2.x (config version 3)
+	criDomainV3 = "io.containerd.cri.v1.runtime"
+)
+
 type Restarter interface {
 	Restart() error
 }
@@ -36,6 +45,9 @@ type Config struct {
 	configPath     string
 	restarter      Restarter
 	runtimeOptions map[string]string
+	// dropInDir is the directory runtimes are configured in, if they are not
+	// added to the config at configPath itself.
+	dropInDir string
 }
 
 func NewConfig(hostFs afero.Fs, configPath string, restarter Restarter, runtimeOptions map[string]string) *Config {
@@ -47,95 +59,223 @@ func NewConfig(hostFs afero.Fs, configPath string, restarter Restarter, runtimeO
 	}
 }
 
-func (c *Config) AddRuntime(shimPath string) error {
+// AddRuntime adds the runtime of a shim to the containerd config, or updates
+// its runtime type and options if it already exists. It returns whether the
+// config was changed.
+func (c *Config) AddRuntime(shimPath string) (bool, error) {
+	if c.dropInDir != "" {
+		return c.addDropIn(shimPath)
+	}
+
 	runtimeName := shim.RuntimeName(path.Base(shimPath))
 	l := slog.With("runtime", runtimeName)
 
 	// Containerd config file needs to exist, otherwise return the error
-	data, err := afero.ReadFile(c.hostFs, c.configPath)
+	doc, err := c.read()
 	if err != nil {
-		return err
+		return false, err
 	}
 
-	// Warn if config.toml already contains runtimeName
-	if strings.Contains(string(data), runtimeName) {
-		l.Info("runtime config already exists, skipping")
-		return nil
+	runtimePath := runtimeTablePath(criDomain(doc), runtimeName)
+	if doc.header(runtimePath) < 0 {
+		if _, ok := doc.lookup(runtimePath); ok {
+			return false, fmt.Errorf("runtime %s is not configured in a table of its own, refusing to change it", runtimeName)
+		}
+		if err := doc.splice(len(doc.data), len(doc.data), generateConfig(runtimePath, runtimeName, shimPath, c.runtimeOptions)); err != nil {
+			return false, err
+		}
+		l.Info("adding runtime config")
+		return true, c.write(doc)
 	}
 
-	cfg := generateConfig(shimPath, runtimeName, c.runtimeOptions, data)
+	changed, err := c.updateRuntime(doc, runtimePath, shimPath)
+	if err != nil {
+		return false, err
+	}
+	if !changed {
+		l.Info("runtime config is up to date")
+		return false, nil
+	}
+	l.Info("updating runtime config")
+	return true, c.write(doc)
+}
 
-	// Open file in append mode
-	file, err := c.hostFs.OpenFile(c.configPath, os.O_APPEND|os.O_WRONLY, 0o644) //nolint:mnd // file permissions
+// updateRuntime sets the runtime type of an existing runtime, and makes its
+// options match the runtime options. Options that are not configured anymore
+// are removed, as is the options table if there are no options left.
+func (c *Config) updateRuntime(doc *tomlDocument, runtimePath []string, shimPath string) (bool, error) {
+	changed, err := doc.setKey(doc.header(runtimePath), "runtime_type", formatString(shimPath))
 	if err != nil {
-		return err
+		return false, err
 	}
-	defer file.Close()
 
-	// Append config
-	_, err = file.WriteString(cfg)
+	optionsPath := append(slices.Clone(runtimePath), "options")
+	if doc.header(optionsPath) < 0 {
+		if _, ok := doc.lookup(optionsPath); ok {
+			return false, fmt.Errorf("options of runtime %s are not configured in a table of their own, refusing to change them", runtimePath[len(runtimePath)-1])
+		}
+		if len(c.runtimeOptions) == 0 {
+			return changed, nil
+		}
+		if err := doc.insertTable(doc.header(runtimePath), optionsPath); err != nil {
+			return false, err
+		}
+		changed = true
+	}
+
+	if len(c.runtimeOptions) == 0 {
+		header := doc.header(optionsPath)
+		return true, doc.splice(doc.exprs[header].start, doc.exprs[doc.sectionEnd(header)].end, "")
+	}
+
+	removed, err := doc.removeKeys(doc.header(optionsPath), func(key string) bool {
+		_, ok := c.runtimeOptions[key]
+		return ok
+	})
 	if err != nil {
-		return err
+		return false, err
 	}
+	changed = changed || removed
 
-	return nil
+	for _, key := range slices.Sorted(maps.Keys(c.runtimeOptions)) {
+		optionChanged, err := doc.setKey(doc.header(optionsPath), key, c.runtimeOptions[key])
+		if err != nil {
+			return false, err
+		}
+		changed = changed || optionChanged
+	}
+	return changed, nil
 }
 
-func (c *Config) RemoveRuntime(shimPath string) (changed bool, err error) {
+// RemoveRuntime removes the runtime of a shim, including its options, from the
+// containerd config. It returns whether the config was changed.
+func (c *Config) RemoveRuntime(shimPath string) (bool, error) {
+	if c.dropInDir != "" {
+		return c.removeDropIn(shimPath)
+	}
+
 	runtimeName := shim.RuntimeName(path.Base(shimPath))
 	l := slog.With("runtime", runtimeName)
 
 	// Containerd config file needs to exist, otherwise return the error
-	data, err := afero.ReadFile(c.hostFs, c.configPath)
+	doc, err := c.read()
 	if err != nil {
 		return false, err
 	}
 
-	// Warn if config.toml does not contain the runtimeName
-	if !strings.Contains(string(data), runtimeName) {
-		l.Warn("runtime config does not exist, skipping")
+	changed, err := removeRuntimeTables(doc, runtimeName)
+	if err != nil {
+		return false, err
+	}
+	if !changed {
+		if _, ok := doc.lookup(runtimeTablePath(criDomain(doc), runtimeName)); ok {
+			l.Warn("runtime config is not a table of its own, skipping")
+		} else {
+			l.Warn("runtime config does not exist, skipping")
+		}
 		return false, nil
 	}
 
-	cfg := generateConfig(shimPath, runtimeName, c.runtimeOptions, data)
+	return true, c.write(doc)
+}
 
-	// Convert the file data to a string and replace the target string with an empty string.
-	modifiedData := strings.ReplaceAll(string(data), cfg, "")
+// removeRuntimeTables removes the tables of a runtime from both config
+// versions, in case the config version changed since the runtime has been added.
+func removeRuntimeTables(doc *tomlDocument, runtimeName string) (changed bool, err error) {
+	for _, domain := range []string{criDomainV2, criDomainV3} {
+		removed, err := doc.removeTables(runtimeTablePath(domain, runtimeName), runtimeComment(runtimeName))
+		if err != nil {
+			return false, err
+		}
+		changed = changed || removed
+	}
+	return changed, nil
+}
 
-	// Write the modified data back to the file.
-	err = afero.WriteFile(c.hostFs, c.configPath, []byte(modifiedData), 0o644) //nolint:mnd // file permissions
+// HasRuntime returns whether the runtime of a shim is configured.
+func (c *Config) HasRuntime(shimPath string) (bool, error) {
+	runtimeName := shim.RuntimeName(path.Base(shimPath))
+	configPath := c.configPath
+	if c.dropInDir != "" {
+		configPath = c.dropInPath(runtimeName)
+	}
+
+	data, err := afero.ReadFile(c.hostFs, configPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
 	if err != nil {
 		return false, err
 	}
+	doc, err := parseTOML(data)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse containerd config %s: %w", configPath, err)
+	}
+	_, ok := doc.lookup(runtimeTablePath(criDomain(doc), runtimeName))
+	return ok, nil
+}
 
-	return true, nil
+// ConfigFiles returns the files AddRuntime and RemoveRuntime may change for
+// the runtime of a shim.
+func (c *Config) ConfigFiles(shimPath string) []string {
+	if c.dropInDir != "" {
+		return []string{c.configPath, c.dropInPath(shim.RuntimeName(path.Base(shimPath)))}
+	}
+	return []string{c.configPath}
 }
 
 func (c *Config) RestartRuntime() error {
 	return c.restarter.Restart()
 }
 
-func generateConfig(shimPath string, runtimeName string, runtimeOptions map[string]string, configData []byte) string {
-	// Config domain for containerd 1.0 (config version 2)
-	domain := "io.containerd.grpc.v1.cri"
-	if strings.Contains(string(configData), "version = 3") {
-		// Config domain for containerd 2.0 (config version 3)
-		domain = "io.containerd.cri.v1.runtime"
+func (c *Config) read() (*tomlDocument, error) {
+	data, err := afero.ReadFile(c.hostFs, c.configPath)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := parseTOML(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse containerd config %s: %w", c.configPath, err)
 	}
+	return doc, nil
+}
+
+func (c *Config) write(doc *tomlDocument) error {
+	return afero.WriteFile(c.hostFs, c.configPath, doc.data, 0o644) //nolint:mnd // file permissions
+}
+
+// criDomain returns the domain of the CRI plugin for the config version.
+func criDomain(doc *tomlDocument) string {
+	if version, ok := doc.lookup([]string{"version"}); ok {
+		if v, ok := version.(int64); ok && v >= 3 { //nolint:mnd // config version
+			return criDomainV3
+		}
+	}
+	return criDomainV2
+}
+
+// runtimeTablePath returns the path of the table configuring a runtime.
+func runtimeTablePath(domain, runtimeName string) []string {
+	return []string{"plugins", domain, "containerd", "runtimes", runtimeName}
+}
+
+func runtimeComment(runtimeName string) string {
+	return "# RCM runtime config for " + runtimeName
+}
 
+// generateConfig generates the tables of a runtime that is not configured yet.
+func generateConfig(runtimePath []string, runtimeName, shimPath string, runtimeOptions map[string]string) string {
 	runtimeConfiguration := fmt.Sprintf(`
-# RCM runtime config for %s
-[plugins."%s".containerd.runtimes.%s]
-runtime_type = "%s"
-`, runtimeName, domain, runtimeName, shimPath)
+%s
+%s
+runtime_type = %s
+`, runtimeComment(runtimeName), formatTableHeader(runtimePath), formatString(shimPath))
 	// Add runtime options if any are provided
 	if len(runtimeOptions) > 0 {
-		options := fmt.Sprintf(`[plugins."%s".containerd.runtimes.%s.options]`, domain, runtimeName)
-		for k, v := range runtimeOptions {
-			options += fmt.Sprintf(`
-%s = %s`, k, v)
+		runtimeConfiguration += formatTableHeader(append(slices.Clone(runtimePath), "options")) + "\n"
+		for _, k := range slices.Sorted(maps.Keys(runtimeOptions)) {
+			runtimeConfiguration += fmt.Sprintf("%s = %s\n", formatKey(k), runtimeOptions[k])
 		}
-		runtimeConfiguration += options
 	}
 	return runtimeConfiguration
 }
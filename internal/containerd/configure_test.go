@@ -101,7 +101,7 @@ runtime_type = "/opt/rcm/bin/containerd-shim-spin-v1"
 				hostFs:     tt.fields.hostFs,
 				configPath: tt.fields.configPath,
 			}
-			err := c.AddRuntime(tt.args.shimPath)
+			_, err := c.AddRuntime(tt.args.shimPath)
 
 			if tt.wantErr {
 				require.Error(t, err)
@@ -141,7 +141,8 @@ func TestConfig_AddRuntimeOptions(t *testing.T) {
 [plugins."io.containerd.grpc.v1.cri".containerd.runtimes.spin-v1]
 runtime_type = "/opt/rcm/bin/containerd-shim-spin-v1"
 [plugins."io.containerd.grpc.v1.cri".containerd.runtimes.spin-v1.options]
-SystemdCgroup = true`
+SystemdCgroup = true
+`
 	t.Run("plugin options added", func(t *testing.T) {
 		c := &Config{
 			hostFs:     tests.FixtureFs("../../testdata/node-installer/containerd/missing-containerd-shim-config"),
@@ -150,7 +151,7 @@ SystemdCgroup = true`
 				"SystemdCgroup": "true",
 			},
 		}
-		err := c.AddRuntime("/opt/rcm/bin/containerd-shim-spin-v1")
+		_, err := c.AddRuntime("/opt/rcm/bin/containerd-shim-spin-v1")
 
 		require.NoError(t, err)
 
@@ -283,7 +284,7 @@ runtime_type = "/opt/rcm/bin/containerd-shim-spin-v1"
 				hostFs:     tt.fields.hostFs,
 				configPath: tt.fields.configPath,
 			}
-			err := c.AddRuntime(tt.args.shimPath)
+			_, err := c.AddRuntime(tt.args.shimPath)
 
 			if tt.wantErr {
 				require.Error(t, err)
@@ -299,3 +300,274 @@ runtime_type = "/opt/rcm/bin/containerd-shim-spin-v1"
 		})
 	}
 }
+
+func TestConfig_EditRuntime(t *testing.T) {
+	const configPath = "/etc/containerd/config.toml"
+	const shimPath = "/opt/rcm/bin/containerd-shim-spin-v1"
+	tests := []struct {
+		name           string
+		config         string
+		runtimeOptions map[string]string
+		remove         bool
+		wantChanged    bool
+		wantErr        bool
+		wantConfig     string
+	}{
+		{
+			name: "runtime name mentioned elsewhere is added",
+			config: `version = 2
+# spin-v1 used to be configured here
+[plugins."io.containerd.grpc.v1.cri".containerd]
+  default_runtime_name = "runc"
+`,
+			wantChanged: true,
+			wantConfig: `version = 2
+# spin-v1 used to be configured here
+[plugins."io.containerd.grpc.v1.cri".containerd]
+  default_runtime_name = "runc"
+
+# RCM runtime config for spin-v1
+[plugins."io.containerd.grpc.v1.cri".containerd.runtimes.spin-v1]
+runtime_type = "/opt/rcm/bin/containerd-shim-spin-v1"
+`,
+		},
+		{
+			name: "up to date runtime is unchanged",
+			config: `version = 2
+
+# RCM runtime config for spin-v1
+[plugins."io.containerd.grpc.v1.cri".containerd.runtimes.spin-v1]
+runtime_type = '/opt/rcm/bin/containerd-shim-spin-v1'
+[plugins."io.containerd.grpc.v1.cri".containerd.runtimes.spin-v1.options]
+SystemdCgroup = true
+`,
+			runtimeOptions: map[string]string{"SystemdCgroup": "true"},
+			wantChanged:    false,
+			wantConfig: `version = 2
+
+# RCM runtime config for spin-v1
+[plugins."io.containerd.grpc.v1.cri".containerd.runtimes.spin-v1]
+runtime_type = '/opt/rcm/bin/containerd-shim-spin-v1'
+[plugins."io.containerd.grpc.v1.cri".containerd.runtimes.spin-v1.options]
+SystemdCgroup = true
+`,
+		},
+		{
+			name: "runtime type and options are updated in place",
+			config: `version = 2
+
+[plugins."io.containerd.grpc.v1.cri".containerd.runtimes.spin-v1]
+  # managed by RCM
+  runtime_type = "/usr/bin/containerd-shim-spin-v1"
+  [plugins."io.containerd.grpc.v1.cri".containerd.runtimes.spin-v1.options]
+    SystemdCgroup = false
+    BinaryName = "spin"
+
+[plugins."io.containerd.grpc.v1.cri".registry]
+  config_path = "/etc/containerd/certs.d"
+`,
+			runtimeOptions: map[string]string{"SystemdCgroup": "true", "BinaryName": `"spin"`, "Debug": "true"},
+			wantChanged:    true,
+			wantConfig: `version = 2
+
+[plugins."io.containerd.grpc.v1.cri".containerd.runtimes.spin-v1]
+  # managed by RCM
+  runtime_type = "/opt/rcm/bin/containerd-shim-spin-v1"
+  [plugins."io.containerd.grpc.v1.cri".containerd.runtimes.spin-v1.options]
+    SystemdCgroup = true
+    BinaryName = "spin"
+    Debug = true
+
+[plugins."io.containerd.grpc.v1.cri".registry]
+  config_path = "/etc/containerd/certs.d"
+`,
+		},
+		{
+			name: "options that are not configured anymore are removed",
+			config: `version = 2
+
+[plugins."io.containerd.grpc.v1.cri".containerd.runtimes.spin-v1]
+  runtime_type = "/opt/rcm/bin/containerd-shim-spin-v1"
+  [plugins."io.containerd.grpc.v1.cri".containerd.runtimes.spin-v1.options]
+    SystemdCgroup = true
+    BinaryName = "spin"
+    Debug = true
+
+[plugins."io.containerd.grpc.v1.cri".registry]
+  config_path = "/etc/containerd/certs.d"
+`,
+			runtimeOptions: map[string]string{"SystemdCgroup": "true"},
+			wantChanged:    true,
+			wantConfig: `version = 2
+
+[plugins."io.containerd.grpc.v1.cri".containerd.runtimes.spin-v1]
+  runtime_type = "/opt/rcm/bin/containerd-shim-spin-v1"
+  [plugins."io.containerd.grpc.v1.cri".containerd.runtimes.spin-v1.options]
+    SystemdCgroup = true
+
+[plugins."io.containerd.grpc.v1.cri".registry]
+  config_path = "/etc/containerd/certs.d"
+`,
+		},
+		{
+			name: "options table is removed without options",
+			config: `version = 2
+
+[plugins."io.containerd.grpc.v1.cri".containerd.runtimes.spin-v1]
+runtime_type = "/opt/rcm/bin/containerd-shim-spin-v1"
+[plugins."io.containerd.grpc.v1.cri".containerd.runtimes.spin-v1.options]
+SystemdCgroup = true
+
+[plugins."io.containerd.grpc.v1.cri".registry]
+config_path = "/etc/containerd/certs.d"
+`,
+			wantChanged: true,
+			wantConfig: `version = 2
+
+[plugins."io.containerd.grpc.v1.cri".containerd.runtimes.spin-v1]
+runtime_type = "/opt/rcm/bin/containerd-shim-spin-v1"
+
+[plugins."io.containerd.grpc.v1.cri".registry]
+config_path = "/etc/containerd/certs.d"
+`,
+		},
+		{
+			name: "emptied options table at the end is removed",
+			config: `version = 2
+
+[plugins."io.containerd.grpc.v1.cri".containerd.runtimes.spin-v1]
+runtime_type = "/opt/rcm/bin/containerd-shim-spin-v1"
+[plugins."io.containerd.grpc.v1.cri".containerd.runtimes.spin-v1.options]
+SystemdCgroup = true
+`,
+			runtimeOptions: map[string]string{},
+			wantChanged:    true,
+			wantConfig: `version = 2
+
+[plugins."io.containerd.grpc.v1.cri".containerd.runtimes.spin-v1]
+runtime_type = "/opt/rcm/bin/containerd-shim-spin-v1"
+`,
+		},
+		{
+			name: "missing options table is added",
+			config: `version = 2
+
+[plugins."io.containerd.grpc.v1.cri".containerd.runtimes.spin-v1]
+runtime_type = "/opt/rcm/bin/containerd-shim-spin-v1"
+
+[plugins."io.containerd.grpc.v1.cri".registry]
+config_path = "/etc/containerd/certs.d"
+`,
+			runtimeOptions: map[string]string{"SystemdCgroup": "true"},
+			wantChanged:    true,
+			wantConfig: `version = 2
+
+[plugins."io.containerd.grpc.v1.cri".containerd.runtimes.spin-v1]
+runtime_type = "/opt/rcm/bin/containerd-shim-spin-v1"
+[plugins."io.containerd.grpc.v1.cri".containerd.runtimes.spin-v1.options]
+SystemdCgroup = true
+
+[plugins."io.containerd.grpc.v1.cri".registry]
+config_path = "/etc/containerd/certs.d"
+`,
+		},
+		{
+			name: "runtime in an inline table is not changed",
+			config: `version = 2
+[plugins."io.containerd.grpc.v1.cri".containerd.runtimes]
+spin-v1 = { runtime_type = "/usr/bin/containerd-shim-spin-v1" }
+`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid config",
+			config:  `[plugins`,
+			wantErr: true,
+		},
+		{
+			name: "template actions are kept",
+			config: `{{ template "base" . }}
+
+[plugins."io.containerd.grpc.v1.cri".containerd.runtimes.runc]
+  runtime_type = "io.containerd.runc.v2"
+`,
+			wantChanged: true,
+			wantConfig: `{{ template "base" . }}
+
+[plugins."io.containerd.grpc.v1.cri".containerd.runtimes.runc]
+  runtime_type = "io.containerd.runc.v2"
+
+# RCM runtime config for spin-v1
+[plugins."io.containerd.grpc.v1.cri".containerd.runtimes.spin-v1]
+runtime_type = "/opt/rcm/bin/containerd-shim-spin-v1"
+`,
+		},
+		{
+			name: "runtime and options are removed",
+			config: `version = 2
+# keep this comment
+[plugins."io.containerd.grpc.v1.cri".containerd.runtimes.runc]
+  runtime_type = "io.containerd.runc.v2"
+
+# RCM runtime config for spin-v1
+[plugins."io.containerd.grpc.v1.cri".containerd.runtimes.spin-v1]
+runtime_type = "/opt/rcm/bin/containerd-shim-spin-v1"
+[plugins."io.containerd.grpc.v1.cri".containerd.runtimes.spin-v1.options]
+SystemdCgroup = true
+
+[plugins."io.containerd.grpc.v1.cri".registry]
+  config_path = "/etc/containerd/certs.d"
+`,
+			remove:      true,
+			wantChanged: true,
+			wantConfig: `version = 2
+# keep this comment
+[plugins."io.containerd.grpc.v1.cri".containerd.runtimes.runc]
+  runtime_type = "io.containerd.runc.v2"
+
+[plugins."io.containerd.grpc.v1.cri".registry]
+  config_path = "/etc/containerd/certs.d"
+`,
+		},
+		{
+			name: "runtime name mentioned elsewhere is not removed",
+			config: `version = 2
+# spin-v1 is installed by RCM
+[plugins."io.containerd.grpc.v1.cri".containerd]
+  default_runtime_name = "spin-v1"
+`,
+			remove:      true,
+			wantChanged: false,
+			wantConfig: `version = 2
+# spin-v1 is installed by RCM
+[plugins."io.containerd.grpc.v1.cri".containerd]
+  default_runtime_name = "spin-v1"
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			require.NoError(t, afero.WriteFile(fs, configPath, []byte(tt.config), 0o644))
+			c := NewConfig(fs, configPath, nil, tt.runtimeOptions)
+
+			var changed bool
+			var err error
+			if tt.remove {
+				changed, err = c.RemoveRuntime(shimPath)
+			} else {
+				changed, err = c.AddRuntime(shimPath)
+			}
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantChanged, changed)
+
+			gotContent, err := afero.ReadFile(fs, configPath)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantConfig, string(gotContent))
+		})
+	}
+}
@@ -0,0 +1,201 @@
+/*
+   Copyright The KWasm Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package containerd
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/spf13/afero"
+	"github.com/spinframework/runtime-class-manager/internal/shim"
+)
+
+// Modes of configuring runtimes.
+const (
+	// ConfigModeInline adds runtimes to the containerd config itself.
+	ConfigModeInline = "inline"
+	// ConfigModeDropIn configures each runtime in a drop-in file of its own,
+	// which is imported by the containerd config.
+	ConfigModeDropIn = "drop-in"
+)
+
+// DefaultDropInDir is the default directory of drop-in files.
+const DefaultDropInDir = "/etc/containerd/conf.d"
+
+// NewDropInConfig returns a Config that configures each runtime in a file of
+// its own in dropInDir. The only change to the containerd config at configPath
+// is importing the files in dropInDir.
+func NewDropInConfig(hostFs afero.Fs, configPath, dropInDir string, restarter Restarter, runtimeOptions map[string]string) *Config {
+	c := NewConfig(hostFs, configPath, restarter, runtimeOptions)
+	c.dropInDir = dropInDir
+	return c
+}
+
+// addDropIn writes the drop-in of a runtime and makes sure that the containerd
+// config imports it.
+func (c *Config) addDropIn(shimPath string) (bool, error) {
+	runtimeName := shim.RuntimeName(path.Base(shimPath))
+	dropInPath := c.dropInPath(runtimeName)
+	l := slog.With("runtime", runtimeName, "path", dropInPath)
+
+	// Containerd config file needs to exist, otherwise return the error
+	doc, err := c.read()
+	if err != nil {
+		return false, err
+	}
+
+	dropInChanged, err := c.writeDropIn(dropInPath, generateDropIn(doc, runtimeName, shimPath, c.runtimeOptions))
+	if err != nil {
+		return false, err
+	}
+	if dropInChanged {
+		l.Info("writing runtime config")
+	} else {
+		l.Info("runtime config is up to date")
+	}
+
+	importChanged, err := c.importDropIns(doc)
+	if err != nil {
+		return false, err
+	}
+	// Runtimes that were added to the containerd config before are moved to the drop-in
+	removed, err := removeRuntimeTables(doc, runtimeName)
+	if err != nil {
+		return false, err
+	}
+	if removed {
+		l.Info("removing runtime config from containerd config", "config", c.configPath)
+	}
+	if importChanged || removed {
+		if err := c.write(doc); err != nil {
+			return false, err
+		}
+	}
+
+	return dropInChanged || importChanged || removed, nil
+}
+
+// removeDropIn removes the drop-in of a runtime. The import of the drop-in
+// directory is kept, as other runtimes may still be configured there.
+func (c *Config) removeDropIn(shimPath string) (bool, error) {
+	runtimeName := shim.RuntimeName(path.Base(shimPath))
+	dropInPath := c.dropInPath(runtimeName)
+	l := slog.With("runtime", runtimeName, "path", dropInPath)
+
+	changed := true
+	if err := c.hostFs.Remove(dropInPath); err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			return false, err
+		}
+		changed = false
+	}
+
+	// Remove the runtime from the containerd config as well, in case it was
+	// added before drop-ins were used
+	doc, err := c.read()
+	if err != nil {
+		return false, err
+	}
+	removed, err := removeRuntimeTables(doc, runtimeName)
+	if err != nil {
+		return false, err
+	}
+	if removed {
+		if err := c.write(doc); err != nil {
+			return false, err
+		}
+	}
+
+	if !changed && !removed {
+		l.Warn("runtime config does not exist, skipping")
+		return false, nil
+	}
+	l.Info("removed runtime config")
+	return true, nil
+}
+
+// importDropIns adds the drop-in directory to the imports of the containerd
+// config, unless it is imported already.
+func (c *Config) importDropIns(doc *tomlDocument) (bool, error) {
+	pattern := path.Join(c.dropInDir, "*.toml")
+
+	var imports []string
+	if value, ok := doc.lookup([]string{"imports"}); ok {
+		list, ok := value.([]any)
+		if !ok {
+			return false, fmt.Errorf("imports of containerd config %s is not an array", c.configPath)
+		}
+		for _, item := range list {
+			imported, ok := item.(string)
+			if !ok {
+				return false, fmt.Errorf("imports of containerd config %s contains a non-string value", c.configPath)
+			}
+			if imported == pattern {
+				return false, nil
+			}
+			imports = append(imports, imported)
+		}
+	}
+
+	formatted := make([]string, 0, len(imports)+1)
+	for _, imported := range append(imports, pattern) {
+		formatted = append(formatted, formatString(imported))
+	}
+	slog.Info("importing drop-ins", "config", c.configPath, "imports", pattern)
+	return doc.setKey(-1, "imports", "["+strings.Join(formatted, ", ")+"]")
+}
+
+// writeDropIn writes a drop-in, unless it is up to date already.
+func (c *Config) writeDropIn(dropInPath, content string) (bool, error) {
+	existing, err := afero.ReadFile(c.hostFs, dropInPath)
+	switch {
+	case err == nil && string(existing) == content:
+		return false, nil
+	case err != nil && !errors.Is(err, os.ErrNotExist):
+		return false, err
+	}
+
+	if err := c.hostFs.MkdirAll(c.dropInDir, 0o755); err != nil { //nolint:mnd // file permissions
+		return false, fmt.Errorf("failed to create drop-in directory %s: %w", c.dropInDir, err)
+	}
+	if err := afero.WriteFile(c.hostFs, dropInPath, []byte(content), 0o644); err != nil { //nolint:mnd // file permissions
+		return false, err
+	}
+	return true, nil
+}
+
+func (c *Config) dropInPath(runtimeName string) string {
+	return path.Join(c.dropInDir, "rcm-"+runtimeName+".toml")
+}
+
+// generateDropIn generates the drop-in of a runtime. containerd migrates every
+// imported file on its own, so the drop-in declares the version of the config
+// importing it, defaulting to version 2.
+func generateDropIn(doc *tomlDocument, runtimeName, shimPath string, runtimeOptions map[string]string) string {
+	version := int64(2) //nolint:mnd // config version
+	if value, ok := doc.lookup([]string{"version"}); ok {
+		if v, ok := value.(int64); ok {
+			version = v
+		}
+	}
+	runtimePath := runtimeTablePath(criDomain(doc), runtimeName)
+	return fmt.Sprintf("version = %d\n", version) + generateConfig(runtimePath, runtimeName, shimPath, runtimeOptions)
+}
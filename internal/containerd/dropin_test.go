@@ -0,0 +1,184 @@
+/*
+   Copyright The KWasm Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package containerd //nolint:testpackage // whitebox test
+
+import (
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_AddDropIn(t *testing.T) {
+	const configPath = "/etc/containerd/config.toml"
+	const dropInPath = DefaultDropInDir + "/rcm-spin-v1.toml"
+	const wantDropIn = `version = 2
+
+# RCM runtime config for spin-v1
+[plugins."io.containerd.grpc.v1.cri".containerd.runtimes.spin-v1]
+runtime_type = "/opt/rcm/bin/containerd-shim-spin-v1"
+`
+	tests := []struct {
+		name        string
+		config      string
+		dropIn      string
+		wantChanged bool
+		wantConfig  string
+		wantDropIn  string
+	}{
+		{
+			name: "import is added after the version",
+			config: `version = 2
+root = "/var/lib/containerd"
+
+[plugins."io.containerd.grpc.v1.cri".containerd]
+  default_runtime_name = "runc"
+`,
+			wantChanged: true,
+			wantConfig: `version = 2
+root = "/var/lib/containerd"
+imports = ["/etc/containerd/conf.d/*.toml"]
+
+[plugins."io.containerd.grpc.v1.cri".containerd]
+  default_runtime_name = "runc"
+`,
+			wantDropIn: wantDropIn,
+		},
+		{
+			name: "existing imports are kept",
+			config: `version = 2
+imports = ["/etc/containerd/runtime_*.toml"]
+`,
+			wantChanged: true,
+			wantConfig: `version = 2
+imports = ["/etc/containerd/runtime_*.toml", "/etc/containerd/conf.d/*.toml"]
+`,
+			wantDropIn: wantDropIn,
+		},
+		{
+			name: "up to date drop-in is unchanged",
+			config: `version = 2
+imports = ['/etc/containerd/conf.d/*.toml']
+`,
+			dropIn:      wantDropIn,
+			wantChanged: false,
+			wantConfig: `version = 2
+imports = ['/etc/containerd/conf.d/*.toml']
+`,
+			wantDropIn: wantDropIn,
+		},
+		{
+			name: "runtime in the containerd config is moved to the drop-in",
+			config: `version = 2
+imports = ["/etc/containerd/conf.d/*.toml"]
+
+# RCM runtime config for spin-v1
+[plugins."io.containerd.grpc.v1.cri".containerd.runtimes.spin-v1]
+runtime_type = "/opt/rcm/bin/containerd-shim-spin-v1"
+`,
+			dropIn:      wantDropIn,
+			wantChanged: true,
+			wantConfig: `version = 2
+imports = ["/etc/containerd/conf.d/*.toml"]
+`,
+			wantDropIn: wantDropIn,
+		},
+		{
+			name: "version 3 is used for containerd 2.x",
+			config: `version = 3
+`,
+			wantChanged: true,
+			wantConfig: `version = 3
+imports = ["/etc/containerd/conf.d/*.toml"]
+`,
+			wantDropIn: `version = 3
+
+# RCM runtime config for spin-v1
+[plugins."io.containerd.cri.v1.runtime".containerd.runtimes.spin-v1]
+runtime_type = "/opt/rcm/bin/containerd-shim-spin-v1"
+`,
+		},
+		{
+			name: "import is added before template actions",
+			config: `{{ template "base" . }}
+`,
+			wantChanged: true,
+			wantConfig: `imports = ["/etc/containerd/conf.d/*.toml"]
+{{ template "base" . }}
+`,
+			wantDropIn: wantDropIn,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			require.NoError(t, afero.WriteFile(fs, configPath, []byte(tt.config), 0o644))
+			if tt.dropIn != "" {
+				require.NoError(t, afero.WriteFile(fs, dropInPath, []byte(tt.dropIn), 0o644))
+			}
+			c := NewDropInConfig(fs, configPath, DefaultDropInDir, nil, nil)
+
+			changed, err := c.AddRuntime("/opt/rcm/bin/containerd-shim-spin-v1")
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantChanged, changed)
+
+			gotConfig, err := afero.ReadFile(fs, configPath)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantConfig, string(gotConfig))
+			gotDropIn, err := afero.ReadFile(fs, dropInPath)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantDropIn, string(gotDropIn))
+		})
+	}
+}
+
+func TestConfig_RemoveDropIn(t *testing.T) {
+	const configPath = "/etc/containerd/config.toml"
+	const dropInPath = DefaultDropInDir + "/rcm-spin-v1.toml"
+	const config = `version = 2
+imports = ["/etc/containerd/conf.d/*.toml"]
+`
+	tests := []struct {
+		name        string
+		dropIn      bool
+		wantChanged bool
+	}{
+		{"existing drop-in", true, true},
+		{"missing drop-in", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			require.NoError(t, afero.WriteFile(fs, configPath, []byte(config), 0o644))
+			if tt.dropIn {
+				require.NoError(t, afero.WriteFile(fs, dropInPath, []byte("version = 2\n"), 0o644))
+			}
+			c := NewDropInConfig(fs, configPath, DefaultDropInDir, nil, nil)
+
+			changed, err := c.RemoveRuntime("/opt/rcm/bin/containerd-shim-spin-v1")
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantChanged, changed)
+
+			_, err = fs.Stat(dropInPath)
+			require.ErrorIs(t, err, afero.ErrFileNotFound)
+			gotConfig, err := afero.ReadFile(fs, configPath)
+			require.NoError(t, err)
+			assert.Equal(t, config, string(gotConfig))
+		})
+	}
+}
@@ -9,7 +9,7 @@ import (
 // and starts the service.
 // NOTE: this limits support to systems using systemctl to manage systemd.
 func InstallDbus() error {
-	cmd := nsenterCmd("systemctl", "start", "dbus", "--quiet")
+	cmd := NsenterCmd("systemctl", "start", "dbus", "--quiet")
 	if err := cmd.Run(); err == nil {
 		slog.Info("D-Bus is already installed and running")
 		return nil
@@ -31,13 +31,13 @@ func InstallDbus() error {
 	}
 	installed := false
 	for _, mgr := range managers {
-		if err := nsenterCmd(mgr.check...).Run(); err == nil {
+		if err := NsenterCmd(mgr.check...).Run(); err == nil {
 			if len(mgr.update) != 0 {
-				if err := nsenterCmd(mgr.update...).Run(); err != nil {
+				if err := NsenterCmd(mgr.update...).Run(); err != nil {
 					return fmt.Errorf("failed to update package manager %s: %w", mgr.name, err)
 				}
 			}
-			if err := nsenterCmd(mgr.install...).Run(); err != nil {
+			if err := NsenterCmd(mgr.install...).Run(); err != nil {
 				return fmt.Errorf("failed to install D-Bus with %s: %w", mgr.name, err)
 			}
 			installed = true
@@ -50,7 +50,7 @@ func InstallDbus() error {
 	}
 
 	slog.Info("restarting D-Bus")
-	cmd = nsenterCmd("systemctl", "restart", "dbus")
+	cmd = NsenterCmd("systemctl", "restart", "dbus")
 	if err := cmd.Run(); err != nil {
 		return fmt.Errorf("failed to restart D-Bus: %w", err)
 	}
@@ -42,13 +42,13 @@ func NewDefaultRestarter() Restarter {
 func (c defaultRestarter) Restart() error {
 	// If listing systemd units succeeds, prefer systemctl restart; otherwise kill pid
 	if _, err := ListSystemdUnits(); err == nil {
-		out, err := nsenterCmd("systemctl", "restart", "containerd").CombinedOutput()
+		out, err := NsenterCmd("systemctl", "restart", "containerd").CombinedOutput()
 		slog.Debug(string(out))
 		if err != nil {
 			return fmt.Errorf("unable to restart containerd: %w", err)
 		}
 	} else {
-		pid, err := getPid("containerd")
+		pid, err := GetPid("containerd")
 		if err != nil {
 			return err
 		}
@@ -74,7 +74,7 @@ func (c K0sRestarter) Restart() error {
 	}
 	service := regexp.MustCompile("k0sworker|k0scontroller").FindString(string(units))
 
-	out, err := nsenterCmd("systemctl", "restart", service).CombinedOutput()
+	out, err := NsenterCmd("systemctl", "restart", service).CombinedOutput()
 	slog.Debug(string(out))
 	if err != nil {
 		return fmt.Errorf("unable to restart %s: %w", service, err)
@@ -90,7 +90,7 @@ func (c K3sRestarter) Restart() error {
 
 	// If listing systemd units succeeds, prefer systemctl restart; otherwise kill pid
 	if _, err := ListSystemdUnits(); err == nil {
-		out, err := nsenterCmd("systemctl", "restart", "k3s").CombinedOutput()
+		out, err := NsenterCmd("systemctl", "restart", "k3s").CombinedOutput()
 		slog.Debug(string(out))
 		if err != nil {
 			return fmt.Errorf("unable to restart k3s: %w", err)
@@ -98,7 +98,7 @@ func (c K3sRestarter) Restart() error {
 	} else {
 		// Assuming k3d; PID 1 is the main k3d entrypoint which should be restarted
 		// rather than the nested k3s process
-		out, err := nsenterCmd("kill", "1").CombinedOutput()
+		out, err := NsenterCmd("kill", "1").CombinedOutput()
 		slog.Debug(string(out))
 		if err != nil {
 			return fmt.Errorf("unable to restart k3s via TERM signal to PID 1: %w", err)
@@ -110,7 +110,7 @@ func (c K3sRestarter) Restart() error {
 type MicroK8sRestarter struct{}
 
 func (c MicroK8sRestarter) Restart() error {
-	out, err := nsenterCmd("systemctl", "restart", "snap.microk8s.daemon-containerd").CombinedOutput()
+	out, err := NsenterCmd("systemctl", "restart", "snap.microk8s.daemon-containerd").CombinedOutput()
 	slog.Debug(string(out))
 	if err != nil {
 		return fmt.Errorf("unable to restart snap.microk8s.daemon-containerd: %w", err)
@@ -130,7 +130,7 @@ func (c RKE2Restarter) Restart() error {
 	}
 	service := regexp.MustCompile("rke2-agent|rke2-server").FindString(string(units))
 
-	out, err := nsenterCmd("systemctl", "restart", service).CombinedOutput()
+	out, err := NsenterCmd("systemctl", "restart", service).CombinedOutput()
 	slog.Debug(string(out))
 	if err != nil {
 		return fmt.Errorf("unable to restart %s: %w", service, err)
@@ -140,32 +140,34 @@ func (c RKE2Restarter) Restart() error {
 }
 
 func ListSystemdUnits() ([]byte, error) {
-	return nsenterCmd("systemctl", "list-units", "--type", "service").CombinedOutput()
+	return NsenterCmd("systemctl", "list-units", "--type", "service").CombinedOutput()
 }
 
-func nsenterCmd(cmd ...string) *exec.Cmd {
+// NsenterCmd returns a command that runs cmd in the mount namespace of the host.
+func NsenterCmd(cmd ...string) *exec.Cmd {
 	// #nosec G204 G702
 	return exec.CommandContext(context.Background(), "nsenter",
 		append([]string{fmt.Sprintf("-m/%s/proc/1/ns/mnt", os.Getenv("HOST_ROOT")), "--"}, cmd...)...)
 }
 
-func getPid(executable string) (int, error) {
+// GetPid returns the pid of the only process running executable.
+func GetPid(executable string) (int, error) {
 	processes, err := psProcesses()
 	if err != nil {
 		return 0, fmt.Errorf("could not get processes: %w", err)
 	}
 
-	var containerdProcesses = []ps.Process{}
+	var matchingProcesses = []ps.Process{}
 
 	for _, process := range processes {
 		if process.Executable() == executable {
-			containerdProcesses = append(containerdProcesses, process)
+			matchingProcesses = append(matchingProcesses, process)
 		}
 	}
 
-	if len(containerdProcesses) != 1 {
-		return 0, fmt.Errorf("need exactly one %s process, found: %d", executable, len(containerdProcesses))
+	if len(matchingProcesses) != 1 {
+		return 0, fmt.Errorf("need exactly one %s process, found: %d", executable, len(matchingProcesses))
 	}
 
-	return containerdProcesses[0].Pid(), nil
+	return matchingProcesses[0].Pid(), nil
 }
@@ -29,7 +29,7 @@ func (p *mockProcess) PPid() int {
 	return 0
 }
 
-func Test_getPid(t *testing.T) {
+func Test_GetPid(t *testing.T) {
 	tests := []struct {
 		name             string
 		psProccessesMock func() ([]ps.Process, error)
@@ -57,7 +57,7 @@ func Test_getPid(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			psProcesses = tt.psProccessesMock
-			got, err := getPid("containerd")
+			got, err := GetPid("containerd")
 
 			if tt.wantErr {
 				require.Error(t, err)
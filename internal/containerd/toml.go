@@ -0,0 +1,330 @@
+/*
+   Copyright The KWasm Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package containerd
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"github.com/pelletier/go-toml/v2/unstable"
+)
+
+// bareKey matches keys that don't need to be quoted.
+var bareKey = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// tomlDocument is a TOML document that is edited in place, so that comments
+// and the layout of everything that is not edited are kept.
+type tomlDocument struct {
+	data  []byte
+	exprs []tomlExpr
+	// values is the decoded document.
+	values map[string]any
+}
+
+// tomlExpr is a top-level expression of a TOML document.
+type tomlExpr struct {
+	kind unstable.Kind
+	// key is the path of a table header, or the key of a key-value pair
+	// relative to its table.
+	key []string
+	// start is the offset of the line the expression starts on, end is the
+	// offset of the line following its last non-blank line.
+	start, end int
+}
+
+func (e tomlExpr) isHeader() bool {
+	return e.kind == unstable.Table || e.kind == unstable.ArrayTable
+}
+
+// parseTOML parses a TOML document. Lines that only consist of a Go template
+// action, as used by the containerd config templates of K3s and RKE2, are
+// treated as comments.
+func parseTOML(data []byte) (*tomlDocument, error) {
+	masked := maskTemplateActions(data)
+
+	doc := &tomlDocument{data: data}
+	if err := toml.Unmarshal(masked, &doc.values); err != nil {
+		return nil, err
+	}
+
+	p := unstable.Parser{KeepComments: true}
+	p.Reset(masked)
+	for p.NextExpression() {
+		node := p.Expression()
+		expr := tomlExpr{kind: node.Kind}
+
+		var offset uint32
+		switch node.Kind {
+		case unstable.Comment:
+			offset = node.Raw.Offset
+		case unstable.Table, unstable.ArrayTable, unstable.KeyValue:
+			it := node.Key()
+			for it.Next() {
+				if len(expr.key) == 0 {
+					offset = it.Node().Raw.Offset
+				}
+				expr.key = append(expr.key, string(it.Node().Data))
+			}
+		default:
+			continue
+		}
+		expr.start = lineStart(data, int(offset))
+		doc.exprs = append(doc.exprs, expr)
+	}
+	if err := p.Error(); err != nil {
+		return nil, err
+	}
+
+	for i := range doc.exprs {
+		end := len(data)
+		if i+1 < len(doc.exprs) {
+			end = doc.exprs[i+1].start
+		}
+		doc.exprs[i].end = trimBlankLines(data, doc.exprs[i].start, end)
+	}
+
+	return doc, nil
+}
+
+// maskTemplateActions turns lines that only consist of a Go template action
+// into comments. The offsets of all other bytes are kept.
+func maskTemplateActions(data []byte) []byte {
+	masked := bytes.Clone(data)
+	offset := 0
+	for line := range bytes.Lines(data) {
+		trimmed := bytes.TrimSpace(line)
+		if bytes.HasPrefix(trimmed, []byte("{{")) && bytes.HasSuffix(trimmed, []byte("}}")) {
+			masked[offset+bytes.Index(line, []byte("{{"))] = '#'
+		}
+		offset += len(line)
+	}
+	return masked
+}
+
+// lineStart returns the offset of the line containing offset.
+func lineStart(data []byte, offset int) int {
+	return bytes.LastIndexByte(data[:offset], '\n') + 1
+}
+
+// trimBlankLines returns the offset following the last non-blank line in
+// data[start:end].
+func trimBlankLines(data []byte, start, end int) int {
+	for end > start {
+		lineBegin := lineStart(data, end-1)
+		if len(bytes.TrimSpace(data[lineBegin:end])) > 0 {
+			return end
+		}
+		end = lineBegin
+	}
+	return end
+}
+
+// lookup returns the decoded value at path.
+func (d *tomlDocument) lookup(path []string) (any, bool) {
+	var value any = d.values
+	for _, key := range path {
+		table, ok := value.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if value, ok = table[key]; !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// header returns the index of the header of the table at path, or -1.
+func (d *tomlDocument) header(path []string) int {
+	return slices.IndexFunc(d.exprs, func(e tomlExpr) bool {
+		return e.kind == unstable.Table && slices.Equal(e.key, path)
+	})
+}
+
+// sectionEnd returns the index of the last key-value pair following the
+// header at index i, or i if there is none. For the root table, i is -1. Comments after the last key-value
+// pair are considered to belong to the next table.
+func (d *tomlDocument) sectionEnd(i int) int {
+	last := i
+	for j := i + 1; j < len(d.exprs) && !d.exprs[j].isHeader(); j++ {
+		if d.exprs[j].kind == unstable.KeyValue {
+			last = j
+		}
+	}
+	return last
+}
+
+// setKey upserts a key of the table whose header is at index i, or of the root
+// table if i is -1. value must be a TOML value. It returns whether the document
+// was changed.
+func (d *tomlDocument) setKey(i int, key, value string) (bool, error) {
+	var want map[string]any
+	if err := toml.Unmarshal([]byte("v = "+value), &want); err != nil {
+		return false, fmt.Errorf("invalid value %q of %s: %w", value, key, err)
+	}
+
+	last := d.sectionEnd(i)
+	for j := i + 1; j <= last; j++ {
+		expr := d.exprs[j]
+		if expr.kind != unstable.KeyValue || !slices.Equal(expr.key, []string{key}) {
+			continue
+		}
+		var got map[string]any
+		if err := toml.Unmarshal(maskTemplateActions(d.data[expr.start:expr.end]), &got); err == nil && reflect.DeepEqual(got[key], want["v"]) {
+			return false, nil
+		}
+		line := indentation(d.data[expr.start:]) + formatKey(key) + " = " + value + "\n"
+		return true, d.splice(expr.start, expr.end, line)
+	}
+
+	if last < 0 {
+		// The root table is empty, add the key at the top of the document
+		return true, d.insert(0, formatKey(key)+" = "+value+"\n")
+	}
+
+	// Add the key after the last key-value pair of the table, using the same indentation
+	indent := indentation(d.data[d.exprs[last].start:])
+	line := indent + formatKey(key) + " = " + value + "\n"
+	return true, d.insert(d.exprs[last].end, line)
+}
+
+// removeKeys removes the key-value pairs of the table whose header is at index
+// i, unless keep returns true for their key. Dotted keys are matched by their
+// first part. It returns whether the document was changed.
+func (d *tomlDocument) removeKeys(i int, keep func(key string) bool) (bool, error) {
+	changed := false
+	for {
+		j := slices.IndexFunc(d.exprs[i+1:d.sectionEnd(i)+1], func(e tomlExpr) bool {
+			return e.kind == unstable.KeyValue && !keep(e.key[0])
+		})
+		if j < 0 {
+			return changed, nil
+		}
+		expr := d.exprs[i+1+j]
+		if err := d.splice(expr.start, expr.end, ""); err != nil {
+			return false, err
+		}
+		changed = true
+	}
+}
+
+// insertTable inserts an empty table at path after the table whose header is
+// at index i.
+func (d *tomlDocument) insertTable(i int, path []string) error {
+	end := d.exprs[d.sectionEnd(i)].end
+	return d.insert(end, indentation(d.data[d.exprs[i].start:])+formatTableHeader(path)+"\n")
+}
+
+// removeTables removes all tables whose path starts with prefix, and the
+// comments directly preceding them that match comment. It returns whether the
+// document was changed.
+func (d *tomlDocument) removeTables(prefix []string, comment string) (bool, error) {
+	changed := false
+	for {
+		i := slices.IndexFunc(d.exprs, func(e tomlExpr) bool {
+			return e.isHeader() && len(e.key) >= len(prefix) && slices.Equal(e.key[:len(prefix)], prefix)
+		})
+		if i < 0 {
+			return changed, nil
+		}
+
+		start := d.exprs[i].start
+		if i > 0 && d.exprs[i-1].kind == unstable.Comment && strings.TrimSpace(string(d.data[d.exprs[i-1].start:d.exprs[i-1].end])) == comment {
+			start = d.exprs[i-1].start
+		}
+		end := len(d.data)
+		if next := d.sectionEnd(i) + 1; next < len(d.exprs) {
+			end = d.exprs[next].start
+		}
+		if end == len(d.data) {
+			// Don't leave blank lines at the end of the document behind
+			start = trimBlankLines(d.data, 0, start)
+		}
+		if err := d.splice(start, end, ""); err != nil {
+			return false, err
+		}
+		changed = true
+	}
+}
+
+// insert inserts the lines s at offset, which is either the start of a line
+// or the end of the document.
+func (d *tomlDocument) insert(offset int, s string) error {
+	if offset > 0 && d.data[offset-1] != '\n' {
+		s = "\n" + s
+	}
+	return d.splice(offset, offset, s)
+}
+
+// splice replaces data[start:end] with s and parses the document again.
+func (d *tomlDocument) splice(start, end int, s string) error {
+	data := make([]byte, 0, len(d.data)-(end-start)+len(s))
+	data = append(data, d.data[:start]...)
+	data = append(data, s...)
+	data = append(data, d.data[end:]...)
+
+	doc, err := parseTOML(data)
+	if err != nil {
+		return fmt.Errorf("edit resulted in invalid TOML: %w", err)
+	}
+	*d = *doc
+	return nil
+}
+
+// indentation returns the leading whitespace of the first line of data.
+func indentation(data []byte) string {
+	return string(data[:len(data)-len(bytes.TrimLeft(data, " \t"))])
+}
+
+func formatKey(key string) string {
+	if bareKey.MatchString(key) {
+		return key
+	}
+	return formatString(key)
+}
+
+func formatTableHeader(path []string) string {
+	keys := make([]string, len(path))
+	for i, key := range path {
+		keys[i] = formatKey(key)
+	}
+	return "[" + strings.Join(keys, ".") + "]"
+}
+
+// formatString formats s as a TOML basic string.
+func formatString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch {
+		case r == '"' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 0x20 || r == 0x7f:
+			fmt.Fprintf(&b, `\u%04X`, r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
@@ -0,0 +1,65 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/events"
+
+	rcmv1 "github.com/spinframework/runtime-class-manager/api/v1alpha1"
+)
+
+// Reasons of the Events recorded for the shim lifecycle.
+const (
+	EventReasonRuntimeClassCreated      = "RuntimeClassCreated"
+	EventReasonRuntimeClassUpdated      = "RuntimeClassUpdated"
+	EventReasonRuntimeClassDeleted      = "RuntimeClassDeleted"
+	EventReasonJobCreated               = "JobCreated"
+	EventReasonJobCreationFailed        = "JobCreationFailed"
+	EventReasonInstallSucceeded         = "InstallSucceeded"
+	EventReasonInstallFailed            = "InstallFailed"
+	EventReasonUninstallSucceeded       = "UninstallSucceeded"
+	EventReasonUninstallFailed          = "UninstallFailed"
+	EventReasonArtifactResolutionFailed = "ArtifactResolutionFailed"
+	EventReasonFinalizerRemoved         = "FinalizerRemoved"
+	EventReasonPodEvicted               = "PodEvicted"
+)
+
+// Actions of the Events recorded for the shim lifecycle.
+const (
+	EventActionCreateRuntimeClass = "CreateRuntimeClass"
+	EventActionUpdateRuntimeClass = "UpdateRuntimeClass"
+	EventActionDeleteRuntimeClass = "DeleteRuntimeClass"
+	EventActionCreateJob          = "CreateJob"
+	EventActionInstall            = "Install"
+	EventActionUninstall          = "Uninstall"
+	EventActionResolveArtifact    = "ResolveArtifact"
+	EventActionRemoveFinalizer    = "RemoveFinalizer"
+	EventActionEvictPod           = "EvictPod"
+)
+
+// recordShimNodeEvent records an Event on both the Shim and the Node it is about,
+// so that it shows up when describing either of them. The Shim may be nil if it
+// no longer exists.
+func recordShimNodeEvent(recorder events.EventRecorder, shim *rcmv1.Shim, node *corev1.Node, eventtype, reason, action, note string, args ...any) {
+	if shim != nil {
+		recorder.Eventf(shim, node, eventtype, reason, action, note, args...)
+		recorder.Eventf(node, shim, eventtype, reason, action, note, args...)
+		return
+	}
+	recorder.Eventf(node, nil, eventtype, reason, action, note, args...)
+}
@@ -19,26 +19,38 @@ package controller
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strings"
 
 	"github.com/rs/zerolog/log"
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/events"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	rcmv1 "github.com/spinframework/runtime-class-manager/api/v1alpha1"
+	"github.com/spinframework/runtime-class-manager/internal/termination"
 )
 
 // JobReconciler reconciles a Job object
 type JobReconciler struct {
 	client.Client
-	Scheme *runtime.Scheme
+	Scheme   *runtime.Scheme
+	Recorder events.EventRecorder
+	// APIReader reads objects directly from the API server. It is used for
+	// Pods, which are not worth caching for the whole cluster.
+	APIReader client.Reader
 }
 
 //+kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=batch,resources=jobs/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=batch,resources=jobs/finalizers,verbs=update
+//+kubebuilder:rbac:groups="",resources=pods,verbs=list
 
 // SetupWithManager sets up the controller with the Manager.
 func (jr *JobReconciler) SetupWithManager(mgr ctrl.Manager) error {
@@ -84,23 +96,26 @@ func (jr *JobReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.
 	if err != nil {
 		return ctrl.Result{}, err
 	}
+	if node.Name == "" {
+		log.Info().Msgf("Node of Job %s no longer exists", job.Name)
+		return ctrl.Result{}, nil
+	}
+
+	// Ignore results of Jobs that installed a configuration which has since been
+	// replaced on the node, e.g. when the Shim was changed in the meantime.
+	if configHash, ok := job.Annotations["spinkube.dev/configHash"]; ok && node.Annotations[configHashAnnotation(shimName)] != configHash {
+		log.Info().Msgf("Job %s installed an outdated configuration of Shim %s, ignoring", job.Name, shimName)
+		return ctrl.Result{}, nil
+	}
 
-	_, finishedType := jr.isJobFinished(job)
+	_, finishedType := isJobFinished(job)
 	switch finishedType {
 	case "": // ongoing
 		log.Info().Msgf("Job %s is still Ongoing", job.Name)
 		return ctrl.Result{}, nil
 	case batchv1.JobFailed:
 		log.Info().Msgf("Job %s is still failing...", job.Name)
-		if err := jr.updateNodeLabels(ctx, node, shimName, "failed"); err != nil {
-			log.Error().Msgf("Unable to update node label %s: %s", shimName, err)
-		}
-		return ctrl.Result{}, nil
-	case batchv1.JobFailureTarget:
-		log.Info().Msgf("Job %s is about to fail", job.Name)
-		if err := jr.updateNodeLabels(ctx, node, shimName, "failed"); err != nil {
-			log.Error().Msgf("Unable to update node label %s: %s", shimName, err)
-		}
+		jr.handleFailedJob(ctx, job, node, shimName)
 		return ctrl.Result{}, nil
 	case batchv1.JobComplete:
 		log.Info().Msgf("Job %s is Completed.", job.Name)
@@ -109,13 +124,28 @@ func (jr *JobReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.
 
 		switch installOrUninstall {
 		case INSTALL:
-			if err := jr.updateNodeLabels(ctx, node, shimName, "provisioned"); err != nil {
+			if node.Labels[shimName] == ProvisioningStatusProvisioned {
+				break
+			}
+			clearNodeFailure(node, shimName)
+			if err := jr.updateNodeLabels(ctx, node, shimName, ProvisioningStatusProvisioned); err != nil {
 				log.Error().Msgf("Unable to update node label %s: %s", shimName, err)
+				break
 			}
+			recordJobFinished(job, shimName, true)
+			recordShimNodeEvent(jr.Recorder, jr.getShim(ctx, shimName), node, corev1.EventTypeNormal, EventReasonInstallSucceeded, EventActionInstall,
+				"Shim %s installed on node %s by Job %s", shimName, node.Name, job.Name)
 		case UNINSTALL:
+			if _, exists := node.Labels[shimName]; !exists {
+				break
+			}
 			if err := jr.deleteNodeLabel(ctx, node, shimName); err != nil {
 				log.Error().Msgf("Unable to delete node label %s: %s", shimName, err)
+				break
 			}
+			recordJobFinished(job, shimName, true)
+			recordShimNodeEvent(jr.Recorder, jr.getShim(ctx, shimName), node, corev1.EventTypeNormal, EventReasonUninstallSucceeded, EventActionUninstall,
+				"Shim %s uninstalled from node %s by Job %s", shimName, node.Name, job.Name)
 		}
 
 		return ctrl.Result{}, err
@@ -127,6 +157,102 @@ func (jr *JobReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.
 	return ctrl.Result{}, nil
 }
 
+// handleFailedJob marks the shim as failed on the node, records the reason of
+// the failure on the node and records an Event containing the termination
+// message of the failed container.
+func (jr *JobReconciler) handleFailedJob(ctx context.Context, job *batchv1.Job, node *corev1.Node, shimName string) {
+	log := log.With().Str("job", job.Name).Logger()
+
+	if node.Labels[shimName] == ProvisioningStatusFailed {
+		// The failure has already been handled
+		return
+	}
+
+	failureReason, message := jr.jobFailure(ctx, job)
+	if message == "" {
+		message = "no termination message available"
+	}
+	setNodeFailure(node, shimName, failureReason, message)
+	if err := jr.updateNodeLabels(ctx, node, shimName, ProvisioningStatusFailed); err != nil {
+		log.Error().Msgf("Unable to update node label %s: %s", shimName, err)
+		return
+	}
+	recordJobFinished(job, shimName, false)
+
+	reason, action := EventReasonInstallFailed, EventActionInstall
+	if job.Annotations["spinkube.dev/operation"] == UNINSTALL {
+		reason, action = EventReasonUninstallFailed, EventActionUninstall
+	}
+	recordShimNodeEvent(jr.Recorder, jr.getShim(ctx, shimName), node, corev1.EventTypeWarning, reason, action,
+		"Job %s failed on node %s (%s): %s", job.Name, node.Name, failureReason, message)
+}
+
+// jobFailure returns the reason and the termination message of the failed
+// container of a Job's most recent Pod.
+func (jr *JobReconciler) jobFailure(ctx context.Context, job *batchv1.Job) (string, string) {
+	log := log.With().Str("job", job.Name).Logger()
+	unknown := string(termination.ReasonUnknown)
+
+	if job.Spec.Selector == nil {
+		return unknown, ""
+	}
+	selector, err := metav1.LabelSelectorAsSelector(job.Spec.Selector)
+	if err != nil {
+		log.Error().Msgf("Invalid selector of Job: %s", err)
+		return unknown, ""
+	}
+
+	pods := corev1.PodList{}
+	if err := jr.APIReader.List(ctx, &pods, client.InNamespace(job.Namespace), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		log.Error().Msgf("Unable to list Pods of Job: %s", err)
+		return unknown, ""
+	}
+
+	return jobFailureFromPods(pods.Items)
+}
+
+// jobFailureFromPods returns the failure reason and termination message of the
+// first container that exited with an error, looking at the most recent Pod
+// first. The reason is taken from structured termination messages written by
+// the downloader and the node installer, and is Unknown otherwise.
+func jobFailureFromPods(pods []corev1.Pod) (string, string) {
+	sorted := append([]corev1.Pod{}, pods...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[j].CreationTimestamp.Before(&sorted[i].CreationTimestamp)
+	})
+
+	for _, pod := range sorted {
+		statuses := append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+		for _, status := range statuses {
+			terminated := status.State.Terminated
+			if terminated == nil || terminated.ExitCode == 0 {
+				continue
+			}
+			reason := termination.ReasonUnknown
+			message := strings.TrimSpace(terminated.Message)
+			if msg, ok := termination.Parse(message); ok {
+				reason, message = msg.Reason, msg.Message
+			}
+			if message == "" {
+				message = terminated.Reason
+			}
+			return string(reason), fmt.Sprintf("container %s exited with code %d: %s", status.Name, terminated.ExitCode, message)
+		}
+	}
+
+	return string(termination.ReasonUnknown), ""
+}
+
+// getShim fetches a Shim by name. It returns nil if the Shim can't be fetched,
+// e.g. because it has already been deleted.
+func (jr *JobReconciler) getShim(ctx context.Context, shimName string) *rcmv1.Shim {
+	shim := rcmv1.Shim{}
+	if err := jr.Get(ctx, types.NamespacedName{Name: shimName}, &shim); err != nil {
+		return nil
+	}
+	return &shim
+}
+
 func (jr *JobReconciler) updateNodeLabels(ctx context.Context, node *corev1.Node, shimName string, status string) error {
 	node.Labels[shimName] = status
 
@@ -139,6 +265,9 @@ func (jr *JobReconciler) updateNodeLabels(ctx context.Context, node *corev1.Node
 
 func (jr *JobReconciler) deleteNodeLabel(ctx context.Context, node *corev1.Node, shimName string) error {
 	delete(node.Labels, shimName)
+	delete(node.Annotations, configHashAnnotation(shimName))
+	delete(node.Annotations, artifactDigestAnnotation(shimName))
+	clearNodeFailure(node, shimName)
 
 	if err := jr.Update(ctx, node); err != nil {
 		return fmt.Errorf("failed to delete node labels: %w", err)
@@ -156,7 +285,7 @@ func (jr *JobReconciler) getNode(ctx context.Context, nodeName string) (*corev1.
 	return &node, nil
 }
 
-func (jr *JobReconciler) isJobFinished(job *batchv1.Job) (bool, batchv1.JobConditionType) {
+func isJobFinished(job *batchv1.Job) (bool, batchv1.JobConditionType) {
 	for _, c := range job.Status.Conditions {
 		if (c.Type == batchv1.JobComplete || c.Type == batchv1.JobFailed) && c.Status == corev1.ConditionTrue {
 			return true, c.Type
@@ -0,0 +1,137 @@
+package controller //nolint:testpackage // whitebox test
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/events"
+)
+
+func makeJobPod(created time.Time, initStatuses []corev1.ContainerStatus, statuses []corev1.ContainerStatus) corev1.Pod {
+	return corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{CreationTimestamp: metav1.NewTime(created)},
+		Status: corev1.PodStatus{
+			InitContainerStatuses: initStatuses,
+			ContainerStatuses:     statuses,
+		},
+	}
+}
+
+func terminatedStatus(name string, exitCode int32, reason string, message string) corev1.ContainerStatus {
+	return corev1.ContainerStatus{
+		Name: name,
+		State: corev1.ContainerState{
+			Terminated: &corev1.ContainerStateTerminated{
+				ExitCode: exitCode,
+				Reason:   reason,
+				Message:  message,
+			},
+		},
+	}
+}
+
+func TestJobFailureFromPods(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		pods       []corev1.Pod
+		wantReason string
+		want       string
+	}{
+		{
+			name:       "no pods",
+			wantReason: "Unknown",
+			want:       "",
+		},
+		{
+			name: "failed init container",
+			pods: []corev1.Pod{
+				makeJobPod(start,
+					[]corev1.ContainerStatus{terminatedStatus("downloader", 1, "Error", "download failed\n")},
+					[]corev1.ContainerStatus{{Name: "provisioner"}},
+				),
+			},
+			wantReason: "Unknown",
+			want:       "container downloader exited with code 1: download failed",
+		},
+		{
+			name: "structured termination message",
+			pods: []corev1.Pod{
+				makeJobPod(start,
+					[]corev1.ContainerStatus{terminatedStatus("downloader", 3, "Error", `{"reason":"ChecksumMismatch","message":"expected sha256 abc, got def"}`)},
+					[]corev1.ContainerStatus{{Name: "provisioner"}},
+				),
+			},
+			wantReason: "ChecksumMismatch",
+			want:       "container downloader exited with code 3: expected sha256 abc, got def",
+		},
+		{
+			name: "failed main container after successful init container",
+			pods: []corev1.Pod{
+				makeJobPod(start,
+					[]corev1.ContainerStatus{terminatedStatus("downloader", 0, "Completed", "")},
+					[]corev1.ContainerStatus{terminatedStatus("provisioner", 1, "Error", "failed to restart containerd")},
+				),
+			},
+			wantReason: "Unknown",
+			want:       "container provisioner exited with code 1: failed to restart containerd",
+		},
+		{
+			name: "falls back to the termination reason",
+			pods: []corev1.Pod{
+				makeJobPod(start, nil, []corev1.ContainerStatus{terminatedStatus("provisioner", 137, "OOMKilled", "")}),
+			},
+			wantReason: "Unknown",
+			want:       "container provisioner exited with code 137: OOMKilled",
+		},
+		{
+			name: "most recent pod wins",
+			pods: []corev1.Pod{
+				makeJobPod(start, nil, []corev1.ContainerStatus{terminatedStatus("provisioner", 1, "Error", "first attempt")}),
+				makeJobPod(start.Add(time.Minute), nil, []corev1.ContainerStatus{terminatedStatus("provisioner", 1, "Error", "second attempt")}),
+			},
+			wantReason: "Unknown",
+			want:       "container provisioner exited with code 1: second attempt",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reason, got := jobFailureFromPods(tt.pods)
+			if reason != tt.wantReason || got != tt.want {
+				t.Errorf("jobFailureFromPods() = %q, %q, want %q, %q", reason, got, tt.wantReason, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecordShimNodeEvent(t *testing.T) {
+	recorder := events.NewFakeRecorder(10)
+	shim := makeShim(nil, nil)
+	node := makeNode("amd64")
+
+	recordShimNodeEvent(recorder, shim, node, corev1.EventTypeNormal, EventReasonInstallSucceeded, EventActionInstall, "installed on %s", node.Name)
+	recordShimNodeEvent(recorder, nil, node, corev1.EventTypeWarning, EventReasonUninstallFailed, EventActionUninstall, "failed on %s", node.Name)
+	close(recorder.Events)
+
+	got := []string{}
+	for event := range recorder.Events {
+		got = append(got, event)
+	}
+	want := []string{
+		"Normal InstallSucceeded installed on node1",
+		"Normal InstallSucceeded installed on node1",
+		"Warning UninstallFailed failed on node1",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("events = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("events[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,154 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	rcmv1 "github.com/spinframework/runtime-class-manager/api/v1alpha1"
+)
+
+const metricsNamespace = "rcm"
+
+var (
+	shimNodesTargeted = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "shim_nodes_targeted",
+		Help:      "Number of nodes matching the node selector of a Shim.",
+	}, []string{"shim"})
+	shimNodesProvisioned = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "shim_nodes_provisioned",
+		Help:      "Number of nodes a Shim is provisioned on.",
+	}, []string{"shim"})
+	shimNodesPending = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "shim_nodes_pending",
+		Help:      "Number of nodes a Shim is waiting to be provisioned on.",
+	}, []string{"shim"})
+	shimNodesFailed = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "shim_nodes_failed",
+		Help:      "Number of nodes on which provisioning a Shim failed.",
+	}, []string{"shim"})
+
+	jobsCreated = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "shim_jobs_created_total",
+		Help:      "Total number of install and uninstall Jobs created.",
+	}, []string{"shim", "operation"})
+	jobsSucceeded = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "shim_jobs_succeeded_total",
+		Help:      "Total number of install and uninstall Jobs that succeeded.",
+	}, []string{"shim", "operation"})
+	jobsFailed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "shim_jobs_failed_total",
+		Help:      "Total number of install and uninstall Jobs that failed.",
+	}, []string{"shim", "operation"})
+	jobDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "shim_job_duration_seconds",
+		Help:      "Time from creating an install or uninstall Job until it finished.",
+		Buckets:   prometheus.ExponentialBuckets(5, 2, 10),
+	}, []string{"shim", "operation", "result"})
+
+	artifactResolutionErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "shim_artifact_resolution_errors_total",
+		Help:      "Total number of failures to resolve a shim artifact for a node.",
+	}, []string{"shim", "platform"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(
+		shimNodesTargeted,
+		shimNodesProvisioned,
+		shimNodesPending,
+		shimNodesFailed,
+		jobsCreated,
+		jobsSucceeded,
+		jobsFailed,
+		jobDuration,
+		artifactResolutionErrors,
+	)
+}
+
+// recordNodeMetrics updates the node gauges of a Shim from its status.
+func recordNodeMetrics(shim *rcmv1.Shim) {
+	phases := map[rcmv1.NodePhase]int{}
+	for _, nodeStatus := range shim.Status.NodeStatuses {
+		phases[nodeStatus.Phase]++
+	}
+
+	shimNodesTargeted.WithLabelValues(shim.Name).Set(float64(len(shim.Status.NodeStatuses)))
+	shimNodesProvisioned.WithLabelValues(shim.Name).Set(float64(phases[rcmv1.NodePhaseProvisioned]))
+	shimNodesPending.WithLabelValues(shim.Name).Set(float64(phases[rcmv1.NodePhasePending]))
+	shimNodesFailed.WithLabelValues(shim.Name).Set(float64(phases[rcmv1.NodePhaseFailed]))
+}
+
+// deleteNodeMetrics removes the node gauges of a Shim that no longer exists.
+func deleteNodeMetrics(shimName string) {
+	shimNodesTargeted.DeleteLabelValues(shimName)
+	shimNodesProvisioned.DeleteLabelValues(shimName)
+	shimNodesPending.DeleteLabelValues(shimName)
+	shimNodesFailed.DeleteLabelValues(shimName)
+}
+
+// recordJobFinished counts a finished Job and observes how long it took.
+func recordJobFinished(job *batchv1.Job, shimName string, succeeded bool) {
+	operation := job.Annotations["spinkube.dev/operation"]
+	result := "succeeded"
+	if succeeded {
+		jobsSucceeded.WithLabelValues(shimName, operation).Inc()
+	} else {
+		result = "failed"
+		jobsFailed.WithLabelValues(shimName, operation).Inc()
+	}
+
+	finished := jobFinishTime(job)
+	if finished.IsZero() || job.CreationTimestamp.IsZero() {
+		return
+	}
+	jobDuration.WithLabelValues(shimName, operation, result).Observe(finished.Sub(job.CreationTimestamp.Time).Seconds())
+}
+
+// jobFinishTime returns when a Job finished. Failed Jobs have no completion
+// time, so the transition time of the condition that finished the Job is used.
+// A Job that is about to fail is considered finished as well.
+func jobFinishTime(job *batchv1.Job) metav1.Time {
+	if job.Status.CompletionTime != nil {
+		return *job.Status.CompletionTime
+	}
+	for _, c := range job.Status.Conditions {
+		if (c.Type == batchv1.JobComplete || c.Type == batchv1.JobFailed) && c.Status == corev1.ConditionTrue {
+			return c.LastTransitionTime
+		}
+	}
+	return metav1.Time{}
+}
+
+// recordArtifactResolutionError counts a failure to resolve an artifact for a node.
+func recordArtifactResolutionError(shim *rcmv1.Shim, node *corev1.Node) {
+	platform := node.Status.NodeInfo.OperatingSystem + "/" + node.Status.NodeInfo.Architecture
+	artifactResolutionErrors.WithLabelValues(shim.Name, platform).Inc()
+}
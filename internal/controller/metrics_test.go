@@ -0,0 +1,113 @@
+package controller //nolint:testpackage // whitebox test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	rcmv1 "github.com/spinframework/runtime-class-manager/api/v1alpha1"
+)
+
+func TestRecordNodeMetrics(t *testing.T) {
+	shim := makeShim(nil, nil)
+	shim.Name = "metrics-shim"
+	shim.Status.NodeStatuses = []rcmv1.NodeStatus{
+		{Name: "node-a", Phase: rcmv1.NodePhaseProvisioned},
+		{Name: "node-b", Phase: rcmv1.NodePhaseProvisioned},
+		{Name: "node-c", Phase: rcmv1.NodePhasePending},
+		{Name: "node-d", Phase: rcmv1.NodePhaseFailed},
+	}
+
+	recordNodeMetrics(shim)
+
+	for _, tt := range []struct {
+		name  string
+		value float64
+		want  float64
+	}{
+		{"targeted", testutil.ToFloat64(shimNodesTargeted.WithLabelValues(shim.Name)), 4},
+		{"provisioned", testutil.ToFloat64(shimNodesProvisioned.WithLabelValues(shim.Name)), 2},
+		{"pending", testutil.ToFloat64(shimNodesPending.WithLabelValues(shim.Name)), 1},
+		{"failed", testutil.ToFloat64(shimNodesFailed.WithLabelValues(shim.Name)), 1},
+	} {
+		if tt.value != tt.want {
+			t.Errorf("nodes %s = %v, want %v", tt.name, tt.value, tt.want)
+		}
+	}
+
+	deleteNodeMetrics(shim.Name)
+	if got := testutil.CollectAndCount(shimNodesTargeted); got != 0 {
+		t.Errorf("series after delete = %d, want 0", got)
+	}
+}
+
+func TestRecordJobFinished(t *testing.T) {
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	job := func(operation string, conditionType batchv1.JobConditionType, finished time.Time) *batchv1.Job {
+		return &batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{
+				CreationTimestamp: metav1.NewTime(created),
+				Annotations:       map[string]string{"spinkube.dev/operation": operation},
+			},
+			Status: batchv1.JobStatus{
+				Conditions: []batchv1.JobCondition{
+					{Type: conditionType, Status: corev1.ConditionTrue, LastTransitionTime: metav1.NewTime(finished)},
+				},
+			},
+		}
+	}
+
+	recordJobFinished(job(INSTALL, batchv1.JobComplete, created.Add(30*time.Second)), "job-shim", true)
+	recordJobFinished(job(UNINSTALL, batchv1.JobFailed, created.Add(time.Minute)), "job-shim", false)
+
+	if got := testutil.ToFloat64(jobsSucceeded.WithLabelValues("job-shim", INSTALL)); got != 1 {
+		t.Errorf("succeeded install jobs = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(jobsFailed.WithLabelValues("job-shim", UNINSTALL)); got != 1 {
+		t.Errorf("failed uninstall jobs = %v, want 1", got)
+	}
+	if got := testutil.CollectAndCount(jobDuration); got != 2 {
+		t.Errorf("duration series = %d, want 2", got)
+	}
+}
+
+func TestJobFinishTime(t *testing.T) {
+	completed := metav1.NewTime(time.Date(2024, 1, 1, 0, 1, 0, 0, time.UTC))
+	failed := metav1.NewTime(time.Date(2024, 1, 1, 0, 2, 0, 0, time.UTC))
+
+	tests := []struct {
+		name string
+		job  batchv1.Job
+		want metav1.Time
+	}{
+		{
+			name: "completion time",
+			job:  batchv1.Job{Status: batchv1.JobStatus{CompletionTime: &completed}},
+			want: completed,
+		},
+		{
+			name: "failed condition",
+			job: batchv1.Job{Status: batchv1.JobStatus{Conditions: []batchv1.JobCondition{
+				{Type: batchv1.JobFailed, Status: corev1.ConditionTrue, LastTransitionTime: failed},
+			}}},
+			want: failed,
+		},
+		{
+			name: "ongoing",
+			job:  batchv1.Job{},
+			want: metav1.Time{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := jobFinishTime(&tt.job); !got.Equal(&tt.want) {
+				t.Errorf("jobFinishTime() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -18,21 +18,29 @@ package controller
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"maps"
 	"math"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/rs/zerolog/log"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/events"
 
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	nodev1 "k8s.io/api/node/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
@@ -43,6 +51,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	rcmv1 "github.com/spinframework/runtime-class-manager/api/v1alpha1"
+	"github.com/spinframework/runtime-class-manager/internal/termination"
 )
 
 const (
@@ -51,13 +60,33 @@ const (
 	UNINSTALL                     = "uninstall"
 	ProvisioningStatusProvisioned = "provisioned"
 	ProvisioningStatusPending     = "pending"
+	ProvisioningStatusFailed      = "failed"
 	K8sNameMaxLength              = 63
+	// ForceDeleteAnnotation removes the finalizer of a Shim that is being
+	// deleted without waiting for it to be uninstalled from all nodes, e.g.
+	// because nodes are unreachable.
+	ForceDeleteAnnotation = "rcm.spinkube.dev/force-delete"
+	// httpAuthMountPath is where the Secret referenced by FetchStrategy.HTTPAuth
+	// is mounted in the downloader container.
+	httpAuthMountPath = "/etc/rcm/http-auth"
+	// maxFailureMessageLength limits the failure message recorded on a node.
+	maxFailureMessageLength = 1024
+	// uninstallBlockedRequeueInterval is how long to wait before checking again
+	// whether the pods blocking the uninstallation of a shim are gone.
+	uninstallBlockedRequeueInterval = 30 * time.Second
 )
 
+// errNoPlatformMatch is returned when no platform artifact of a Shim matches a node.
+var errNoPlatformMatch = errors.New("no platform artifact matches node")
+
 // ShimReconciler reconciles a Shim object
 type ShimReconciler struct {
 	client.Client
-	Scheme *runtime.Scheme
+	Scheme   *runtime.Scheme
+	Recorder events.EventRecorder
+	// APIReader reads objects directly from the API server. It is used for
+	// Pods, which are not worth caching for the whole cluster.
+	APIReader client.Reader
 }
 
 // configuration for INSTALL or UNINSTALL jobs
@@ -65,6 +94,7 @@ type opConfig struct {
 	operation     string
 	privileged    bool
 	initContainer []corev1.Container
+	volumes       []corev1.Volume
 	args          []string
 }
 
@@ -74,13 +104,20 @@ type opConfig struct {
 type resolvedArtifact struct {
 	location string
 	sha256   string
+	// oci is set if location is a reference to an OCI registry. The shim
+	// is then pulled for platform (os/arch) of the node.
+	oci      *rcmv1.OCISpec
+	platform string
 }
 
 //+kubebuilder:rbac:groups=runtime.spinkube.dev,resources=shims,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=runtime.spinkube.dev,resources=shims/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=runtime.spinkube.dev,resources=shims/finalizers,verbs=update
 //+kubebuilder:rbac:groups="",resources=nodes,verbs=list;watch;update
-//+kubebuilder:rbac:groups=node.k8s.io,resources=runtimeclasses,verbs=get;list;watch;create;patch
+//+kubebuilder:rbac:groups="",resources=pods,verbs=list
+//+kubebuilder:rbac:groups="",resources=pods/eviction,verbs=create
+//+kubebuilder:rbac:groups=node.k8s.io,resources=runtimeclasses,verbs=get;list;watch;create;patch;delete
+//+kubebuilder:rbac:groups=events.k8s.io,resources=events,verbs=create;patch
 
 // SetupWithManager sets up the controller with the Manager.
 func (sr *ShimReconciler) SetupWithManager(mgr ctrl.Manager) error {
@@ -90,6 +127,8 @@ func (sr *ShimReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		// Jobs are important for us to update the Shims installation status
 		// on respective nodes
 		Owns(&batchv1.Job{}).
+		// Changes to the RuntimeClass are reverted
+		Owns(&nodev1.RuntimeClass{}).
 		// As we don't own nodes, but need to react on node label changes,
 		// we need to watch node label changes.
 		// Whenever a label changes, we want to reconcile Shims, to make sure
@@ -122,6 +161,12 @@ func (sr *ShimReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
+	// Shim has been requested for deletion, uninstall it from all nodes
+	if !shimResource.DeletionTimestamp.IsZero() {
+		log.Debug().Msgf("Deleting shim %s", shimResource.Name)
+		return sr.reconcileDeletion(ctx, &shimResource)
+	}
+
 	// Ensure the finalizer is called even if a return happens before
 	defer func() {
 		err := sr.ensureFinalizerForShim(ctx, &shimResource, RCMOperatorFinalizer)
@@ -135,6 +180,7 @@ func (sr *ShimReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl
 	if err != nil {
 		return ctrl.Result{}, err
 	}
+	sr.backfillConfigHash(ctx, &shimResource, nodes)
 
 	err = sr.updateStatus(ctx, &shimResource, nodes)
 	if err != nil {
@@ -142,29 +188,10 @@ func (sr *ShimReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl
 		return ctrl.Result{}, err
 	}
 
-	// Shim has been requested for deletion, delete the child resources
-	if !shimResource.DeletionTimestamp.IsZero() {
-		log.Debug().Msgf("Deleting shim %s", shimResource.Name)
-		err := sr.handleDeleteShim(ctx, &shimResource, nodes)
-		if err != nil {
-			return ctrl.Result{}, err
-		}
-
-		err = sr.removeFinalizerFromShim(ctx, &shimResource)
-		return ctrl.Result{}, client.IgnoreNotFound(err)
-	}
-
-	// 3. Check if referenced runtimeClass exists in cluster
-	rcExists, err := sr.runtimeClassExists(ctx, &shimResource)
+	// 3. Apply the RuntimeClass, to create it or to correct any drift
+	_, err = sr.handleDeployRuntimeClass(ctx, &shimResource)
 	if err != nil {
-		log.Error().Msgf("RuntimeClass issue: %s", err)
-	}
-	if !rcExists {
-		log.Info().Msgf("RuntimeClass '%s' not found", shimResource.Spec.RuntimeClass.Name)
-		_, err = sr.handleDeployRuntimeClass(ctx, &shimResource)
-		if err != nil {
-			return ctrl.Result{}, err
-		}
+		return ctrl.Result{}, err
 	}
 
 	// 4. Deploy job to each node in list
@@ -174,7 +201,13 @@ func (sr *ShimReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl
 		log.Info().Msg("No nodes found")
 	}
 
-	return ctrl.Result{}, err
+	// 5. Uninstall the shim from nodes that don't match the node selector anymore
+	blocked, uninstallErr := sr.handleUninstallStaleShim(ctx, &shimResource, nodes)
+	if err := sr.updateBlockedCondition(ctx, &shimResource, blocked); err != nil {
+		uninstallErr = errors.Join(uninstallErr, err)
+	}
+
+	return uninstallBlockedResult(blocked), errors.Join(err, uninstallErr)
 }
 
 // findShimsToReconcile finds all Shims that need to be reconciled.
@@ -207,20 +240,10 @@ func (sr *ShimReconciler) findShimsToReconcile(ctx context.Context, node client.
 func (sr *ShimReconciler) updateStatus(ctx context.Context, shim *rcmv1.Shim, nodes *corev1.NodeList) error {
 	log := log.Ctx(ctx)
 
-	shim.Status.NodeCount = len(nodes.Items)
-	shim.Status.NodeReadyCount = 0
-
-	if len(nodes.Items) > 0 {
-		for _, node := range nodes.Items {
-			if node.Labels[shim.Name] == ProvisioningStatusProvisioned {
-				shim.Status.NodeReadyCount++
-			}
-		}
-	}
-
-	// TODO: include proper status conditions to update
+	setShimStatus(shim, nodes.Items, metav1.Now())
+	recordNodeMetrics(shim)
 
-	if err := sr.Update(ctx, shim); err != nil {
+	if err := sr.Status().Update(ctx, shim); err != nil {
 		log.Error().Msgf("Unable to update status %s", err)
 	}
 
@@ -241,7 +264,7 @@ func (sr *ShimReconciler) handleInstallShim(ctx context.Context, shim *rcmv1.Shi
 	case rcmv1.RolloutStrategyTypeRolling:
 		{
 			log.Debug().Msgf("Rolling strategy selected: maxUpdate=%d", shim.Spec.RolloutStrategy.Rolling.MaxUpdate)
-			return ctrl.Result{}, errors.New("rolling strategy not implemented yet")
+			return sr.rollingStrategyRollout(ctx, shim, nodes)
 		}
 	case rcmv1.RolloutStrategyTypeRecreate:
 		{
@@ -262,20 +285,97 @@ func (sr *ShimReconciler) recreateStrategyRollout(ctx context.Context, shim *rcm
 	for i := range nodes.Items {
 		node := nodes.Items[i]
 
-		shimProvisioned := node.Labels[shim.Name] == ProvisioningStatusProvisioned
-		shimPending := node.Labels[shim.Name] == ProvisioningStatusPending
-		if !shimProvisioned && !shimPending {
-			err := sr.deployJobOnNode(ctx, shim, node, INSTALL)
-			shimInstallationErrors = append(shimInstallationErrors, err)
+		switch node.Labels[shim.Name] {
+		case ProvisioningStatusPending:
+			continue
+		case ProvisioningStatusProvisioned:
+			if !shimOutdatedOnNode(shim, &node) {
+				log.Info().Msgf("Shim %s already provisioned on Node %s", shim.Name, node.Name)
+				continue
+			}
+			log.Info().Msgf("Shim %s on Node %s is outdated", shim.Name, node.Name)
 		}
 
-		if shimProvisioned {
-			log.Info().Msgf("Shim %s already provisioned on Node %s", shim.Name, node.Name)
-		}
+		err := sr.deployJobOnNode(ctx, shim, node, INSTALL)
+		shimInstallationErrors = append(shimInstallationErrors, err)
+	}
+	return ctrl.Result{}, errors.Join(shimInstallationErrors...)
+}
+
+// rollingStrategyRollout installs the shim on at most maxUpdate nodes at a time.
+// The next batch is only started once every node of the current batch has been
+// provisioned. The rollout halts as soon as a node of a batch failed.
+func (sr *ShimReconciler) rollingStrategyRollout(ctx context.Context, shim *rcmv1.Shim, nodes *corev1.NodeList) (ctrl.Result, error) {
+	log := log.Ctx(ctx)
+
+	batch, inProgress, failed := nextRollingBatch(shim, nodes.Items)
+	if len(failed) > 0 {
+		log.Warn().Msgf("Rolling rollout of Shim %s halted, installation failed on nodes: %s", shim.Name, strings.Join(failed, ", "))
+		return ctrl.Result{}, nil
+	}
+	if inProgress > 0 {
+		log.Info().Msgf("Waiting for %d node(s) of the current batch to be provisioned", inProgress)
+		return ctrl.Result{}, nil
+	}
+	if len(batch) == 0 {
+		log.Info().Msgf("Shim %s already provisioned on all nodes", shim.Name)
+		return ctrl.Result{}, nil
+	}
+
+	shimInstallationErrors := []error{}
+	for _, node := range batch {
+		err := sr.deployJobOnNode(ctx, shim, node, INSTALL)
+		shimInstallationErrors = append(shimInstallationErrors, err)
 	}
 	return ctrl.Result{}, errors.Join(shimInstallationErrors...)
 }
 
+// nextRollingBatch determines the next batch of nodes for a rolling rollout.
+// It returns the nodes to install the shim on next, the number of nodes of the
+// current batch that are still pending and the names of nodes on which the
+// installation of the current configuration failed. A new batch is only
+// returned when no node is pending or failed. Nodes with an outdated
+// configuration are part of the rollout like nodes without the shim.
+func nextRollingBatch(shim *rcmv1.Shim, nodes []corev1.Node) (batch []corev1.Node, inProgress int, failed []string) {
+	maxUpdate := shim.Spec.RolloutStrategy.Rolling.MaxUpdate
+	if maxUpdate < 1 {
+		maxUpdate = 1
+	}
+
+	candidates := []corev1.Node{}
+	for _, node := range nodes {
+		status := node.Labels[shim.Name]
+		outdated := shimOutdatedOnNode(shim, &node)
+		switch {
+		case status == ProvisioningStatusPending:
+			inProgress++
+		case status == ProvisioningStatusProvisioned && !outdated:
+			continue
+		case status == ProvisioningStatusFailed && !outdated:
+			// A failed node only halts the rollout as long as the Shim has not
+			// been changed since. Otherwise, the new configuration is tried.
+			failed = append(failed, node.Name)
+		default:
+			candidates = append(candidates, node)
+		}
+	}
+
+	if inProgress > 0 || len(failed) > 0 {
+		sort.Strings(failed)
+		return nil, inProgress, failed
+	}
+
+	// Roll out in a stable order, so that batches are predictable
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].Name < candidates[j].Name
+	})
+	if len(candidates) > maxUpdate {
+		candidates = candidates[:maxUpdate]
+	}
+
+	return candidates, 0, nil
+}
+
 // deployUninstallJob deploys an uninstall Job for a Shim.
 func (sr *ShimReconciler) deployJobOnNode(ctx context.Context, shim *rcmv1.Shim, node corev1.Node, jobType string) error {
 	log := log.Ctx(ctx)
@@ -290,36 +390,59 @@ func (sr *ShimReconciler) deployJobOnNode(ctx context.Context, shim *rcmv1.Shim,
 	// Resolve the platform-specific artifact for this node
 	artifact, err := resolveArtifactForNode(shim, &node)
 	if err != nil && jobType == INSTALL {
+		recordArtifactResolutionError(shim, &node)
+		recordShimNodeEvent(sr.Recorder, shim, &node, corev1.EventTypeWarning, EventReasonArtifactResolutionFailed, EventActionResolveArtifact,
+			"Unable to resolve artifact for node %s: %s", node.Name, err)
+		if errors.Is(err, errNoPlatformMatch) && setNodeFailure(&node, shim.Name, string(termination.ReasonNoPlatformMatch), err.Error()) {
+			if err := sr.Update(ctx, &node); err != nil {
+				log.Error().Msgf("Unable to record failure on node %s: %s", node.Name, err)
+			}
+		}
 		return fmt.Errorf("failed to resolve artifact for node %s: %w", node.Name, err)
 	}
 
 	var job *batchv1.Job
+	var status string
 
 	switch jobType {
 	case INSTALL:
-		err := sr.updateNodeLabels(ctx, &node, shim, ProvisioningStatusPending)
-		if err != nil {
-			log.Error().Msgf("Unable to update node label %s: %s", shim.Name, err)
-		}
-
 		job, err = sr.createJobManifest(shim, &node, INSTALL, artifact)
 		if err != nil {
 			return err
 		}
-	case UNINSTALL:
-		err := sr.updateNodeLabels(ctx, &node, shim, UNINSTALL)
-		if err != nil {
-			log.Error().Msgf("Unable to update node label %s: %s", shim.Name, err)
-		}
+		status = ProvisioningStatusPending
 
+		// Record which configuration is being installed on the node,
+		// so that later changes to the Shim can be detected.
+		if node.Annotations == nil {
+			node.Annotations = map[string]string{}
+		}
+		node.Annotations[configHashAnnotation(shim.Name)] = job.Annotations["spinkube.dev/configHash"]
+		if artifact.sha256 != "" {
+			node.Annotations[artifactDigestAnnotation(shim.Name)] = artifact.sha256
+		} else {
+			delete(node.Annotations, artifactDigestAnnotation(shim.Name))
+		}
+	case UNINSTALL:
 		job, err = sr.createJobManifest(shim, &node, UNINSTALL, resolvedArtifact{})
 		if err != nil {
 			return err
 		}
+		status = UNINSTALL
 	default:
 		return fmt.Errorf("invalid jobType: %s", jobType)
 	}
 
+	// Jobs are immutable, so a Job of a previous run has to be removed first.
+	if err := sr.deleteFinishedJob(ctx, job); err != nil {
+		return err
+	}
+
+	clearNodeFailure(&node, shim.Name)
+	if err := sr.updateNodeLabels(ctx, &node, shim, status); err != nil {
+		log.Error().Msgf("Unable to update node label %s: %s", shim.Name, err)
+	}
+
 	// We want to use server-side apply https://kubernetes.io/docs/reference/using-api/server-side-apply
 	jobData, err := json.Marshal(job)
 	if err != nil {
@@ -334,12 +457,43 @@ func (sr *ShimReconciler) deployJobOnNode(ctx context.Context, shim *rcmv1.Shim,
 	// We rely on controller-runtime to rate limit us.
 	if err := sr.Patch(ctx, job, patchMethod, patchOptions); err != nil {
 		log.Error().Msgf("Unable to reconcile Job: %s", err)
-		if err := sr.updateNodeLabels(ctx, &node, shim, "failed"); err != nil {
+		recordShimNodeEvent(sr.Recorder, shim, &node, corev1.EventTypeWarning, EventReasonJobCreationFailed, EventActionCreateJob,
+			"Unable to create %s Job %s: %s", jobType, job.Name, err)
+		if err := sr.updateNodeLabels(ctx, &node, shim, ProvisioningStatusFailed); err != nil {
 			log.Error().Msgf("Unable to update node label %s: %s", shim.Name, err)
 		}
 		return fmt.Errorf("failed to reconcile job: %w", err)
 	}
 
+	jobsCreated.WithLabelValues(shim.Name, jobType).Inc()
+	recordShimNodeEvent(sr.Recorder, shim, &node, corev1.EventTypeNormal, EventReasonJobCreated, EventActionCreateJob,
+		"Created %s Job %s on node %s", jobType, job.Name, node.Name)
+
+	return nil
+}
+
+// deleteFinishedJob deletes a finished Job with the same name as the given Job.
+// Jobs that are still running are left untouched.
+func (sr *ShimReconciler) deleteFinishedJob(ctx context.Context, job *batchv1.Job) error {
+	log := log.Ctx(ctx)
+
+	existing := batchv1.Job{}
+	if err := sr.Get(ctx, types.NamespacedName{Name: job.Name, Namespace: job.Namespace}, &existing); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to get job: %w", err)
+	}
+
+	if finished, _ := isJobFinished(&existing); !finished {
+		return nil
+	}
+
+	log.Debug().Msgf("Deleting finished Job %s", existing.Name)
+	if err := sr.Delete(ctx, &existing, client.PropagationPolicy(metav1.DeletePropagationBackground)); client.IgnoreNotFound(err) != nil {
+		return fmt.Errorf("failed to delete finished job: %w", err)
+	}
+
 	return nil
 }
 
@@ -353,8 +507,122 @@ func (sr *ShimReconciler) updateNodeLabels(ctx context.Context, node *corev1.Nod
 	return nil
 }
 
+// configHashAnnotation returns the node annotation key that records the
+// configuration hash of the last installation of a shim on that node.
+func configHashAnnotation(shimName string) string {
+	return shimName + ".rcm.spinkube.dev/config-hash"
+}
+
+// artifactDigestAnnotation returns the node annotation key that records the
+// SHA-256 digest of the artifact last installed for a shim on that node.
+func artifactDigestAnnotation(shimName string) string {
+	return shimName + ".rcm.spinkube.dev/artifact-digest"
+}
+
+// failureReasonAnnotation returns the node annotation key that records why
+// the last operation of a shim failed on that node.
+func failureReasonAnnotation(shimName string) string {
+	return shimName + ".rcm.spinkube.dev/failure-reason"
+}
+
+// failureMessageAnnotation returns the node annotation key that records the
+// message of the last failure of a shim on that node.
+func failureMessageAnnotation(shimName string) string {
+	return shimName + ".rcm.spinkube.dev/failure-message"
+}
+
+// setNodeFailure records why an operation of a shim failed on a node. It
+// returns whether the node was changed.
+func setNodeFailure(node *corev1.Node, shimName, reason, message string) bool {
+	if len(message) > maxFailureMessageLength {
+		message = strings.ToValidUTF8(message[:maxFailureMessageLength], "") + "..."
+	}
+	if node.Annotations[failureReasonAnnotation(shimName)] == reason && node.Annotations[failureMessageAnnotation(shimName)] == message {
+		return false
+	}
+	if node.Annotations == nil {
+		node.Annotations = map[string]string{}
+	}
+	node.Annotations[failureReasonAnnotation(shimName)] = reason
+	node.Annotations[failureMessageAnnotation(shimName)] = message
+	return true
+}
+
+// clearNodeFailure removes the failure of a shim recorded on a node.
+func clearNodeFailure(node *corev1.Node, shimName string) {
+	delete(node.Annotations, failureReasonAnnotation(shimName))
+	delete(node.Annotations, failureMessageAnnotation(shimName))
+}
+
+// installConfigHash computes a hash over everything that ends up on a node when
+// installing a shim. Whenever the hash changes, the shim needs to be reinstalled.
+func installConfigHash(shim *rcmv1.Shim, artifact resolvedArtifact) string {
+	data, _ := json.Marshal(struct {
+		Location       string            `json:"location"`
+		SHA256         string            `json:"sha256"`
+		RuntimeOptions map[string]string `json:"runtimeOptions"`
+	}{
+		Location:       artifact.location,
+		SHA256:         artifact.sha256,
+		RuntimeOptions: shim.Spec.ContainerdRuntimeOptions,
+	})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])[:16]
+}
+
+// shimOutdatedOnNode reports whether the last installation of a shim on a node
+// used a different configuration than the one currently specified by the Shim.
+// Provisioned nodes without a recorded configuration, i.e. nodes provisioned
+// by a version of the controller that didn't record it, are considered up to
+// date. Other nodes without a recorded configuration are considered outdated.
+func shimOutdatedOnNode(shim *rcmv1.Shim, node *corev1.Node) bool {
+	artifact, err := resolveArtifactForNode(shim, node)
+	if err != nil {
+		// Nothing can be installed anyway, don't treat the node as outdated
+		return false
+	}
+	configHash, ok := node.Annotations[configHashAnnotation(shim.Name)]
+	if !ok {
+		return node.Labels[shim.Name] != ProvisioningStatusProvisioned
+	}
+	return configHash != installConfigHash(shim, artifact)
+}
+
+// backfillConfigHash records the current configuration of a Shim on
+// provisioned nodes that don't have a configuration recorded yet, so that
+// later changes to the Shim are rolled out to them without reinstalling the
+// shim on every such node right away.
+func (sr *ShimReconciler) backfillConfigHash(ctx context.Context, shim *rcmv1.Shim, nodes *corev1.NodeList) {
+	log := log.Ctx(ctx)
+
+	for i := range nodes.Items {
+		node := &nodes.Items[i]
+		if node.Labels[shim.Name] != ProvisioningStatusProvisioned {
+			continue
+		}
+		if _, ok := node.Annotations[configHashAnnotation(shim.Name)]; ok {
+			continue
+		}
+		artifact, err := resolveArtifactForNode(shim, node)
+		if err != nil {
+			continue
+		}
+
+		// A merge patch only sets the annotation, so it doesn't conflict with
+		// concurrent changes to the node
+		patch := client.MergeFrom(node.DeepCopy())
+		if node.Annotations == nil {
+			node.Annotations = map[string]string{}
+		}
+		node.Annotations[configHashAnnotation(shim.Name)] = installConfigHash(shim, artifact)
+		if err := sr.Patch(ctx, node, patch); err != nil {
+			log.Error().Msgf("Unable to record configuration of Shim %s on Node %s: %s", shim.Name, node.Name, err)
+		}
+	}
+}
+
 // resolveArtifactForNode selects the matching platform artifact for a given node.
-// It first checks the Platforms list for OS/arch match, then falls back to AnonHTTP.
+// It first checks the Platforms list for OS/arch match, then OCI, and falls back to AnonHTTP.
 func resolveArtifactForNode(shim *rcmv1.Shim, node *corev1.Node) (resolvedArtifact, error) {
 	nodeOS := node.Status.NodeInfo.OperatingSystem
 	nodeArch := node.Status.NodeInfo.Architecture
@@ -369,10 +637,19 @@ func resolveArtifactForNode(shim *rcmv1.Shim, node *corev1.Node) (resolvedArtifa
 				}, nil
 			}
 		}
-		return resolvedArtifact{}, fmt.Errorf("no platform artifact matches node %s (%s/%s)", node.Name, nodeOS, nodeArch)
+		return resolvedArtifact{}, fmt.Errorf("%w %s (%s/%s)", errNoPlatformMatch, node.Name, nodeOS, nodeArch)
+	}
+
+	// 2. Pull from an OCI registry, which selects the platform of the node itself
+	if oci := shim.Spec.FetchStrategy.OCI; oci != nil {
+		return resolvedArtifact{
+			location: oci.Reference,
+			oci:      oci,
+			platform: nodeOS + "/" + nodeArch,
+		}, nil
 	}
 
-	// 2. Fallback to anonHttp (backward compatible single-URL mode)
+	// 3. Fallback to anonHttp (backward compatible single-URL mode)
 	if shim.Spec.FetchStrategy.AnonHTTP != nil {
 		return resolvedArtifact{
 			location: shim.Spec.FetchStrategy.AnonHTTP.Location,
@@ -408,45 +685,12 @@ func normalizeArch(goArch string) string {
 // setOperationConfiguration sets operation specific configuration for the job manifest
 func (sr *ShimReconciler) setOperationConfiguration(shim *rcmv1.Shim, opConfig *opConfig, artifact resolvedArtifact) {
 	if opConfig.operation == INSTALL {
-		envVars := []corev1.EnvVar{
-			{
-				Name:  "SHIM_NAME",
-				Value: shim.Name,
-			},
-			{
-				Name:  "SHIM_LOCATION",
-				Value: artifact.location,
-			},
-		}
-		if artifact.sha256 != "" {
-			envVars = append(envVars, corev1.EnvVar{
-				Name:  "SHIM_SHA256",
-				Value: artifact.sha256,
-			})
-		}
-		opConfig.initContainer = []corev1.Container{{
-			Image: os.Getenv("SHIM_DOWNLOADER_IMAGE"),
-			Name:  "downloader",
-			SecurityContext: &corev1.SecurityContext{
-				Privileged: &opConfig.privileged,
-			},
-			Env: envVars,
-			VolumeMounts: []corev1.VolumeMount{
-				{
-					Name:      "shim-download",
-					MountPath: "/assets",
-				},
-			},
-		}}
-		if configMapName := os.Getenv("SHIM_DOWNLOADER_CONFIG_MAP"); configMapName != "" {
-			opConfig.initContainer[0].EnvFrom = []corev1.EnvFromSource{
-				{
-					ConfigMapRef: &corev1.ConfigMapEnvSource{
-						LocalObjectReference: corev1.LocalObjectReference{
-							Name: configMapName,
-						},
-					},
-				},
+		if artifact.oci != nil {
+			opConfig.initContainer = []corev1.Container{ociPullContainer(shim, opConfig, artifact)}
+		} else {
+			opConfig.initContainer = []corev1.Container{httpDownloadContainer(shim, opConfig, artifact)}
+			if auth := shim.Spec.FetchStrategy.HTTPAuth; auth != nil {
+				projectHTTPAuth(opConfig, auth)
 			}
 		}
 
@@ -471,6 +715,149 @@ func (sr *ShimReconciler) setOperationConfiguration(shim *rcmv1.Shim, opConfig *
 	}
 }
 
+// httpDownloadContainer returns the init container downloading the shim over HTTP.
+func httpDownloadContainer(shim *rcmv1.Shim, opConfig *opConfig, artifact resolvedArtifact) corev1.Container {
+	envVars := []corev1.EnvVar{
+		{
+			Name:  "SHIM_NAME",
+			Value: shim.Name,
+		},
+		{
+			Name:  "SHIM_LOCATION",
+			Value: artifact.location,
+		},
+	}
+	if artifact.sha256 != "" {
+		envVars = append(envVars, corev1.EnvVar{
+			Name:  "SHIM_SHA256",
+			Value: artifact.sha256,
+		})
+	}
+	container := corev1.Container{
+		Image: os.Getenv("SHIM_DOWNLOADER_IMAGE"),
+		Name:  "downloader",
+		// Surface the reason of a failure in the container status
+		TerminationMessagePolicy: corev1.TerminationMessageFallbackToLogsOnError,
+		SecurityContext: &corev1.SecurityContext{
+			Privileged: &opConfig.privileged,
+		},
+		Env: envVars,
+		VolumeMounts: []corev1.VolumeMount{
+			{
+				Name:      "shim-download",
+				MountPath: "/assets",
+			},
+		},
+	}
+	if configMapName := os.Getenv("SHIM_DOWNLOADER_CONFIG_MAP"); configMapName != "" {
+		container.EnvFrom = []corev1.EnvFromSource{
+			{
+				ConfigMapRef: &corev1.ConfigMapEnvSource{
+					LocalObjectReference: corev1.LocalObjectReference{
+						Name: configMapName,
+					},
+				},
+			},
+		}
+	}
+	return container
+}
+
+// projectHTTPAuth mounts the Secret of an HTTPAuthSpec into the downloader
+// container and tells it where to find the credentials.
+func projectHTTPAuth(opConfig *opConfig, auth *rcmv1.HTTPAuthSpec) {
+	opConfig.volumes = append(opConfig.volumes, corev1.Volume{
+		Name: "http-auth",
+		VolumeSource: corev1.VolumeSource{
+			Secret: &corev1.SecretVolumeSource{
+				SecretName: auth.SecretRef.Name,
+			},
+		},
+	})
+
+	container := &opConfig.initContainer[0]
+	container.Env = append(container.Env, corev1.EnvVar{
+		Name:  "SHIM_AUTH_DIR",
+		Value: httpAuthMountPath,
+	})
+	container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+		Name:      "http-auth",
+		MountPath: httpAuthMountPath,
+		ReadOnly:  true,
+	})
+}
+
+// ociPullContainer returns the init container pulling the shim from an OCI
+// registry. The pull command of the node-installer selects the platform of the
+// node and verifies the pulled content against its digests.
+func ociPullContainer(shim *rcmv1.Shim, opConfig *opConfig, artifact resolvedArtifact) corev1.Container {
+	args := []string{
+		"pull",
+		"--reference",
+		artifact.location,
+		"--platform",
+		artifact.platform,
+		"--output",
+		"/assets/containerd-shim-" + shim.Name,
+	}
+	if artifact.oci.PlainHTTP {
+		args = append(args, "--plain-http")
+	}
+	return corev1.Container{
+		Image: os.Getenv("SHIM_NODE_INSTALLER_IMAGE"),
+		Name:  "downloader",
+		Args:  args,
+		// Surface the reason of a failure in the container status
+		TerminationMessagePolicy: corev1.TerminationMessageFallbackToLogsOnError,
+		SecurityContext: &corev1.SecurityContext{
+			Privileged: &opConfig.privileged,
+		},
+		VolumeMounts: []corev1.VolumeMount{
+			{
+				Name:      "shim-download",
+				MountPath: "/assets",
+			},
+		},
+	}
+}
+
+// nodeContainerRuntime returns the container runtime reported by a node, in
+// the format of the node-installer's --container-runtime flag. It returns an
+// empty string for unknown runtimes, which lets the node-installer detect it.
+func nodeContainerRuntime(node *corev1.Node) string {
+	runtime, _, found := strings.Cut(node.Status.NodeInfo.ContainerRuntimeVersion, "://")
+	if !found {
+		return ""
+	}
+	switch runtime {
+	case "containerd", "cri-o":
+		return runtime
+	default:
+		return ""
+	}
+}
+
+// containerdConfigArgs returns the node-installer flags configuring how
+// runtimes are added to the containerd config. Flags that are not configured
+// are left out, so that the defaults of the node-installer apply.
+func containerdConfigArgs() []string {
+	var args []string
+	if mode := os.Getenv("SHIM_NODE_INSTALLER_CONTAINERD_CONFIG_MODE"); mode != "" {
+		args = append(args, "--containerd-config-mode", mode)
+	}
+	if dir := os.Getenv("SHIM_NODE_INSTALLER_CONTAINERD_DROP_IN_DIR"); dir != "" {
+		args = append(args, "--containerd-drop-in-dir", dir)
+	}
+	return args
+}
+
+// jobName returns the name of the Job performing an operation for a Shim on a node.
+func jobName(node *corev1.Node, shim *rcmv1.Shim, operation string) string {
+	name := node.Name + "-" + shim.Name + "-" + operation
+	nameMax := int(math.Min(float64(len(name)), K8sNameMaxLength))
+	return name[:nameMax]
+}
+
 // createJobManifest creates a Job manifest for a Shim.
 //
 //nolint:funlen // function is longer due to scaffolding an entire K8s Job manifest
@@ -480,9 +867,15 @@ func (sr *ShimReconciler) createJobManifest(shim *rcmv1.Shim, node *corev1.Node,
 		privileged: true,
 	}
 	sr.setOperationConfiguration(shim, &opConfig, artifact)
+	containerRuntime := nodeContainerRuntime(node)
+	if containerRuntime != "" {
+		opConfig.args = append(opConfig.args, "--container-runtime", containerRuntime)
+	}
+	if containerRuntime != "cri-o" {
+		opConfig.args = append(opConfig.args, containerdConfigArgs()...)
+	}
 
-	name := node.Name + "-" + shim.Name + "-" + operation
-	nameMax := int(math.Min(float64(len(name)), K8sNameMaxLength))
+	name := jobName(node, shim, operation)
 
 	job := &batchv1.Job{
 		TypeMeta: metav1.TypeMeta{
@@ -490,7 +883,7 @@ func (sr *ShimReconciler) createJobManifest(shim *rcmv1.Shim, node *corev1.Node,
 			Kind:       "Job",
 		},
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      name[:nameMax],
+			Name:      name,
 			Namespace: os.Getenv("CONTROLLER_NAMESPACE"),
 			Annotations: map[string]string{
 				"spinkube.dev/nodeName":  node.Name,
@@ -498,7 +891,7 @@ func (sr *ShimReconciler) createJobManifest(shim *rcmv1.Shim, node *corev1.Node,
 				"spinkube.dev/operation": operation,
 			},
 			Labels: map[string]string{
-				name[:nameMax]:           "true",
+				name:                     "true",
 				"spinkube.dev/shimName":  shim.Name,
 				"spinkube.dev/operation": operation,
 				"spinkube.dev/job":       "true",
@@ -509,7 +902,7 @@ func (sr *ShimReconciler) createJobManifest(shim *rcmv1.Shim, node *corev1.Node,
 				Spec: corev1.PodSpec{
 					NodeName: node.Name,
 					HostPID:  true,
-					Volumes: []corev1.Volume{
+					Volumes: append([]corev1.Volume{
 						{
 							Name: "shim-download",
 						},
@@ -521,12 +914,14 @@ func (sr *ShimReconciler) createJobManifest(shim *rcmv1.Shim, node *corev1.Node,
 								},
 							},
 						},
-					},
+					}, opConfig.volumes...),
 					InitContainers: opConfig.initContainer,
 					Containers: []corev1.Container{{
 						Image: os.Getenv("SHIM_NODE_INSTALLER_IMAGE"),
 						Args:  opConfig.args,
 						Name:  "provisioner",
+						// Surface the reason of a failure in the container status
+						TerminationMessagePolicy: corev1.TerminationMessageFallbackToLogsOnError,
 						SecurityContext: &corev1.SecurityContext{
 							Privileged: &opConfig.privileged,
 						},
@@ -572,24 +967,49 @@ func (sr *ShimReconciler) createJobManifest(shim *rcmv1.Shim, node *corev1.Node,
 		}
 	}
 	if operation == INSTALL {
-		if err := ctrl.SetControllerReference(shim, job, sr.Scheme); err != nil {
-			return nil, fmt.Errorf("failed to set controller reference: %w", err)
-		}
+		job.Annotations["spinkube.dev/configHash"] = installConfigHash(shim, artifact)
+	}
+	if err := ctrl.SetControllerReference(shim, job, sr.Scheme); err != nil {
+		return nil, fmt.Errorf("failed to set controller reference: %w", err)
 	}
 
 	return job, nil
 }
 
-// handleDeployRuntimeClass deploys a RuntimeClass for a Shim.
+// handleDeployRuntimeClass deploys a RuntimeClass for a Shim. The RuntimeClass
+// is applied on every reconciliation, so that changes to the Shim and manual
+// changes to the RuntimeClass are corrected.
 func (sr *ShimReconciler) handleDeployRuntimeClass(ctx context.Context, shim *rcmv1.Shim) (ctrl.Result, error) {
 	log := log.Ctx(ctx)
 
-	log.Info().Msgf("Deploying RuntimeClass: %s", shim.Spec.RuntimeClass.Name)
 	runtimeClass, err := sr.createRuntimeClassManifest(shim)
 	if err != nil {
 		return ctrl.Result{}, err
 	}
 
+	existing := &nodev1.RuntimeClass{}
+	if err := sr.Get(ctx, types.NamespacedName{Name: runtimeClass.Name}, existing); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return ctrl.Result{}, fmt.Errorf("failed to get runtimeClass: %w", err)
+		}
+		existing = nil
+		log.Info().Msgf("Deploying RuntimeClass: %s", runtimeClass.Name)
+	}
+
+	// The handler of a RuntimeClass is immutable, so the RuntimeClass has to
+	// be recreated if it changed. RuntimeClasses that haven't been created
+	// for the Shim are left alone.
+	if existing != nil && existing.Handler != runtimeClass.Handler {
+		if !metav1.IsControlledBy(existing, shim) {
+			return ctrl.Result{}, fmt.Errorf("RuntimeClass %s with handler %s is not managed by Shim %s", existing.Name, existing.Handler, shim.Name)
+		}
+		log.Info().Msgf("Recreating RuntimeClass %s, handler changed from %s to %s", runtimeClass.Name, existing.Handler, runtimeClass.Handler)
+		if err := sr.Delete(ctx, existing); client.IgnoreNotFound(err) != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to delete RuntimeClass: %w", err)
+		}
+		existing = nil
+	}
+
 	// We want to use server-side apply https://kubernetes.io/docs/reference/using-api/server-side-apply
 	runtimeClassData, err := json.Marshal(runtimeClass)
 	if err != nil {
@@ -607,7 +1027,41 @@ func (sr *ShimReconciler) handleDeployRuntimeClass(ctx context.Context, shim *rc
 		return ctrl.Result{}, fmt.Errorf("failed to reconcile RuntimeClass: %w", err)
 	}
 
-	return ctrl.Result{}, nil
+	switch {
+	case existing == nil:
+		sr.Recorder.Eventf(shim, runtimeClass, corev1.EventTypeNormal, EventReasonRuntimeClassCreated, EventActionCreateRuntimeClass,
+			"Created RuntimeClass %s with handler %s", runtimeClass.Name, runtimeClass.Handler)
+	case existing.ResourceVersion != runtimeClass.ResourceVersion:
+		log.Info().Msgf("Updated RuntimeClass %s", runtimeClass.Name)
+		sr.Recorder.Eventf(shim, runtimeClass, corev1.EventTypeNormal, EventReasonRuntimeClassUpdated, EventActionUpdateRuntimeClass,
+			"Updated RuntimeClass %s", runtimeClass.Name)
+	}
+
+	return ctrl.Result{}, sr.deleteStaleRuntimeClasses(ctx, shim, runtimeClass.Name)
+}
+
+// deleteStaleRuntimeClasses deletes the RuntimeClasses controlled by a Shim
+// other than the one named name, e.g. after spec.runtimeClass.name changed.
+func (sr *ShimReconciler) deleteStaleRuntimeClasses(ctx context.Context, shim *rcmv1.Shim, name string) error {
+	log := log.Ctx(ctx)
+
+	runtimeClasses := &nodev1.RuntimeClassList{}
+	if err := sr.List(ctx, runtimeClasses); err != nil {
+		return fmt.Errorf("failed to list RuntimeClasses: %w", err)
+	}
+
+	for _, runtimeClass := range runtimeClasses.Items {
+		if runtimeClass.Name == name || !metav1.IsControlledBy(&runtimeClass, shim) {
+			continue
+		}
+		log.Info().Msgf("Deleting RuntimeClass %s, the RuntimeClass of Shim %s is %s now", runtimeClass.Name, shim.Name, name)
+		if err := sr.Delete(ctx, &runtimeClass); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("failed to delete RuntimeClass: %w", err)
+		}
+		sr.Recorder.Eventf(shim, &runtimeClass, corev1.EventTypeNormal, EventReasonRuntimeClassDeleted, EventActionDeleteRuntimeClass,
+			"Deleted RuntimeClass %s, replaced by %s", runtimeClass.Name, name)
+	}
+	return nil
 }
 
 // createRuntimeClassManifest creates a RuntimeClass manifest for a Shim.
@@ -615,10 +1069,11 @@ func (sr *ShimReconciler) createRuntimeClassManifest(shim *rcmv1.Shim) (*nodev1.
 	name := shim.Spec.RuntimeClass.Name
 	nameMax := int(math.Min(float64(len(name)), K8sNameMaxLength))
 
-	nodeSelector := shim.Spec.NodeSelector
-	if nodeSelector == nil {
-		nodeSelector = map[string]string{}
-	}
+	nodeSelector := runtimeClassNodeSelector(shim)
+
+	labels := map[string]string{}
+	maps.Copy(labels, shim.Spec.RuntimeClass.Labels)
+	labels[name[:nameMax]] = "true"
 
 	runtimeClass := &nodev1.RuntimeClass{
 		TypeMeta: metav1.TypeMeta{
@@ -626,12 +1081,15 @@ func (sr *ShimReconciler) createRuntimeClassManifest(shim *rcmv1.Shim) (*nodev1.
 			Kind:       "RuntimeClass",
 		},
 		ObjectMeta: metav1.ObjectMeta{
-			Name:   name[:nameMax],
-			Labels: map[string]string{name[:nameMax]: "true"},
+			Name:        name[:nameMax],
+			Labels:      labels,
+			Annotations: shim.Spec.RuntimeClass.Annotations,
 		},
-		Handler: shim.Spec.RuntimeClass.Handler,
+		Handler:  shim.Spec.RuntimeClass.Handler,
+		Overhead: shim.Spec.RuntimeClass.Overhead,
 		Scheduling: &nodev1.Scheduling{
 			NodeSelector: nodeSelector,
+			Tolerations:  shim.Spec.RuntimeClass.Tolerations,
 		},
 	}
 
@@ -642,24 +1100,318 @@ func (sr *ShimReconciler) createRuntimeClassManifest(shim *rcmv1.Shim) (*nodev1.
 	return runtimeClass, nil
 }
 
+// runtimeClassNodeSelector returns the node selector of the RuntimeClass of a
+// Shim. With the provisioned node selection, it also selects the status label
+// the JobReconciler sets once the shim is installed on a node.
+func runtimeClassNodeSelector(shim *rcmv1.Shim) map[string]string {
+	nodeSelector := map[string]string{}
+	maps.Copy(nodeSelector, shim.Spec.NodeSelector)
+	if shim.Spec.RuntimeClass.NodeSelection == rcmv1.NodeSelectionProvisioned {
+		nodeSelector[shim.Name] = ProvisioningStatusProvisioned
+	}
+	return nodeSelector
+}
+
 // handleDeleteShim deletes all possible child resources of a Shim. It will ignore NotFound errors.
-func (sr *ShimReconciler) handleDeleteShim(ctx context.Context, shim *rcmv1.Shim, nodes *corev1.NodeList) error {
-	// deploy uninstall job on every node in node list
+// It returns the nodes on which the uninstallation waits for pods.
+func (sr *ShimReconciler) handleDeleteShim(ctx context.Context, shim *rcmv1.Shim) ([]string, error) {
+	log := log.Ctx(ctx)
+
+	// deploy uninstall job on every node the shim is installed on, including
+	// nodes that don't match its node selector anymore
+	nodes, err := sr.getNodeListWithShimLabel(ctx, shim)
+	if err != nil {
+		return nil, err
+	}
+	blocked := []string{}
+	shimUninstallationErrors := []error{}
 	for i := range nodes.Items {
 		node := nodes.Items[i]
 
-		if _, exists := node.Labels[shim.Name]; exists {
-			err := sr.deployJobOnNode(ctx, shim, node, UNINSTALL)
-			if client.IgnoreNotFound(err) != nil {
-				return err
+		switch node.Labels[shim.Name] {
+		case UNINSTALL, ProvisioningStatusPending:
+			continue
+		case ProvisioningStatusFailed:
+			// Don't retry a failed uninstallation over and over again, it
+			// is reported in the status until the node is fixed or the
+			// deletion is forced
+			failed, err := sr.uninstallJobFailed(ctx, shim, &node)
+			if err != nil {
+				shimUninstallationErrors = append(shimUninstallationErrors, err)
+				continue
 			}
-		} else {
-			log.Info().Msgf("Shim %s has no label on Node %s", shim.Name, node.Name)
+			if failed {
+				log.Warn().Msgf("Uninstallation of Shim %s failed on Node %s, delete the failed Job to retry or annotate the Shim with %s=true", shim.Name, node.Name, ForceDeleteAnnotation)
+				continue
+			}
+		}
+
+		isBlocked, err := sr.uninstallBlocked(ctx, shim, &node)
+		if err != nil {
+			shimUninstallationErrors = append(shimUninstallationErrors, err)
+			continue
+		}
+		if isBlocked {
+			blocked = append(blocked, node.Name)
+			continue
 		}
+
+		err = sr.deployJobOnNode(ctx, shim, node, UNINSTALL)
+		if client.IgnoreNotFound(err) != nil {
+			shimUninstallationErrors = append(shimUninstallationErrors, err)
+		}
+	}
+	return blocked, errors.Join(shimUninstallationErrors...)
+}
+
+// uninstallJobFailed returns whether the uninstall Job of a Shim failed on a node.
+func (sr *ShimReconciler) uninstallJobFailed(ctx context.Context, shim *rcmv1.Shim, node *corev1.Node) (bool, error) {
+	job := batchv1.Job{}
+	err := sr.Get(ctx, types.NamespacedName{Name: jobName(node, shim, UNINSTALL), Namespace: os.Getenv("CONTROLLER_NAMESPACE")}, &job)
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to get job: %w", err)
+	}
+	_, finishedType := isJobFinished(&job)
+	return finishedType == batchv1.JobFailed, nil
+}
+
+// reconcileDeletion uninstalls a Shim that has been requested for deletion
+// from all nodes. The finalizer is only removed once no node carries the
+// status label of the Shim anymore, or if the deletion is forced.
+func (sr *ShimReconciler) reconcileDeletion(ctx context.Context, shim *rcmv1.Shim) (ctrl.Result, error) {
+	log := log.Ctx(ctx)
+
+	if shim.Annotations[ForceDeleteAnnotation] == "true" {
+		log.Warn().Msgf("Deletion of Shim %s is forced, not waiting for nodes to be uninstalled", shim.Name)
+		return ctrl.Result{}, client.IgnoreNotFound(sr.removeFinalizerFromShim(ctx, shim))
+	}
+
+	blocked, deleteErr := sr.handleDeleteShim(ctx, shim)
+
+	// The status labels of nodes change while the uninstall Jobs run, which
+	// reconciles the Shim again
+	remaining, err := sr.getNodeListWithShimLabel(ctx, shim)
+	if err != nil {
+		return ctrl.Result{}, errors.Join(deleteErr, err)
+	}
+	if err := sr.updateStatus(ctx, shim, remaining); err != nil {
+		return ctrl.Result{}, errors.Join(deleteErr, client.IgnoreNotFound(err))
+	}
+	if err := sr.updateBlockedCondition(ctx, shim, blocked); err != nil {
+		return ctrl.Result{}, errors.Join(deleteErr, client.IgnoreNotFound(err))
+	}
+	if len(remaining.Items) > 0 {
+		log.Info().Msgf("Waiting for Shim %s to be uninstalled from %d node(s)", shim.Name, len(remaining.Items))
+		return uninstallBlockedResult(blocked), deleteErr
+	}
+
+	return ctrl.Result{}, errors.Join(deleteErr, client.IgnoreNotFound(sr.removeFinalizerFromShim(ctx, shim)))
+}
+
+// uninstallBlocked checks whether pods on a node still use the RuntimeClass
+// of a Shim before it is uninstalled from the node. Depending on the uninstall
+// policy of the Shim, the pods are waited for, evicted or ignored. It returns
+// whether the uninstallation has to wait for the pods.
+func (sr *ShimReconciler) uninstallBlocked(ctx context.Context, shim *rcmv1.Shim, node *corev1.Node) (bool, error) {
+	log := log.Ctx(ctx)
+
+	if shim.Spec.UninstallPolicy == rcmv1.UninstallPolicyForce || shim.Spec.RuntimeClass.Name == "" {
+		return false, nil
+	}
+
+	pods := corev1.PodList{}
+	if err := sr.APIReader.List(ctx, &pods, client.MatchingFields{"spec.nodeName": node.Name}); err != nil {
+		return false, fmt.Errorf("failed to list pods on node %s: %w", node.Name, err)
+	}
+	blocking := podsUsingRuntimeClass(shim, pods.Items)
+	if len(blocking) == 0 {
+		return false, nil
+	}
+
+	names := make([]string, 0, len(blocking))
+	for i := range blocking {
+		pod := &blocking[i]
+		names = append(names, pod.Namespace+"/"+pod.Name)
+		if shim.Spec.UninstallPolicy != rcmv1.UninstallPolicyEvict || !pod.DeletionTimestamp.IsZero() {
+			continue
+		}
+
+		// Evictions respect PodDisruptionBudgets, so they may have to be
+		// retried until enough replicas are available elsewhere
+		eviction := &policyv1.Eviction{ObjectMeta: metav1.ObjectMeta{Name: pod.Name, Namespace: pod.Namespace}}
+		if err := sr.SubResource("eviction").Create(ctx, pod, eviction); client.IgnoreNotFound(err) != nil {
+			log.Warn().Msgf("Unable to evict Pod %s/%s from Node %s: %s", pod.Namespace, pod.Name, node.Name, err)
+			continue
+		}
+		recordShimNodeEvent(sr.Recorder, shim, node, corev1.EventTypeNormal, EventReasonPodEvicted, EventActionEvictPod,
+			"Evicted Pod %s/%s using RuntimeClass %s from node %s", pod.Namespace, pod.Name, shim.Spec.RuntimeClass.Name, node.Name)
+	}
+
+	log.Info().Msgf("Uninstallation of Shim %s from Node %s waits for pods using RuntimeClass %s: %s",
+		shim.Name, node.Name, shim.Spec.RuntimeClass.Name, strings.Join(names, ", "))
+	return true, nil
+}
+
+// podsUsingRuntimeClass returns the pods that run with the RuntimeClass of a
+// Shim and haven't terminated yet.
+func podsUsingRuntimeClass(shim *rcmv1.Shim, pods []corev1.Pod) []corev1.Pod {
+	using := []corev1.Pod{}
+	for _, pod := range pods {
+		if pod.Spec.RuntimeClassName == nil || *pod.Spec.RuntimeClassName != shim.Spec.RuntimeClass.Name {
+			continue
+		}
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		using = append(using, pod)
+	}
+	return using
+}
+
+// uninstallBlockedResult requeues the Shim to check again whether the pods
+// blocking its uninstallation are gone, as pods are not watched.
+func uninstallBlockedResult(blocked []string) ctrl.Result {
+	if len(blocked) == 0 {
+		return ctrl.Result{}
+	}
+	return ctrl.Result{RequeueAfter: uninstallBlockedRequeueInterval}
+}
+
+// updateBlockedCondition reports the nodes on which the uninstallation of a
+// Shim waits for pods in its Blocked condition.
+func (sr *ShimReconciler) updateBlockedCondition(ctx context.Context, shim *rcmv1.Shim, blocked []string) error {
+	if !setBlockedCondition(shim, blocked) {
+		return nil
+	}
+	if err := sr.Status().Update(ctx, shim); err != nil {
+		return fmt.Errorf("failed to update status: %w", err)
 	}
 	return nil
 }
 
+// handleUninstallStaleShim deploys uninstall Jobs to nodes that carry the
+// status label of a Shim, but don't match its node selector anymore, e.g.
+// because the label of a node or the node selector changed.
+func (sr *ShimReconciler) handleUninstallStaleShim(ctx context.Context, shim *rcmv1.Shim, nodes *corev1.NodeList) ([]string, error) {
+	log := log.Ctx(ctx)
+
+	labeled, err := sr.getNodeListWithShimLabel(ctx, shim)
+	if err != nil {
+		return nil, err
+	}
+	stale, err := sr.withoutFailedUninstalls(ctx, shim, staleNodes(labeled.Items, nodes.Items))
+	if err != nil {
+		return nil, err
+	}
+	if len(stale) == 0 {
+		return nil, nil
+	}
+
+	batch, inProgress := nextUninstallBatch(shim, stale)
+	if inProgress > 0 && len(batch) == 0 {
+		log.Info().Msgf("Waiting for %d node(s) that don't match the node selector anymore to finish", inProgress)
+		return nil, nil
+	}
+
+	blocked := []string{}
+	shimUninstallationErrors := []error{}
+	for _, node := range batch {
+		log.Info().Msgf("Node %s doesn't match the node selector of Shim %s anymore", node.Name, shim.Name)
+		isBlocked, err := sr.uninstallBlocked(ctx, shim, &node)
+		if err != nil {
+			shimUninstallationErrors = append(shimUninstallationErrors, err)
+			continue
+		}
+		if isBlocked {
+			blocked = append(blocked, node.Name)
+			continue
+		}
+		err = sr.deployJobOnNode(ctx, shim, node, UNINSTALL)
+		shimUninstallationErrors = append(shimUninstallationErrors, client.IgnoreNotFound(err))
+	}
+	return blocked, errors.Join(shimUninstallationErrors...)
+}
+
+// withoutFailedUninstalls filters out the nodes on which the uninstall Job of
+// a Shim failed. Like on deletion, a failed uninstallation is not retried over
+// and over again, but only once the failed Job is gone.
+func (sr *ShimReconciler) withoutFailedUninstalls(ctx context.Context, shim *rcmv1.Shim, nodes []corev1.Node) ([]corev1.Node, error) {
+	log := log.Ctx(ctx)
+
+	remaining := []corev1.Node{}
+	for _, node := range nodes {
+		if node.Labels[shim.Name] == ProvisioningStatusFailed {
+			failed, err := sr.uninstallJobFailed(ctx, shim, &node)
+			if err != nil {
+				return nil, err
+			}
+			if failed {
+				log.Warn().Msgf("Uninstallation of Shim %s failed on Node %s", shim.Name, node.Name)
+				continue
+			}
+		}
+		remaining = append(remaining, node)
+	}
+	return remaining, nil
+}
+
+// staleNodes returns the nodes of labeled that are not in selected.
+func staleNodes(labeled, selected []corev1.Node) []corev1.Node {
+	selectedNames := make(map[string]bool, len(selected))
+	for _, node := range selected {
+		selectedNames[node.Name] = true
+	}
+
+	stale := []corev1.Node{}
+	for _, node := range labeled {
+		if !selectedNames[node.Name] {
+			stale = append(stale, node)
+		}
+	}
+	return stale
+}
+
+// nextUninstallBatch determines the stale nodes to uninstall a shim from
+// next, and the number of stale nodes on which a Job is still running. Nodes
+// are uninstalled once their install Job finished. With the recreate strategy,
+// all other nodes are uninstalled at once. With the rolling strategy, at most
+// maxUpdate nodes are uninstalled at a time, and the next batch is only started
+// once no Job is running anymore. Nodes where the uninstallation failed must
+// be filtered out before, see withoutFailedUninstalls.
+func nextUninstallBatch(shim *rcmv1.Shim, stale []corev1.Node) (batch []corev1.Node, inProgress int) {
+	candidates := []corev1.Node{}
+	for _, node := range stale {
+		switch node.Labels[shim.Name] {
+		case ProvisioningStatusPending, UNINSTALL:
+			inProgress++
+		default:
+			candidates = append(candidates, node)
+		}
+	}
+
+	if shim.Spec.RolloutStrategy.Type != rcmv1.RolloutStrategyTypeRolling {
+		return candidates, inProgress
+	}
+	if inProgress > 0 {
+		return nil, inProgress
+	}
+
+	maxUpdate := shim.Spec.RolloutStrategy.Rolling.MaxUpdate
+	if maxUpdate < 1 {
+		maxUpdate = 1
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].Name < candidates[j].Name
+	})
+	if len(candidates) > maxUpdate {
+		candidates = candidates[:maxUpdate]
+	}
+	return candidates, 0
+}
+
 func (sr *ShimReconciler) getNodeListFromShimsNodeSelector(ctx context.Context, shim *rcmv1.Shim) (*corev1.NodeList, error) {
 	nodes := &corev1.NodeList{}
 	if shim.Spec.NodeSelector != nil {
@@ -677,32 +1429,14 @@ func (sr *ShimReconciler) getNodeListFromShimsNodeSelector(ctx context.Context,
 	return nodes, nil
 }
 
-// runtimeClassExists checks whether a RuntimeClass for a Shim exists.
-func (sr *ShimReconciler) runtimeClassExists(ctx context.Context, shim *rcmv1.Shim) (bool, error) {
-	log := log.Ctx(ctx)
-
-	if shim.Spec.RuntimeClass.Name != "" {
-		runtimeClass, err := sr.getRuntimeClass(ctx, shim)
-		if err != nil {
-			log.Debug().Msgf("No RuntimeClass '%s' found", shim.Spec.RuntimeClass.Name)
-
-			return false, err
-		}
-		log.Debug().Msgf("RuntimeClass found: %s", runtimeClass.Name)
-		return true, nil
-	}
-	log.Debug().Msg("Shim.Spec.RuntimeClass not defined")
-	return false, nil
-}
-
-// getRuntimeClass finds a RuntimeClass.
-func (sr *ShimReconciler) getRuntimeClass(ctx context.Context, shim *rcmv1.Shim) (*nodev1.RuntimeClass, error) {
-	rc := nodev1.RuntimeClass{}
-	err := sr.Get(ctx, types.NamespacedName{Name: shim.Spec.RuntimeClass.Name, Namespace: shim.Namespace}, &rc)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get runtimeClass: %w", err)
+// getNodeListWithShimLabel returns the nodes that carry the status label of a
+// Shim, i.e. the nodes it is installed on or that a Job has been deployed to.
+func (sr *ShimReconciler) getNodeListWithShimLabel(ctx context.Context, shim *rcmv1.Shim) (*corev1.NodeList, error) {
+	nodes := &corev1.NodeList{}
+	if err := sr.List(ctx, nodes, client.HasLabels{shim.Name}); err != nil {
+		return &corev1.NodeList{}, fmt.Errorf("failed to get node list: %w", err)
 	}
-	return &rc, nil
+	return nodes, nil
 }
 
 // removeFinalizerFromShim removes the finalizer from a Shim.
@@ -712,6 +1446,9 @@ func (sr *ShimReconciler) removeFinalizerFromShim(ctx context.Context, shim *rcm
 		if err := sr.Update(ctx, shim); err != nil {
 			return fmt.Errorf("failed to remove finalizer: %w", err)
 		}
+		deleteNodeMetrics(shim.Name)
+		sr.Recorder.Eventf(shim, nil, corev1.EventTypeNormal, EventReasonFinalizerRemoved, EventActionRemoveFinalizer,
+			"Removed finalizer %s", RCMOperatorFinalizer)
 	}
 	return nil
 }
@@ -1,10 +1,23 @@
 package controller //nolint:testpackage // whitebox test
 
 import (
+	"context"
+	"maps"
+	"strings"
 	"testing"
 
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	nodev1 "k8s.io/api/node/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/events"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
 	rcmv1 "github.com/spinframework/runtime-class-manager/api/v1alpha1"
 )
@@ -33,6 +46,12 @@ func makeShim(platforms []rcmv1.PlatformArtifact, anonHTTP *rcmv1.AnonHTTPSpec)
 	}
 }
 
+func makeOCIShim(platforms []rcmv1.PlatformArtifact, anonHTTP *rcmv1.AnonHTTPSpec) *rcmv1.Shim {
+	shim := makeShim(platforms, anonHTTP)
+	shim.Spec.FetchStrategy.OCI = &rcmv1.OCISpec{Reference: "registry.example.com/shims/spin:v1"}
+	return shim
+}
+
 func TestResolveArtifactForNode(t *testing.T) {
 	tests := []struct {
 		name         string
@@ -126,6 +145,26 @@ func TestResolveArtifactForNode(t *testing.T) {
 			node:    makeNode("amd64"),
 			wantErr: true,
 		},
+		{
+			name:         "resolves oci reference",
+			shim:         makeOCIShim(nil, nil),
+			node:         makeNode("arm64"),
+			wantLocation: "registry.example.com/shims/spin:v1",
+		},
+		{
+			name:         "oci takes precedence over anonHttp",
+			shim:         makeOCIShim(nil, &rcmv1.AnonHTTPSpec{Location: "https://example.com/shim-anon.tar.gz"}),
+			node:         makeNode("amd64"),
+			wantLocation: "registry.example.com/shims/spin:v1",
+		},
+		{
+			name: "platforms take precedence over oci",
+			shim: makeOCIShim([]rcmv1.PlatformArtifact{
+				{OS: "linux", Arch: "amd64", Location: "https://example.com/shim-platform.tar.gz"},
+			}, nil),
+			node:         makeNode("amd64"),
+			wantLocation: "https://example.com/shim-platform.tar.gz",
+		},
 		{
 			name:    "no fetch source configured returns error",
 			shim:    makeShim(nil, nil),
@@ -164,6 +203,58 @@ func TestResolveArtifactForNode(t *testing.T) {
 	}
 }
 
+func TestSetOperationConfiguration_OCI(t *testing.T) {
+	t.Setenv("SHIM_NODE_INSTALLER_IMAGE", "ghcr.io/spinframework/node-installer:latest")
+	shim := makeOCIShim(nil, nil)
+	shim.Spec.FetchStrategy.OCI.PlainHTTP = true
+	artifact, err := resolveArtifactForNode(shim, makeNode("arm64"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	sr := &ShimReconciler{}
+	opConfig := &opConfig{operation: INSTALL}
+	sr.setOperationConfiguration(shim, opConfig, artifact)
+
+	if len(opConfig.initContainer) != 1 {
+		t.Fatalf("init containers = %d, want 1", len(opConfig.initContainer))
+	}
+	container := opConfig.initContainer[0]
+	if container.Image != "ghcr.io/spinframework/node-installer:latest" {
+		t.Errorf("image = %q, want the node-installer image", container.Image)
+	}
+	wantArgs := "pull --reference registry.example.com/shims/spin:v1 --platform linux/arm64 --output /assets/containerd-shim-test-shim --plain-http"
+	if got := strings.Join(container.Args, " "); got != wantArgs {
+		t.Errorf("args = %q, want %q", got, wantArgs)
+	}
+}
+
+func TestSetOperationConfiguration_HTTPAuth(t *testing.T) {
+	shim := makeShim(nil, &rcmv1.AnonHTTPSpec{Location: "https://example.com/shim.tar.gz"})
+	shim.Spec.FetchStrategy.HTTPAuth = &rcmv1.HTTPAuthSpec{SecretRef: corev1.LocalObjectReference{Name: "shim-credentials"}}
+	artifact, err := resolveArtifactForNode(shim, makeNode("amd64"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	sr := &ShimReconciler{}
+	opConfig := &opConfig{operation: INSTALL}
+	sr.setOperationConfiguration(shim, opConfig, artifact)
+
+	if len(opConfig.volumes) != 1 || opConfig.volumes[0].Secret == nil || opConfig.volumes[0].Secret.SecretName != "shim-credentials" {
+		t.Fatalf("volumes = %+v, want the shim-credentials Secret", opConfig.volumes)
+	}
+	downloader := opConfig.initContainer[0]
+	mount := downloader.VolumeMounts[len(downloader.VolumeMounts)-1]
+	if mount.Name != opConfig.volumes[0].Name || mount.MountPath != httpAuthMountPath || !mount.ReadOnly {
+		t.Errorf("mount = %+v, want the Secret mounted read-only at %s", mount, httpAuthMountPath)
+	}
+	env := downloader.Env[len(downloader.Env)-1]
+	if env.Name != "SHIM_AUTH_DIR" || env.Value != httpAuthMountPath {
+		t.Errorf("env = %+v, want SHIM_AUTH_DIR=%s", env, httpAuthMountPath)
+	}
+}
+
 func TestMatchesPlatform(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -261,3 +352,808 @@ func TestNormalizeArch(t *testing.T) {
 		})
 	}
 }
+
+func TestNodeContainerRuntime(t *testing.T) {
+	tests := []struct {
+		version string
+		want    string
+	}{
+		{"containerd://1.7.27", "containerd"},
+		{"cri-o://1.31.1", "cri-o"},
+		{"docker://28.0.1", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			node := &corev1.Node{Status: corev1.NodeStatus{NodeInfo: corev1.NodeSystemInfo{ContainerRuntimeVersion: tt.version}}}
+			if got := nodeContainerRuntime(node); got != tt.want {
+				t.Errorf("nodeContainerRuntime(%q) = %q, want %q", tt.version, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestContainerdConfigArgs(t *testing.T) {
+	if got := containerdConfigArgs(); len(got) != 0 {
+		t.Errorf("containerdConfigArgs() = %q, want no args by default", got)
+	}
+
+	t.Setenv("SHIM_NODE_INSTALLER_CONTAINERD_CONFIG_MODE", "drop-in")
+	t.Setenv("SHIM_NODE_INSTALLER_CONTAINERD_DROP_IN_DIR", "/etc/containerd/rcm.d")
+	want := "--containerd-config-mode drop-in --containerd-drop-in-dir /etc/containerd/rcm.d"
+	if got := strings.Join(containerdConfigArgs(), " "); got != want {
+		t.Errorf("containerdConfigArgs() = %q, want %q", got, want)
+	}
+}
+
+func TestCreateJobManifestArgs(t *testing.T) {
+	tests := []struct {
+		name             string
+		configMode       string
+		dropInDir        string
+		containerRuntime string
+		want             string
+	}{
+		{"defaults", "", "", "containerd://1.7.0", "uninstall -H /mnt/node-root -r test-shim --container-runtime containerd"},
+		{
+			"containerd config mode", "drop-in", "/etc/containerd/rcm.d", "containerd://1.7.0",
+			"uninstall -H /mnt/node-root -r test-shim --container-runtime containerd --containerd-config-mode drop-in --containerd-drop-in-dir /etc/containerd/rcm.d",
+		},
+		{"only the config mode", "drop-in", "", "", "uninstall -H /mnt/node-root -r test-shim --containerd-config-mode drop-in"},
+		{"cri-o ignores the containerd config mode", "drop-in", "/etc/containerd/rcm.d", "cri-o://1.30.0", "uninstall -H /mnt/node-root -r test-shim --container-runtime cri-o"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("SHIM_NODE_INSTALLER_CONTAINERD_CONFIG_MODE", tt.configMode)
+			t.Setenv("SHIM_NODE_INSTALLER_CONTAINERD_DROP_IN_DIR", tt.dropInDir)
+			shim := makeStatusShim(rcmv1.RolloutStrategyTypeRecreate)
+			node := makeNode("amd64")
+			node.Status.NodeInfo.ContainerRuntimeVersion = tt.containerRuntime
+			sr := newFakeReconciler(t, shim)
+
+			job, err := sr.createJobManifest(shim, node, UNINSTALL, resolvedArtifact{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := strings.Join(job.Spec.Template.Spec.Containers[0].Args, " "); got != tt.want {
+				t.Errorf("args = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func makeLabeledNode(name string, status string) corev1.Node {
+	node := corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{}},
+	}
+	if status != "" {
+		node.Labels["test-shim"] = status
+	}
+	return node
+}
+
+// makeOutdatedNode returns a node on which a previous configuration of the
+// shim has been installed with the given status.
+func makeOutdatedNode(name string, status string) corev1.Node {
+	node := makeLabeledNode(name, status)
+	node.Annotations = map[string]string{configHashAnnotation("test-shim"): "previous"}
+	return node
+}
+
+func makeRollingShim(maxUpdate int) *rcmv1.Shim {
+	return &rcmv1.Shim{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-shim"},
+		Spec: rcmv1.ShimSpec{
+			RolloutStrategy: rcmv1.RolloutStrategy{
+				Type:    rcmv1.RolloutStrategyTypeRolling,
+				Rolling: rcmv1.RollingSpec{MaxUpdate: maxUpdate},
+			},
+		},
+	}
+}
+
+func TestNextRollingBatch(t *testing.T) {
+	tests := []struct {
+		name           string
+		shim           *rcmv1.Shim
+		nodes          []corev1.Node
+		wantBatch      []string
+		wantInProgress int
+		wantFailed     []string
+	}{
+		{
+			name: "first batch is limited by maxUpdate",
+			shim: makeRollingShim(2),
+			nodes: []corev1.Node{
+				makeLabeledNode("node-c", ""),
+				makeLabeledNode("node-a", ""),
+				makeLabeledNode("node-b", ""),
+			},
+			wantBatch: []string{"node-a", "node-b"},
+		},
+		{
+			name: "next batch skips provisioned nodes",
+			shim: makeRollingShim(2),
+			nodes: []corev1.Node{
+				makeLabeledNode("node-a", ProvisioningStatusProvisioned),
+				makeLabeledNode("node-b", ProvisioningStatusProvisioned),
+				makeLabeledNode("node-c", ""),
+			},
+			wantBatch: []string{"node-c"},
+		},
+		{
+			name: "waits while current batch is pending",
+			shim: makeRollingShim(2),
+			nodes: []corev1.Node{
+				makeLabeledNode("node-a", ProvisioningStatusProvisioned),
+				makeLabeledNode("node-b", ProvisioningStatusPending),
+				makeLabeledNode("node-c", ""),
+			},
+			wantInProgress: 1,
+		},
+		{
+			name: "halts when a node failed",
+			shim: makeRollingShim(2),
+			nodes: []corev1.Node{
+				makeLabeledNode("node-b", ProvisioningStatusFailed),
+				makeLabeledNode("node-a", ProvisioningStatusFailed),
+				makeLabeledNode("node-c", ""),
+			},
+			wantFailed: []string{"node-a", "node-b"},
+		},
+		{
+			name: "all nodes provisioned",
+			shim: makeRollingShim(2),
+			nodes: []corev1.Node{
+				makeLabeledNode("node-a", ProvisioningStatusProvisioned),
+			},
+		},
+		{
+			name: "outdated provisioned and failed nodes are rolled out again",
+			shim: func() *rcmv1.Shim {
+				shim := makeRollingShim(2)
+				shim.Spec.FetchStrategy.AnonHTTP = &rcmv1.AnonHTTPSpec{Location: "https://example.com/shim-v2.tar.gz"}
+				return shim
+			}(),
+			nodes: []corev1.Node{
+				makeOutdatedNode("node-a", ProvisioningStatusProvisioned),
+				makeOutdatedNode("node-b", ProvisioningStatusFailed),
+				makeOutdatedNode("node-c", ProvisioningStatusProvisioned),
+			},
+			wantBatch: []string{"node-a", "node-b"},
+		},
+		{
+			name: "maxUpdate below one is treated as one",
+			shim: makeRollingShim(0),
+			nodes: []corev1.Node{
+				makeLabeledNode("node-a", ""),
+				makeLabeledNode("node-b", ""),
+			},
+			wantBatch: []string{"node-a"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			batch, inProgress, failed := nextRollingBatch(tt.shim, tt.nodes)
+			got := []string{}
+			for _, node := range batch {
+				got = append(got, node.Name)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.wantBatch, ",") {
+				t.Errorf("batch = %v, want %v", got, tt.wantBatch)
+			}
+			if inProgress != tt.wantInProgress {
+				t.Errorf("inProgress = %d, want %d", inProgress, tt.wantInProgress)
+			}
+			if strings.Join(failed, ",") != strings.Join(tt.wantFailed, ",") {
+				t.Errorf("failed = %v, want %v", failed, tt.wantFailed)
+			}
+		})
+	}
+}
+
+func TestShimOutdatedOnNode(t *testing.T) {
+	shim := makeShim(nil, &rcmv1.AnonHTTPSpec{Location: "https://example.com/shim-v1.tar.gz"})
+	node := makeNode("amd64")
+
+	artifact, err := resolveArtifactForNode(shim, node)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !shimOutdatedOnNode(shim, node) {
+		t.Errorf("expected node without recorded configuration to be outdated")
+	}
+
+	node.Labels = map[string]string{shim.Name: ProvisioningStatusProvisioned}
+	if shimOutdatedOnNode(shim, node) {
+		t.Errorf("expected provisioned node without recorded configuration to be up to date")
+	}
+
+	node.Annotations = map[string]string{configHashAnnotation(shim.Name): installConfigHash(shim, artifact)}
+	if shimOutdatedOnNode(shim, node) {
+		t.Errorf("expected node with current configuration to be up to date")
+	}
+
+	changedOptions := shim.DeepCopy()
+	changedOptions.Spec.ContainerdRuntimeOptions = map[string]string{"SystemdCgroup": "true"}
+	if !shimOutdatedOnNode(changedOptions, node) {
+		t.Errorf("expected node to be outdated after changing runtime options")
+	}
+
+	changedLocation := shim.DeepCopy()
+	changedLocation.Spec.FetchStrategy.AnonHTTP.Location = "https://example.com/shim-v2.tar.gz"
+	if !shimOutdatedOnNode(changedLocation, node) {
+		t.Errorf("expected node to be outdated after changing the artifact location")
+	}
+
+	unresolvable := makeShim(nil, nil)
+	if shimOutdatedOnNode(unresolvable, node) {
+		t.Errorf("expected node to not be outdated when no artifact can be resolved")
+	}
+}
+
+func TestSetNodeFailure(t *testing.T) {
+	node := makeNode("amd64")
+
+	if !setNodeFailure(node, "test-shim", "RestartFailed", "failed to restart containerd") {
+		t.Fatal("expected the node to be changed")
+	}
+	if setNodeFailure(node, "test-shim", "RestartFailed", "failed to restart containerd") {
+		t.Error("expected recording the same failure again to leave the node unchanged")
+	}
+	if got := node.Annotations[failureReasonAnnotation("test-shim")]; got != "RestartFailed" {
+		t.Errorf("reason = %q, want RestartFailed", got)
+	}
+
+	setNodeFailure(node, "test-shim", "Unknown", strings.Repeat("x", 2*maxFailureMessageLength))
+	if got := node.Annotations[failureMessageAnnotation("test-shim")]; len(got) != maxFailureMessageLength+len("...") {
+		t.Errorf("message length = %d, want %d", len(got), maxFailureMessageLength+len("..."))
+	}
+
+	clearNodeFailure(node, "test-shim")
+	if len(node.Annotations) != 0 {
+		t.Errorf("annotations = %v, want none", node.Annotations)
+	}
+}
+
+func TestStaleNodes(t *testing.T) {
+	labeled := []corev1.Node{
+		makeLabeledNode("node-a", ProvisioningStatusProvisioned),
+		makeLabeledNode("node-b", ProvisioningStatusProvisioned),
+		makeLabeledNode("node-c", ProvisioningStatusFailed),
+	}
+	selected := []corev1.Node{
+		makeLabeledNode("node-a", ProvisioningStatusProvisioned),
+		makeLabeledNode("node-d", ""),
+	}
+
+	got := []string{}
+	for _, node := range staleNodes(labeled, selected) {
+		got = append(got, node.Name)
+	}
+	if strings.Join(got, ",") != "node-b,node-c" {
+		t.Errorf("staleNodes() = %v, want [node-b node-c]", got)
+	}
+}
+
+func TestNextUninstallBatch(t *testing.T) {
+	recreate := &rcmv1.Shim{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-shim"},
+		Spec: rcmv1.ShimSpec{
+			RolloutStrategy: rcmv1.RolloutStrategy{Type: rcmv1.RolloutStrategyTypeRecreate},
+		},
+	}
+
+	tests := []struct {
+		name           string
+		shim           *rcmv1.Shim
+		nodes          []corev1.Node
+		wantBatch      []string
+		wantInProgress int
+	}{
+		{
+			name: "recreate uninstalls all nodes at once",
+			shim: recreate,
+			nodes: []corev1.Node{
+				makeLabeledNode("node-a", ProvisioningStatusProvisioned),
+				makeLabeledNode("node-b", ProvisioningStatusFailed),
+				makeLabeledNode("node-c", ProvisioningStatusProvisioned),
+			},
+			wantBatch: []string{"node-a", "node-b", "node-c"},
+		},
+		{
+			name: "recreate waits for running jobs",
+			shim: recreate,
+			nodes: []corev1.Node{
+				makeLabeledNode("node-a", ProvisioningStatusPending),
+				makeLabeledNode("node-b", UNINSTALL),
+				makeLabeledNode("node-c", ProvisioningStatusProvisioned),
+			},
+			wantBatch:      []string{"node-c"},
+			wantInProgress: 2,
+		},
+		{
+			name: "rolling batch is limited by maxUpdate",
+			shim: makeRollingShim(2),
+			nodes: []corev1.Node{
+				makeLabeledNode("node-c", ProvisioningStatusProvisioned),
+				makeLabeledNode("node-b", ProvisioningStatusFailed),
+				makeLabeledNode("node-a", ProvisioningStatusProvisioned),
+			},
+			wantBatch: []string{"node-a", "node-b"},
+		},
+		{
+			name: "rolling waits while the current batch is uninstalling",
+			shim: makeRollingShim(2),
+			nodes: []corev1.Node{
+				makeLabeledNode("node-a", UNINSTALL),
+				makeLabeledNode("node-b", ProvisioningStatusProvisioned),
+			},
+			wantInProgress: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			batch, inProgress := nextUninstallBatch(tt.shim, tt.nodes)
+			got := []string{}
+			for _, node := range batch {
+				got = append(got, node.Name)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.wantBatch, ",") {
+				t.Errorf("batch = %v, want %v", got, tt.wantBatch)
+			}
+			if inProgress != tt.wantInProgress {
+				t.Errorf("inProgress = %d, want %d", inProgress, tt.wantInProgress)
+			}
+		})
+	}
+}
+
+func TestHandleUninstallStaleShim(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name       string
+		nodeStatus string
+		failedJob  bool
+		wantLabel  string
+	}{
+		{"provisioned node is uninstalled", ProvisioningStatusProvisioned, false, UNINSTALL},
+		{"failed installation is uninstalled", ProvisioningStatusFailed, false, UNINSTALL},
+		{"failed uninstallation is not retried", ProvisioningStatusFailed, true, ProvisioningStatusFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shim := makeStatusShim(rcmv1.RolloutStrategyTypeRecreate)
+			node := makeLabeledNode("node-a", tt.nodeStatus)
+			objs := []client.Object{shim, &node}
+			if tt.failedJob {
+				objs = append(objs, makeFailedUninstallJob(shim, &node))
+			}
+			sr := newFakeReconciler(t, objs...)
+
+			if _, err := sr.handleUninstallStaleShim(ctx, shim, &corev1.NodeList{}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if err := sr.Get(ctx, types.NamespacedName{Name: node.Name}, &node); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if node.Labels[shim.Name] != tt.wantLabel {
+				t.Errorf("label = %q, want %q", node.Labels[shim.Name], tt.wantLabel)
+			}
+		})
+	}
+}
+
+func newFakeReconciler(t *testing.T, objs ...client.Object) *ShimReconciler {
+	t.Helper()
+	t.Setenv("CONTROLLER_NAMESPACE", "rcm-system")
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := rcmv1.AddToScheme(scheme); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(objs...).
+		WithStatusSubresource(&rcmv1.Shim{}).
+		WithIndex(&corev1.Pod{}, "spec.nodeName", func(obj client.Object) []string {
+			return []string{obj.(*corev1.Pod).Spec.NodeName}
+		}).
+		Build()
+	return &ShimReconciler{Client: c, Scheme: scheme, Recorder: events.NewFakeRecorder(10), APIReader: c}
+}
+
+func makeDeletedShim(annotations map[string]string) *rcmv1.Shim {
+	shim := makeStatusShim(rcmv1.RolloutStrategyTypeRecreate)
+	shim.Annotations = annotations
+	shim.Finalizers = []string{RCMOperatorFinalizer}
+	shim.DeletionTimestamp = ptr(metav1.Now())
+	return shim
+}
+
+func makeFailedUninstallJob(shim *rcmv1.Shim, node *corev1.Node) *batchv1.Job {
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: jobName(node, shim, UNINSTALL), Namespace: "rcm-system"},
+		Status: batchv1.JobStatus{
+			Conditions: []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionTrue}},
+		},
+	}
+}
+
+func TestReconcileDeletion(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name          string
+		annotations   map[string]string
+		nodeStatus    string
+		failedJob     bool
+		wantFinalizer bool
+		wantLabel     string
+		wantJob       bool
+	}{
+		{"no nodes left", nil, "", false, false, "", false},
+		{"provisioned node is uninstalled", nil, ProvisioningStatusProvisioned, false, true, UNINSTALL, true},
+		{"uninstallation in progress", nil, UNINSTALL, false, true, UNINSTALL, false},
+		{"failed installation is uninstalled", nil, ProvisioningStatusFailed, false, true, UNINSTALL, true},
+		{"failed uninstallation is not retried", nil, ProvisioningStatusFailed, true, true, ProvisioningStatusFailed, false},
+		{"forced deletion", map[string]string{ForceDeleteAnnotation: "true"}, ProvisioningStatusProvisioned, false, false, ProvisioningStatusProvisioned, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shim := makeDeletedShim(tt.annotations)
+			node := makeLabeledNode("node-a", tt.nodeStatus)
+			objs := []client.Object{shim, &node}
+			if tt.failedJob {
+				objs = append(objs, makeFailedUninstallJob(shim, &node))
+			}
+			sr := newFakeReconciler(t, objs...)
+
+			if _, err := sr.reconcileDeletion(ctx, shim); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			got := rcmv1.Shim{}
+			err := sr.Get(ctx, types.NamespacedName{Name: shim.Name}, &got)
+			if tt.wantFinalizer && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !tt.wantFinalizer && !apierrors.IsNotFound(err) {
+				t.Errorf("shim still exists with finalizers %v", got.Finalizers)
+			}
+
+			if err := sr.Get(ctx, types.NamespacedName{Name: node.Name}, &node); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if node.Labels[shim.Name] != tt.wantLabel {
+				t.Errorf("label = %q, want %q", node.Labels[shim.Name], tt.wantLabel)
+			}
+
+			job := batchv1.Job{}
+			err = sr.Get(ctx, types.NamespacedName{Name: jobName(&node, shim, UNINSTALL), Namespace: "rcm-system"}, &job)
+			if tt.wantJob {
+				if err != nil {
+					t.Fatalf("uninstall job not created: %v", err)
+				}
+				if owner := metav1.GetControllerOf(&job); owner == nil || owner.Name != shim.Name {
+					t.Errorf("controller of uninstall job = %v, want %s", owner, shim.Name)
+				}
+			} else if !tt.failedJob && !apierrors.IsNotFound(err) {
+				t.Errorf("unexpected uninstall job: %v", err)
+			}
+		})
+	}
+}
+
+func makeRuntimeClassPod(name, nodeName, runtimeClass string, phase corev1.PodPhase) *corev1.Pod {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec:       corev1.PodSpec{NodeName: nodeName},
+		Status:     corev1.PodStatus{Phase: phase},
+	}
+	if runtimeClass != "" {
+		pod.Spec.RuntimeClassName = ptr(runtimeClass)
+	}
+	return pod
+}
+
+func TestPodsUsingRuntimeClass(t *testing.T) {
+	shim := makeStatusShim(rcmv1.RolloutStrategyTypeRecreate)
+	shim.Spec.RuntimeClass.Name = "wasmtime-spin-v2"
+	pods := []corev1.Pod{
+		*makeRuntimeClassPod("running", "node-a", "wasmtime-spin-v2", corev1.PodRunning),
+		*makeRuntimeClassPod("pending", "node-a", "wasmtime-spin-v2", corev1.PodPending),
+		*makeRuntimeClassPod("succeeded", "node-a", "wasmtime-spin-v2", corev1.PodSucceeded),
+		*makeRuntimeClassPod("failed", "node-a", "wasmtime-spin-v2", corev1.PodFailed),
+		*makeRuntimeClassPod("other-runtime-class", "node-a", "gvisor", corev1.PodRunning),
+		*makeRuntimeClassPod("default-runtime-class", "node-a", "", corev1.PodRunning),
+	}
+
+	got := podsUsingRuntimeClass(shim, pods)
+	names := []string{}
+	for _, pod := range got {
+		names = append(names, pod.Name)
+	}
+	if strings.Join(names, ",") != "running,pending" {
+		t.Errorf("pods = %v, want [running pending]", names)
+	}
+}
+
+func TestUninstallBlocked(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name        string
+		policy      rcmv1.UninstallPolicy
+		pod         *corev1.Pod
+		wantBlocked bool
+		wantPod     bool
+	}{
+		{"no pods", rcmv1.UninstallPolicyWait, nil, false, false},
+		{"pod on another node", rcmv1.UninstallPolicyWait, makeRuntimeClassPod("spin-app", "node-b", "wasmtime-spin-v2", corev1.PodRunning), false, true},
+		{"default policy waits", "", makeRuntimeClassPod("spin-app", "node-a", "wasmtime-spin-v2", corev1.PodRunning), true, true},
+		{"wait", rcmv1.UninstallPolicyWait, makeRuntimeClassPod("spin-app", "node-a", "wasmtime-spin-v2", corev1.PodRunning), true, true},
+		{"evict", rcmv1.UninstallPolicyEvict, makeRuntimeClassPod("spin-app", "node-a", "wasmtime-spin-v2", corev1.PodRunning), true, false},
+		{"force", rcmv1.UninstallPolicyForce, makeRuntimeClassPod("spin-app", "node-a", "wasmtime-spin-v2", corev1.PodRunning), false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shim := makeStatusShim(rcmv1.RolloutStrategyTypeRecreate)
+			shim.Spec.RuntimeClass.Name = "wasmtime-spin-v2"
+			shim.Spec.UninstallPolicy = tt.policy
+			node := makeLabeledNode("node-a", ProvisioningStatusProvisioned)
+			objs := []client.Object{shim, &node}
+			if tt.pod != nil {
+				objs = append(objs, tt.pod)
+			}
+			sr := newFakeReconciler(t, objs...)
+
+			blocked, err := sr.uninstallBlocked(ctx, shim, &node)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if blocked != tt.wantBlocked {
+				t.Errorf("blocked = %t, want %t", blocked, tt.wantBlocked)
+			}
+
+			if tt.pod == nil {
+				return
+			}
+			err = sr.Get(ctx, client.ObjectKeyFromObject(tt.pod), &corev1.Pod{})
+			if tt.wantPod && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if !tt.wantPod && !apierrors.IsNotFound(err) {
+				t.Errorf("pod has not been evicted")
+			}
+		})
+	}
+}
+
+func TestRuntimeClassNodeSelector(t *testing.T) {
+	tests := []struct {
+		name          string
+		nodeSelector  map[string]string
+		nodeSelection rcmv1.NodeSelection
+		want          map[string]string
+	}{
+		{"default without node selector", nil, "", map[string]string{}},
+		{"default", map[string]string{"spin": "true"}, "", map[string]string{"spin": "true"}},
+		{"node selector", map[string]string{"spin": "true"}, rcmv1.NodeSelectionNodeSelector, map[string]string{"spin": "true"}},
+		{"provisioned without node selector", nil, rcmv1.NodeSelectionProvisioned, map[string]string{"test-shim": "provisioned"}},
+		{"provisioned", map[string]string{"spin": "true"}, rcmv1.NodeSelectionProvisioned, map[string]string{"spin": "true", "test-shim": "provisioned"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shim := makeShim(nil, nil)
+			shim.Spec.NodeSelector = tt.nodeSelector
+			shim.Spec.RuntimeClass.NodeSelection = tt.nodeSelection
+
+			got := runtimeClassNodeSelector(shim)
+			if !maps.Equal(got, tt.want) {
+				t.Errorf("node selector = %v, want %v", got, tt.want)
+			}
+			if len(tt.nodeSelector) != len(shim.Spec.NodeSelector) {
+				t.Errorf("node selector of the shim changed to %v", shim.Spec.NodeSelector)
+			}
+		})
+	}
+}
+
+func makeRuntimeClassShim() *rcmv1.Shim {
+	shim := makeStatusShim(rcmv1.RolloutStrategyTypeRecreate)
+	shim.UID = "shim-uid"
+	shim.Spec.NodeSelector = map[string]string{"spin": "true"}
+	shim.Spec.RuntimeClass = rcmv1.RuntimeClassSpec{
+		Name:        "wasmtime-spin-v2",
+		Handler:     "spin",
+		Overhead:    &nodev1.Overhead{PodFixed: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("64Mi")}},
+		Tolerations: []corev1.Toleration{{Key: "wasm", Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule}},
+		Labels:      map[string]string{"team": "wasm"},
+		Annotations: map[string]string{"example.com/owner": "wasm-team"},
+	}
+	return shim
+}
+
+func TestHandleDeployRuntimeClass(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		drift   func(rc *nodev1.RuntimeClass)
+		owned   bool
+		wantErr bool
+	}{
+		{"creates the runtime class", nil, false, false},
+		{"corrects manual changes", func(rc *nodev1.RuntimeClass) {
+			rc.Scheduling = &nodev1.Scheduling{NodeSelector: map[string]string{"spin": "false"}}
+			rc.Overhead = nil
+		}, true, false},
+		{"recreates the runtime class when the handler changed", func(rc *nodev1.RuntimeClass) {
+			rc.Handler = "spin-old"
+		}, true, false},
+		{"leaves unmanaged runtime classes with another handler alone", func(rc *nodev1.RuntimeClass) {
+			rc.Handler = "spin-old"
+		}, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shim := makeRuntimeClassShim()
+			objs := []client.Object{shim}
+			if tt.drift != nil {
+				existing := &nodev1.RuntimeClass{
+					ObjectMeta: metav1.ObjectMeta{Name: shim.Spec.RuntimeClass.Name},
+					Handler:    shim.Spec.RuntimeClass.Handler,
+				}
+				tt.drift(existing)
+				if tt.owned {
+					existing.OwnerReferences = []metav1.OwnerReference{{
+						APIVersion: rcmv1.GroupVersion.String(), Kind: "Shim", Name: shim.Name, UID: shim.UID, Controller: ptr(true),
+					}}
+				}
+				objs = append(objs, existing)
+			}
+			sr := newFakeReconciler(t, objs...)
+
+			_, err := sr.handleDeployRuntimeClass(ctx, shim)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			rc := nodev1.RuntimeClass{}
+			if err := sr.Get(ctx, types.NamespacedName{Name: shim.Spec.RuntimeClass.Name}, &rc); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if rc.Handler != "spin" {
+				t.Errorf("handler = %s, want spin", rc.Handler)
+			}
+			if !metav1.IsControlledBy(&rc, shim) {
+				t.Errorf("runtime class is not controlled by the shim")
+			}
+			if want := map[string]string{"team": "wasm", "wasmtime-spin-v2": "true"}; !maps.Equal(rc.Labels, want) {
+				t.Errorf("labels = %v, want %v", rc.Labels, want)
+			}
+			if rc.Annotations["example.com/owner"] != "wasm-team" {
+				t.Errorf("annotations = %v, want example.com/owner=wasm-team", rc.Annotations)
+			}
+			if rc.Overhead == nil || !rc.Overhead.PodFixed.Memory().Equal(resource.MustParse("64Mi")) {
+				t.Errorf("overhead = %v, want 64Mi memory", rc.Overhead)
+			}
+			if rc.Scheduling == nil || rc.Scheduling.NodeSelector["spin"] != "true" || len(rc.Scheduling.Tolerations) != 1 {
+				t.Errorf("scheduling = %v, want node selector spin=true and the wasm toleration", rc.Scheduling)
+			}
+		})
+	}
+}
+
+func TestDeleteStaleRuntimeClasses(t *testing.T) {
+	ctx := context.Background()
+	shim := makeRuntimeClassShim()
+	ownedBy := func(shim *rcmv1.Shim) []metav1.OwnerReference {
+		return []metav1.OwnerReference{{
+			APIVersion: rcmv1.GroupVersion.String(), Kind: "Shim", Name: shim.Name, UID: shim.UID, Controller: ptr(true),
+		}}
+	}
+	objs := []client.Object{
+		shim,
+		&nodev1.RuntimeClass{
+			ObjectMeta: metav1.ObjectMeta{Name: "wasmtime-spin-v1", OwnerReferences: ownedBy(shim)},
+			Handler:    "spin",
+		},
+		&nodev1.RuntimeClass{
+			ObjectMeta: metav1.ObjectMeta{Name: "unmanaged"},
+			Handler:    "spin",
+		},
+	}
+	sr := newFakeReconciler(t, objs...)
+
+	if _, err := sr.handleDeployRuntimeClass(ctx, shim); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	runtimeClasses := nodev1.RuntimeClassList{}
+	if err := sr.List(ctx, &runtimeClasses); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got := []string{}
+	for _, rc := range runtimeClasses.Items {
+		got = append(got, rc.Name)
+	}
+	if want := []string{"unmanaged", shim.Spec.RuntimeClass.Name}; strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("runtime classes = %v, want %v", got, want)
+	}
+}
+
+func TestBackfillConfigHash(t *testing.T) {
+	ctx := context.Background()
+	shim := makeStatusShim(rcmv1.RolloutStrategyTypeRecreate)
+
+	legacy := makeLabeledNode("legacy", ProvisioningStatusProvisioned)
+	legacy.Status.NodeInfo = corev1.NodeSystemInfo{OperatingSystem: "linux", Architecture: "amd64"}
+	pending := makeLabeledNode("pending", ProvisioningStatusPending)
+	pending.Status.NodeInfo = legacy.Status.NodeInfo
+	outdated := makeUpToDateNode(t, shim, "outdated", ProvisioningStatusProvisioned)
+	outdated.Annotations[configHashAnnotation(shim.Name)] = "previous"
+
+	sr := newFakeReconciler(t, shim, &legacy, &pending, &outdated)
+	sr.backfillConfigHash(ctx, shim, &corev1.NodeList{Items: []corev1.Node{legacy, pending, outdated}})
+
+	artifact, err := resolveArtifactForNode(shim, &legacy)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := map[string]string{
+		"legacy":   installConfigHash(shim, artifact),
+		"pending":  "",
+		"outdated": "previous",
+	}
+	for name, hash := range want {
+		node := corev1.Node{}
+		if err := sr.Get(ctx, types.NamespacedName{Name: name}, &node); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := node.Annotations[configHashAnnotation(shim.Name)]; got != hash {
+			t.Errorf("config hash of %s = %q, want %q", name, got, hash)
+		}
+	}
+
+	// The configuration is only recorded once
+	nodes := corev1.NodeList{}
+	if err := sr.List(ctx, &nodes); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resourceVersions := map[string]string{}
+	for _, node := range nodes.Items {
+		resourceVersions[node.Name] = node.ResourceVersion
+	}
+	sr.backfillConfigHash(ctx, shim, &nodes)
+	for _, node := range nodes.Items {
+		got := corev1.Node{}
+		if err := sr.Get(ctx, types.NamespacedName{Name: node.Name}, &got); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got.ResourceVersion != resourceVersions[node.Name] {
+			t.Errorf("node %s was changed again", node.Name)
+		}
+	}
+}
@@ -0,0 +1,236 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	rcmv1 "github.com/spinframework/runtime-class-manager/api/v1alpha1"
+)
+
+// Reasons used for the conditions reported in the Shim status.
+const (
+	ReasonAllNodesProvisioned = "AllNodesProvisioned"
+	ReasonNoMatchingNodes     = "NoMatchingNodes"
+	ReasonNodesNotReady       = "NodesNotReady"
+	ReasonRolloutInProgress   = "RolloutInProgress"
+	ReasonRolloutComplete     = "RolloutComplete"
+	ReasonRolloutHalted       = "RolloutHalted"
+	ReasonUninstalling        = "Uninstalling"
+	ReasonInstallFailed       = "InstallFailed"
+	ReasonUninstallFailed     = "UninstallFailed"
+	ReasonNoFailures          = "NoFailures"
+	ReasonPodsRunning         = "PodsRunning"
+	ReasonNotBlocked          = "NotBlocked"
+)
+
+// maxNodesInConditionMessage limits the number of node names listed in a
+// condition message, to keep the status readable on large clusters.
+const maxNodesInConditionMessage = 5
+
+// setShimStatus computes the status of a Shim from the state of the nodes it
+// targets. The transition time of nodes and conditions is only updated when
+// their phase or status changes.
+func setShimStatus(shim *rcmv1.Shim, nodes []corev1.Node, now metav1.Time) {
+	previous := map[string]rcmv1.NodeStatus{}
+	for _, nodeStatus := range shim.Status.NodeStatuses {
+		previous[nodeStatus.Name] = nodeStatus
+	}
+
+	sorted := append([]corev1.Node{}, nodes...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Name < sorted[j].Name
+	})
+
+	nodeStatuses := make([]rcmv1.NodeStatus, 0, len(sorted))
+	provisioned, upToDate := 0, 0
+	var failed []string
+	for i := range sorted {
+		node := &sorted[i]
+		nodeStatus := rcmv1.NodeStatus{
+			Name:               node.Name,
+			Phase:              nodePhase(shim, node),
+			ArtifactDigest:     node.Annotations[artifactDigestAnnotation(shim.Name)],
+			LastTransitionTime: now,
+			Reason:             node.Annotations[failureReasonAnnotation(shim.Name)],
+			Message:            node.Annotations[failureMessageAnnotation(shim.Name)],
+		}
+		if prev, ok := previous[node.Name]; ok && prev.Phase == nodeStatus.Phase {
+			nodeStatus.LastTransitionTime = prev.LastTransitionTime
+		}
+		if status, ok := node.Labels[shim.Name]; ok {
+			operation := INSTALL
+			if status == UNINSTALL || (!shim.DeletionTimestamp.IsZero() && status == ProvisioningStatusFailed) {
+				operation = UNINSTALL
+			}
+			nodeStatus.LastJobName = jobName(node, shim, operation)
+		}
+
+		switch nodeStatus.Phase {
+		case rcmv1.NodePhaseProvisioned:
+			provisioned++
+			if !shimOutdatedOnNode(shim, node) {
+				upToDate++
+			}
+		case rcmv1.NodePhaseFailed:
+			failed = append(failed, node.Name)
+		}
+
+		nodeStatuses = append(nodeStatuses, nodeStatus)
+	}
+
+	shim.Status.NodeStatuses = nodeStatuses
+	shim.Status.NodeCount = len(nodeStatuses)
+	shim.Status.NodeReadyCount = provisioned
+
+	if !shim.DeletionTimestamp.IsZero() {
+		setUninstallConditions(shim, failed)
+		return
+	}
+	setShimConditions(shim, upToDate, failed)
+}
+
+// setUninstallConditions sets the Ready, Progressing and Degraded conditions
+// of a Shim that is being deleted. The nodes of its status are the nodes it
+// still has to be uninstalled from.
+func setUninstallConditions(shim *rcmv1.Shim, failed []string) {
+	message := fmt.Sprintf("%d nodes left to uninstall", shim.Status.NodeCount)
+
+	degraded := metav1.Condition{
+		Type:    rcmv1.ConditionTypeDegraded,
+		Status:  metav1.ConditionFalse,
+		Reason:  ReasonNoFailures,
+		Message: "no uninstallation failures",
+	}
+	if len(failed) > 0 {
+		degraded.Status = metav1.ConditionTrue
+		degraded.Reason = ReasonUninstallFailed
+		degraded.Message = fmt.Sprintf("uninstallation failed on %s; delete the failed Jobs to retry, or annotate the Shim with %s=true to delete it without uninstalling", summarizeNodes(failed), ForceDeleteAnnotation)
+	}
+
+	for _, condition := range []metav1.Condition{
+		{Type: rcmv1.ConditionTypeReady, Status: metav1.ConditionFalse, Reason: ReasonUninstalling, Message: message},
+		{Type: rcmv1.ConditionTypeProgressing, Status: metav1.ConditionTrue, Reason: ReasonUninstalling, Message: message},
+		degraded,
+	} {
+		condition.ObservedGeneration = shim.Generation
+		meta.SetStatusCondition(&shim.Status.Conditions, condition)
+	}
+}
+
+// setShimConditions sets the Ready, Progressing and Degraded conditions.
+func setShimConditions(shim *rcmv1.Shim, upToDate int, failed []string) {
+	total := shim.Status.NodeCount
+	provisionedMessage := fmt.Sprintf("%d of %d nodes provisioned", upToDate, total)
+
+	ready := metav1.Condition{
+		Type:    rcmv1.ConditionTypeReady,
+		Status:  metav1.ConditionFalse,
+		Reason:  ReasonNodesNotReady,
+		Message: provisionedMessage,
+	}
+	switch {
+	case total == 0:
+		ready.Status = metav1.ConditionTrue
+		ready.Reason = ReasonNoMatchingNodes
+		ready.Message = "no nodes match the node selector"
+	case upToDate == total:
+		ready.Status = metav1.ConditionTrue
+		ready.Reason = ReasonAllNodesProvisioned
+	}
+
+	progressing := metav1.Condition{
+		Type:    rcmv1.ConditionTypeProgressing,
+		Status:  metav1.ConditionFalse,
+		Reason:  ReasonRolloutComplete,
+		Message: provisionedMessage,
+	}
+	switch {
+	case len(failed) > 0 && shim.Spec.RolloutStrategy.Type == rcmv1.RolloutStrategyTypeRolling:
+		progressing.Reason = ReasonRolloutHalted
+		progressing.Message = "rollout halted after installation failed on " + summarizeNodes(failed)
+	case upToDate < total:
+		progressing.Status = metav1.ConditionTrue
+		progressing.Reason = ReasonRolloutInProgress
+	}
+
+	degraded := metav1.Condition{
+		Type:    rcmv1.ConditionTypeDegraded,
+		Status:  metav1.ConditionFalse,
+		Reason:  ReasonNoFailures,
+		Message: "no installation failures",
+	}
+	if len(failed) > 0 {
+		degraded.Status = metav1.ConditionTrue
+		degraded.Reason = ReasonInstallFailed
+		degraded.Message = "installation failed on " + summarizeNodes(failed)
+	}
+
+	for _, condition := range []metav1.Condition{ready, progressing, degraded} {
+		condition.ObservedGeneration = shim.Generation
+		meta.SetStatusCondition(&shim.Status.Conditions, condition)
+	}
+}
+
+// setBlockedCondition sets the Blocked condition from the nodes on which the
+// uninstallation of the shim waits for pods using its RuntimeClass. It returns
+// whether the condition changed.
+func setBlockedCondition(shim *rcmv1.Shim, blocked []string) bool {
+	blockedCondition := metav1.Condition{
+		Type:               rcmv1.ConditionTypeBlocked,
+		Status:             metav1.ConditionFalse,
+		Reason:             ReasonNotBlocked,
+		Message:            "no pods block the uninstallation",
+		ObservedGeneration: shim.Generation,
+	}
+	if len(blocked) > 0 {
+		sorted := append([]string{}, blocked...)
+		sort.Strings(sorted)
+		blockedCondition.Status = metav1.ConditionTrue
+		blockedCondition.Reason = ReasonPodsRunning
+		blockedCondition.Message = fmt.Sprintf("uninstallation waits for pods using RuntimeClass %s on %s", shim.Spec.RuntimeClass.Name, summarizeNodes(sorted))
+	}
+	return meta.SetStatusCondition(&shim.Status.Conditions, blockedCondition)
+}
+
+// nodePhase derives the installation phase of a shim from the node's status label.
+func nodePhase(shim *rcmv1.Shim, node *corev1.Node) rcmv1.NodePhase {
+	switch node.Labels[shim.Name] {
+	case ProvisioningStatusProvisioned:
+		return rcmv1.NodePhaseProvisioned
+	case ProvisioningStatusFailed:
+		return rcmv1.NodePhaseFailed
+	case UNINSTALL:
+		return rcmv1.NodePhaseUninstalling
+	default:
+		return rcmv1.NodePhasePending
+	}
+}
+
+// summarizeNodes formats a list of node names for a condition message.
+func summarizeNodes(names []string) string {
+	if len(names) <= maxNodesInConditionMessage {
+		return "nodes: " + strings.Join(names, ", ")
+	}
+	return fmt.Sprintf("nodes: %s and %d more", strings.Join(names[:maxNodesInConditionMessage], ", "), len(names)-maxNodesInConditionMessage)
+}
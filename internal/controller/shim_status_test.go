@@ -0,0 +1,257 @@
+package controller //nolint:testpackage // whitebox test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	rcmv1 "github.com/spinframework/runtime-class-manager/api/v1alpha1"
+)
+
+// makeUpToDateNode returns a node on which the current configuration of the shim
+// has been installed with the given status.
+func makeUpToDateNode(t *testing.T, shim *rcmv1.Shim, name string, status string) corev1.Node {
+	t.Helper()
+	node := makeLabeledNode(name, status)
+	node.Status.NodeInfo = corev1.NodeSystemInfo{OperatingSystem: "linux", Architecture: "amd64"}
+	artifact, err := resolveArtifactForNode(shim, &node)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	node.Annotations = map[string]string{
+		configHashAnnotation(shim.Name):     installConfigHash(shim, artifact),
+		artifactDigestAnnotation(shim.Name): artifact.sha256,
+	}
+	return node
+}
+
+func makeStatusShim(strategy rcmv1.RolloutStrategyType) *rcmv1.Shim {
+	shim := makeShim([]rcmv1.PlatformArtifact{
+		{OS: "linux", Arch: "amd64", Location: "https://example.com/shim-amd64.tar.gz", SHA256: "abc123"},
+	}, nil)
+	shim.Generation = 3
+	shim.Spec.RolloutStrategy.Type = strategy
+	return shim
+}
+
+func assertCondition(t *testing.T, shim *rcmv1.Shim, conditionType string, status metav1.ConditionStatus, reason string) {
+	t.Helper()
+	condition := meta.FindStatusCondition(shim.Status.Conditions, conditionType)
+	if condition == nil {
+		t.Fatalf("condition %s not set", conditionType)
+	}
+	if condition.Status != status || condition.Reason != reason {
+		t.Errorf("condition %s = %s/%s, want %s/%s", conditionType, condition.Status, condition.Reason, status, reason)
+	}
+	if condition.ObservedGeneration != shim.Generation {
+		t.Errorf("condition %s observedGeneration = %d, want %d", conditionType, condition.ObservedGeneration, shim.Generation)
+	}
+}
+
+func TestSetShimStatus(t *testing.T) {
+	now := metav1.NewTime(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	t.Run("all nodes provisioned", func(t *testing.T) {
+		shim := makeStatusShim(rcmv1.RolloutStrategyTypeRecreate)
+		nodes := []corev1.Node{
+			makeUpToDateNode(t, shim, "node-b", ProvisioningStatusProvisioned),
+			makeUpToDateNode(t, shim, "node-a", ProvisioningStatusProvisioned),
+		}
+
+		setShimStatus(shim, nodes, now)
+
+		if shim.Status.NodeCount != 2 || shim.Status.NodeReadyCount != 2 {
+			t.Errorf("nodes = %d, nodesReady = %d, want 2, 2", shim.Status.NodeCount, shim.Status.NodeReadyCount)
+		}
+		assertCondition(t, shim, rcmv1.ConditionTypeReady, metav1.ConditionTrue, ReasonAllNodesProvisioned)
+		assertCondition(t, shim, rcmv1.ConditionTypeProgressing, metav1.ConditionFalse, ReasonRolloutComplete)
+		assertCondition(t, shim, rcmv1.ConditionTypeDegraded, metav1.ConditionFalse, ReasonNoFailures)
+
+		want := rcmv1.NodeStatus{
+			Name:               "node-a",
+			Phase:              rcmv1.NodePhaseProvisioned,
+			ArtifactDigest:     "abc123",
+			LastJobName:        "node-a-test-shim-install",
+			LastTransitionTime: now,
+		}
+		if shim.Status.NodeStatuses[0] != want {
+			t.Errorf("nodeStatuses[0] = %+v, want %+v", shim.Status.NodeStatuses[0], want)
+		}
+	})
+
+	t.Run("rollout in progress", func(t *testing.T) {
+		shim := makeStatusShim(rcmv1.RolloutStrategyTypeRecreate)
+		nodes := []corev1.Node{
+			makeUpToDateNode(t, shim, "node-a", ProvisioningStatusProvisioned),
+			makeUpToDateNode(t, shim, "node-b", ProvisioningStatusPending),
+			makeLabeledNode("node-c", ""),
+		}
+
+		setShimStatus(shim, nodes, now)
+
+		assertCondition(t, shim, rcmv1.ConditionTypeReady, metav1.ConditionFalse, ReasonNodesNotReady)
+		assertCondition(t, shim, rcmv1.ConditionTypeProgressing, metav1.ConditionTrue, ReasonRolloutInProgress)
+		if shim.Status.NodeStatuses[2].Phase != rcmv1.NodePhasePending || shim.Status.NodeStatuses[2].LastJobName != "" {
+			t.Errorf("unexpected status for node without label: %+v", shim.Status.NodeStatuses[2])
+		}
+	})
+
+	t.Run("outdated nodes are not ready", func(t *testing.T) {
+		shim := makeStatusShim(rcmv1.RolloutStrategyTypeRecreate)
+		nodes := []corev1.Node{
+			makeUpToDateNode(t, shim, "node-a", ProvisioningStatusProvisioned),
+		}
+		shim.Spec.ContainerdRuntimeOptions = map[string]string{"SystemdCgroup": "true"}
+
+		setShimStatus(shim, nodes, now)
+
+		if shim.Status.NodeReadyCount != 1 {
+			t.Errorf("nodesReady = %d, want 1", shim.Status.NodeReadyCount)
+		}
+		assertCondition(t, shim, rcmv1.ConditionTypeReady, metav1.ConditionFalse, ReasonNodesNotReady)
+		assertCondition(t, shim, rcmv1.ConditionTypeProgressing, metav1.ConditionTrue, ReasonRolloutInProgress)
+	})
+
+	t.Run("failed rolling rollout is halted", func(t *testing.T) {
+		shim := makeStatusShim(rcmv1.RolloutStrategyTypeRolling)
+		nodes := []corev1.Node{
+			makeUpToDateNode(t, shim, "node-a", ProvisioningStatusFailed),
+			makeLabeledNode("node-b", ""),
+		}
+
+		setShimStatus(shim, nodes, now)
+
+		assertCondition(t, shim, rcmv1.ConditionTypeProgressing, metav1.ConditionFalse, ReasonRolloutHalted)
+		assertCondition(t, shim, rcmv1.ConditionTypeDegraded, metav1.ConditionTrue, ReasonInstallFailed)
+	})
+
+	t.Run("failure reason is reported", func(t *testing.T) {
+		shim := makeStatusShim(rcmv1.RolloutStrategyTypeRecreate)
+		node := makeUpToDateNode(t, shim, "node-a", ProvisioningStatusFailed)
+		setNodeFailure(&node, shim.Name, "ChecksumMismatch", "container downloader exited with code 3: checksum mismatch")
+
+		setShimStatus(shim, []corev1.Node{node}, now)
+
+		nodeStatus := shim.Status.NodeStatuses[0]
+		if nodeStatus.Reason != "ChecksumMismatch" || nodeStatus.Message != "container downloader exited with code 3: checksum mismatch" {
+			t.Errorf("reason = %q, message = %q", nodeStatus.Reason, nodeStatus.Message)
+		}
+	})
+
+	t.Run("no matching nodes", func(t *testing.T) {
+		shim := makeStatusShim(rcmv1.RolloutStrategyTypeRecreate)
+
+		setShimStatus(shim, nil, now)
+
+		assertCondition(t, shim, rcmv1.ConditionTypeReady, metav1.ConditionTrue, ReasonNoMatchingNodes)
+	})
+
+	t.Run("uninstalling", func(t *testing.T) {
+		shim := makeStatusShim(rcmv1.RolloutStrategyTypeRecreate)
+		shim.DeletionTimestamp = &now
+		nodes := []corev1.Node{
+			makeUpToDateNode(t, shim, "node-a", UNINSTALL),
+		}
+
+		setShimStatus(shim, nodes, now)
+
+		assertCondition(t, shim, rcmv1.ConditionTypeReady, metav1.ConditionFalse, ReasonUninstalling)
+		assertCondition(t, shim, rcmv1.ConditionTypeProgressing, metav1.ConditionTrue, ReasonUninstalling)
+		assertCondition(t, shim, rcmv1.ConditionTypeDegraded, metav1.ConditionFalse, ReasonNoFailures)
+		if shim.Status.NodeStatuses[0].Phase != rcmv1.NodePhaseUninstalling {
+			t.Errorf("phase = %s, want %s", shim.Status.NodeStatuses[0].Phase, rcmv1.NodePhaseUninstalling)
+		}
+		if shim.Status.NodeStatuses[0].LastJobName != "node-a-test-shim-uninstall" {
+			t.Errorf("lastJobName = %s, want node-a-test-shim-uninstall", shim.Status.NodeStatuses[0].LastJobName)
+		}
+	})
+
+	t.Run("uninstallation failed", func(t *testing.T) {
+		shim := makeStatusShim(rcmv1.RolloutStrategyTypeRecreate)
+		shim.DeletionTimestamp = &now
+		nodes := []corev1.Node{
+			makeUpToDateNode(t, shim, "node-a", ProvisioningStatusFailed),
+			makeUpToDateNode(t, shim, "node-b", UNINSTALL),
+		}
+
+		setShimStatus(shim, nodes, now)
+
+		assertCondition(t, shim, rcmv1.ConditionTypeProgressing, metav1.ConditionTrue, ReasonUninstalling)
+		assertCondition(t, shim, rcmv1.ConditionTypeDegraded, metav1.ConditionTrue, ReasonUninstallFailed)
+		if msg := meta.FindStatusCondition(shim.Status.Conditions, rcmv1.ConditionTypeDegraded).Message; !strings.Contains(msg, ForceDeleteAnnotation+"=true") {
+			t.Errorf("degraded message = %q, want a hint at %s", msg, ForceDeleteAnnotation)
+		}
+		if msg := meta.FindStatusCondition(shim.Status.Conditions, rcmv1.ConditionTypeProgressing).Message; msg != "2 nodes left to uninstall" {
+			t.Errorf("progressing message = %q, want %q", msg, "2 nodes left to uninstall")
+		}
+		if shim.Status.NodeStatuses[0].LastJobName != "node-a-test-shim-uninstall" {
+			t.Errorf("lastJobName = %s, want node-a-test-shim-uninstall", shim.Status.NodeStatuses[0].LastJobName)
+		}
+	})
+
+	t.Run("transition time only changes with the phase", func(t *testing.T) {
+		shim := makeStatusShim(rcmv1.RolloutStrategyTypeRecreate)
+		nodes := []corev1.Node{
+			makeUpToDateNode(t, shim, "node-a", ProvisioningStatusPending),
+			makeUpToDateNode(t, shim, "node-b", ProvisioningStatusPending),
+		}
+		setShimStatus(shim, nodes, now)
+
+		later := metav1.NewTime(now.Add(time.Minute))
+		nodes[1] = makeUpToDateNode(t, shim, "node-b", ProvisioningStatusProvisioned)
+		setShimStatus(shim, nodes, later)
+
+		if !shim.Status.NodeStatuses[0].LastTransitionTime.Equal(&now) {
+			t.Errorf("node-a lastTransitionTime = %s, want %s", shim.Status.NodeStatuses[0].LastTransitionTime, now)
+		}
+		if !shim.Status.NodeStatuses[1].LastTransitionTime.Equal(&later) {
+			t.Errorf("node-b lastTransitionTime = %s, want %s", shim.Status.NodeStatuses[1].LastTransitionTime, later)
+		}
+	})
+}
+
+func TestSetBlockedCondition(t *testing.T) {
+	shim := makeStatusShim(rcmv1.RolloutStrategyTypeRecreate)
+	shim.Spec.RuntimeClass.Name = "wasmtime-spin-v2"
+
+	if !setBlockedCondition(shim, nil) {
+		t.Errorf("condition not changed when set initially")
+	}
+	assertCondition(t, shim, rcmv1.ConditionTypeBlocked, metav1.ConditionFalse, ReasonNotBlocked)
+
+	if !setBlockedCondition(shim, []string{"node-b", "node-a"}) {
+		t.Errorf("condition not changed when nodes are blocked")
+	}
+	assertCondition(t, shim, rcmv1.ConditionTypeBlocked, metav1.ConditionTrue, ReasonPodsRunning)
+	want := "uninstallation waits for pods using RuntimeClass wasmtime-spin-v2 on nodes: node-a, node-b"
+	if msg := meta.FindStatusCondition(shim.Status.Conditions, rcmv1.ConditionTypeBlocked).Message; msg != want {
+		t.Errorf("message = %q, want %q", msg, want)
+	}
+
+	if setBlockedCondition(shim, []string{"node-a", "node-b"}) {
+		t.Errorf("condition changed although the blocked nodes are the same")
+	}
+}
+
+func TestSummarizeNodes(t *testing.T) {
+	tests := []struct {
+		names []string
+		want  string
+	}{
+		{[]string{"a"}, "nodes: a"},
+		{[]string{"a", "b", "c", "d", "e"}, "nodes: a, b, c, d, e"},
+		{[]string{"a", "b", "c", "d", "e", "f", "g"}, "nodes: a, b, c, d, e and 2 more"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := summarizeNodes(tt.names); got != tt.want {
+				t.Errorf("summarizeNodes() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
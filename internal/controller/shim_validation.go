@@ -0,0 +1,145 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+
+	"github.com/pelletier/go-toml/v2"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	rcmv1 "github.com/spinframework/runtime-class-manager/api/v1alpha1"
+	"github.com/spinframework/runtime-class-manager/internal/oci"
+)
+
+// ValidateShim checks a Shim for configuration errors that would otherwise only
+// surface once it is reconciled, or would lead to broken installations on nodes.
+func ValidateShim(shim *rcmv1.Shim) field.ErrorList {
+	specPath := field.NewPath("spec")
+
+	var errs field.ErrorList
+	errs = append(errs, validateFetchStrategy(&shim.Spec.FetchStrategy, specPath.Child("fetchStrategy"))...)
+	errs = append(errs, validateRuntimeClass(&shim.Spec.RuntimeClass, specPath.Child("runtimeClass"))...)
+	errs = append(errs, validateRolloutStrategy(&shim.Spec.RolloutStrategy, specPath.Child("rolloutStrategy"))...)
+	errs = append(errs, validateRuntimeOptions(shim.Spec.ContainerdRuntimeOptions, specPath.Child("containerdRuntimeOptions"))...)
+	return errs
+}
+
+// ValidateRuntimeClassUnique checks that no other Shim claims the RuntimeClass
+// name or handler of the given Shim.
+func ValidateRuntimeClassUnique(shim *rcmv1.Shim, others []rcmv1.Shim) field.ErrorList {
+	path := field.NewPath("spec", "runtimeClass")
+
+	var errs field.ErrorList
+	for _, other := range others {
+		if other.Name == shim.Name {
+			continue
+		}
+		if other.Spec.RuntimeClass.Name == shim.Spec.RuntimeClass.Name {
+			errs = append(errs, field.Duplicate(path.Child("name"), fmt.Sprintf("%s (claimed by Shim %s)", shim.Spec.RuntimeClass.Name, other.Name)))
+		}
+		if other.Spec.RuntimeClass.Handler == shim.Spec.RuntimeClass.Handler {
+			errs = append(errs, field.Duplicate(path.Child("handler"), fmt.Sprintf("%s (claimed by Shim %s)", shim.Spec.RuntimeClass.Handler, other.Name)))
+		}
+	}
+	return errs
+}
+
+func validateFetchStrategy(fetchStrategy *rcmv1.FetchStrategy, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	if !hasFetchSource(fetchStrategy) {
+		errs = append(errs, field.Required(path, "one of anonHttp, platforms or oci must be set"))
+	}
+	if fetchStrategy.OCI != nil {
+		if _, err := oci.ParseReference(fetchStrategy.OCI.Reference); err != nil {
+			errs = append(errs, field.Invalid(path.Child("oci", "reference"), fetchStrategy.OCI.Reference, err.Error()))
+		}
+	}
+
+	if auth := fetchStrategy.HTTPAuth; auth != nil {
+		for _, msg := range validation.IsDNS1123Subdomain(auth.SecretRef.Name) {
+			errs = append(errs, field.Invalid(path.Child("httpAuth", "secretRef", "name"), auth.SecretRef.Name, msg))
+		}
+	}
+
+	platformsPath := path.Child("platforms")
+	for i, p := range fetchStrategy.Platforms {
+		// The same platform may be spelled differently, e.g. amd64 and x86_64,
+		// so the entries are compared the same way nodes are matched.
+		for _, previous := range fetchStrategy.Platforms[:i] {
+			if matchesPlatform(previous, p.OS, p.Arch) || matchesPlatform(p, previous.OS, previous.Arch) {
+				errs = append(errs, field.Duplicate(platformsPath.Index(i), p.OS+"/"+p.Arch))
+				break
+			}
+		}
+		if p.SHA256 != "" && !isSHA256(p.SHA256) {
+			errs = append(errs, field.Invalid(platformsPath.Index(i).Child("sha256"), p.SHA256, "must be a hex-encoded SHA-256 digest"))
+		}
+	}
+	return errs
+}
+
+func validateRuntimeClass(runtimeClass *rcmv1.RuntimeClassSpec, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	for _, msg := range validation.IsDNS1123Label(runtimeClass.Name) {
+		errs = append(errs, field.Invalid(path.Child("name"), runtimeClass.Name, msg))
+	}
+	for _, msg := range validation.IsDNS1123Label(runtimeClass.Handler) {
+		errs = append(errs, field.Invalid(path.Child("handler"), runtimeClass.Handler, msg))
+	}
+	return errs
+}
+
+func validateRolloutStrategy(rolloutStrategy *rcmv1.RolloutStrategy, path *field.Path) field.ErrorList {
+	if rolloutStrategy.Type == rcmv1.RolloutStrategyTypeRolling && rolloutStrategy.Rolling.MaxUpdate <= 0 {
+		return field.ErrorList{field.Invalid(path.Child("rolling", "maxUpdate"), rolloutStrategy.Rolling.MaxUpdate, "must be greater than 0 when using the rolling strategy")}
+	}
+	return nil
+}
+
+// validateRuntimeOptions checks that each option can be written to the
+// containerd configuration as a TOML key/value pair.
+func validateRuntimeOptions(options map[string]string, path *field.Path) field.ErrorList {
+	keys := make([]string, 0, len(options))
+	for key := range options {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var errs field.ErrorList
+	for _, key := range keys {
+		var parsed map[string]any
+		if err := toml.Unmarshal(fmt.Appendf(nil, "%s = %s", key, options[key]), &parsed); err != nil {
+			errs = append(errs, field.Invalid(path.Key(key), options[key], fmt.Sprintf("must be a valid TOML value: %s", err)))
+		}
+	}
+	return errs
+}
+
+// hasFetchSource reports whether a fetch strategy configures any artifact source.
+func hasFetchSource(fetchStrategy *rcmv1.FetchStrategy) bool {
+	return fetchStrategy.AnonHTTP != nil || len(fetchStrategy.Platforms) > 0 || fetchStrategy.OCI != nil
+}
+
+func isSHA256(digest string) bool {
+	decoded, err := hex.DecodeString(digest)
+	return err == nil && len(decoded) == sha256.Size
+}
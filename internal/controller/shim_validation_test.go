@@ -0,0 +1,186 @@
+package controller //nolint:testpackage // whitebox test
+
+import (
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	rcmv1 "github.com/spinframework/runtime-class-manager/api/v1alpha1"
+)
+
+const validSHA256 = "4f7d7e4b1a1c5b1e6f1b2b0a4f0e6a2d7c9b8a7f6e5d4c3b2a1f0e9d8c7b6a59"
+
+func makeValidShim() *rcmv1.Shim {
+	shim := makeShim([]rcmv1.PlatformArtifact{
+		{OS: "linux", Arch: "amd64", Location: "https://example.com/shim-amd64.tar.gz", SHA256: validSHA256},
+		{OS: "linux", Arch: "arm64", Location: "https://example.com/shim-arm64.tar.gz"},
+	}, nil)
+	shim.Spec.RuntimeClass = rcmv1.RuntimeClassSpec{Name: "wasmtime-spin-v2", Handler: "spin"}
+	shim.Spec.RolloutStrategy = rcmv1.RolloutStrategy{
+		Type:    rcmv1.RolloutStrategyTypeRolling,
+		Rolling: rcmv1.RollingSpec{MaxUpdate: 1},
+	}
+	shim.Spec.ContainerdRuntimeOptions = map[string]string{
+		"SystemdCgroup": "true",
+		"BinaryName":    `"/usr/bin/spin"`,
+	}
+	return shim
+}
+
+func TestValidateShim(t *testing.T) {
+	tests := []struct {
+		name      string
+		mutate    func(shim *rcmv1.Shim)
+		wantField string
+	}{
+		{
+			name:   "valid shim",
+			mutate: func(_ *rcmv1.Shim) {},
+		},
+		{
+			name: "valid anonHttp shim",
+			mutate: func(shim *rcmv1.Shim) {
+				shim.Spec.FetchStrategy = rcmv1.FetchStrategy{AnonHTTP: &rcmv1.AnonHTTPSpec{Location: "https://example.com/shim.tar.gz"}}
+			},
+		},
+		{
+			name: "valid oci shim",
+			mutate: func(shim *rcmv1.Shim) {
+				shim.Spec.FetchStrategy = rcmv1.FetchStrategy{OCI: &rcmv1.OCISpec{Reference: "registry.example.com/shims/spin@sha256:" + validSHA256}}
+			},
+		},
+		{
+			name: "invalid oci reference",
+			mutate: func(shim *rcmv1.Shim) {
+				shim.Spec.FetchStrategy = rcmv1.FetchStrategy{OCI: &rcmv1.OCISpec{Reference: "registry.example.com/Shims/Spin"}}
+			},
+			wantField: "spec.fetchStrategy.oci.reference",
+		},
+		{
+			name: "http auth without secret name",
+			mutate: func(shim *rcmv1.Shim) {
+				shim.Spec.FetchStrategy.HTTPAuth = &rcmv1.HTTPAuthSpec{}
+			},
+			wantField: "spec.fetchStrategy.httpAuth.secretRef.name",
+		},
+		{
+			name:      "no fetch source",
+			mutate:    func(shim *rcmv1.Shim) { shim.Spec.FetchStrategy = rcmv1.FetchStrategy{} },
+			wantField: "spec.fetchStrategy",
+		},
+		{
+			name: "duplicate platform",
+			mutate: func(shim *rcmv1.Shim) {
+				shim.Spec.FetchStrategy.Platforms = append(shim.Spec.FetchStrategy.Platforms,
+					rcmv1.PlatformArtifact{OS: "linux", Arch: "amd64", Location: "https://example.com/other.tar.gz"})
+			},
+			wantField: "spec.fetchStrategy.platforms[2]",
+		},
+		{
+			name: "duplicate platform with uname-style arch",
+			mutate: func(shim *rcmv1.Shim) {
+				shim.Spec.FetchStrategy.Platforms = append(shim.Spec.FetchStrategy.Platforms,
+					rcmv1.PlatformArtifact{OS: "linux", Arch: "x86_64", Location: "https://example.com/other.tar.gz"})
+			},
+			wantField: "spec.fetchStrategy.platforms[2]",
+		},
+		{
+			name:      "malformed sha256",
+			mutate:    func(shim *rcmv1.Shim) { shim.Spec.FetchStrategy.Platforms[0].SHA256 = "not-a-digest" },
+			wantField: "spec.fetchStrategy.platforms[0].sha256",
+		},
+		{
+			name:      "truncated sha256",
+			mutate:    func(shim *rcmv1.Shim) { shim.Spec.FetchStrategy.Platforms[0].SHA256 = validSHA256[:32] },
+			wantField: "spec.fetchStrategy.platforms[0].sha256",
+		},
+		{
+			name:      "runtime class name is not a DNS label",
+			mutate:    func(shim *rcmv1.Shim) { shim.Spec.RuntimeClass.Name = "Wasmtime_Spin" },
+			wantField: "spec.runtimeClass.name",
+		},
+		{
+			name:      "zero maxUpdate with rolling strategy",
+			mutate:    func(shim *rcmv1.Shim) { shim.Spec.RolloutStrategy.Rolling.MaxUpdate = 0 },
+			wantField: "spec.rolloutStrategy.rolling.maxUpdate",
+		},
+		{
+			name: "zero maxUpdate with recreate strategy",
+			mutate: func(shim *rcmv1.Shim) {
+				shim.Spec.RolloutStrategy = rcmv1.RolloutStrategy{Type: rcmv1.RolloutStrategyTypeRecreate}
+			},
+		},
+		{
+			name:      "runtime option is not valid TOML",
+			mutate:    func(shim *rcmv1.Shim) { shim.Spec.ContainerdRuntimeOptions["BinaryName"] = "/usr/bin/spin" },
+			wantField: "spec.containerdRuntimeOptions[BinaryName]",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shim := makeValidShim()
+			tt.mutate(shim)
+
+			errs := ValidateShim(shim)
+			if tt.wantField == "" {
+				if len(errs) > 0 {
+					t.Fatalf("unexpected errors: %v", errs)
+				}
+				return
+			}
+			if len(errs) != 1 || errs[0].Field != tt.wantField {
+				t.Fatalf("errors = %v, want a single error for %s", errs, tt.wantField)
+			}
+		})
+	}
+}
+
+func TestValidateRuntimeClassUnique(t *testing.T) {
+	shim := makeValidShim()
+	other := func(name, runtimeClass, handler string) rcmv1.Shim {
+		return rcmv1.Shim{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       rcmv1.ShimSpec{RuntimeClass: rcmv1.RuntimeClassSpec{Name: runtimeClass, Handler: handler}},
+		}
+	}
+
+	tests := []struct {
+		name       string
+		others     []rcmv1.Shim
+		wantFields []string
+	}{
+		{
+			name:   "only itself",
+			others: []rcmv1.Shim{other(shim.Name, shim.Spec.RuntimeClass.Name, shim.Spec.RuntimeClass.Handler)},
+		},
+		{
+			name:       "same runtime class name",
+			others:     []rcmv1.Shim{other("other", shim.Spec.RuntimeClass.Name, "other")},
+			wantFields: []string{"spec.runtimeClass.name"},
+		},
+		{
+			name:       "same handler",
+			others:     []rcmv1.Shim{other("other", "other", shim.Spec.RuntimeClass.Handler)},
+			wantFields: []string{"spec.runtimeClass.handler"},
+		},
+		{
+			name:   "different runtime class",
+			others: []rcmv1.Shim{other("other", "other", "other")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := ValidateRuntimeClassUnique(shim, tt.others)
+			fields := []string{}
+			for _, err := range errs {
+				fields = append(fields, err.Field)
+			}
+			if strings.Join(fields, ",") != strings.Join(tt.wantFields, ",") {
+				t.Errorf("errors = %v, want errors for %v", errs, tt.wantFields)
+			}
+		})
+	}
+}
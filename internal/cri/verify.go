@@ -0,0 +1,125 @@
+/*
+   Copyright The KWasm Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package cri verifies the runtime handlers of a container runtime over its
+// CRI socket.
+package cri
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1"
+)
+
+// ErrHandlerNotRegistered means the container runtime doesn't know a runtime handler.
+var ErrHandlerNotRegistered = errors.New("runtime handler is not registered")
+
+// ErrHandlersNotReported means the container runtime reports neither its
+// runtime handlers nor its config, so that no runtime handler can be verified.
+var ErrHandlersNotReported = fmt.Errorf("%w: the container runtime doesn't report its runtime handlers", ErrHandlerNotRegistered)
+
+// VerifyHandlers queries the status of the container runtime serving on
+// socketPath until all handlers are registered, or ctx is done.
+func VerifyHandlers(ctx context.Context, socketPath string, handlers []string, interval time.Duration) error {
+	conn, err := grpc.NewClient("unix://"+socketPath, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return fmt.Errorf("failed to create CRI client for %s: %w", socketPath, err)
+	}
+	defer conn.Close()
+	client := runtimeapi.NewRuntimeServiceClient(conn)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var lastErr error
+	for {
+		err := verifyHandlers(ctx, client, handlers)
+		if err == nil {
+			slog.Info("runtime handlers are registered", "handlers", handlers)
+			return nil
+		}
+		slog.Debug("runtime handlers are not registered yet", "error", err)
+		// Keep the reason of the last attempt rather than the deadline
+		// interrupting the current one
+		if ctx.Err() == nil || lastErr == nil {
+			lastErr = err
+		}
+
+		select {
+		case <-ctx.Done():
+			return lastErr
+		case <-ticker.C:
+		}
+	}
+}
+
+func verifyHandlers(ctx context.Context, client runtimeapi.RuntimeServiceClient, handlers []string) error {
+	resp, err := client.Status(ctx, &runtimeapi.StatusRequest{Verbose: true})
+	if err != nil {
+		return fmt.Errorf("failed to query CRI runtime status: %w", err)
+	}
+
+	registered, err := registeredHandlers(resp)
+	if err != nil {
+		return err
+	}
+	if registered == nil {
+		return ErrHandlersNotReported
+	}
+	for _, handler := range handlers {
+		if _, ok := registered[handler]; !ok {
+			return fmt.Errorf("%w: %s", ErrHandlerNotRegistered, handler)
+		}
+	}
+	return nil
+}
+
+// registeredHandlers returns the runtime handlers reported in the status.
+// Runtimes that don't report runtime_handlers yet are covered by the verbose
+// config of containerd, which lists the configured runtimes. It returns nil if
+// the status contains neither.
+func registeredHandlers(resp *runtimeapi.StatusResponse) (map[string]struct{}, error) {
+	handlers := map[string]struct{}{}
+	for _, handler := range resp.GetRuntimeHandlers() {
+		handlers[handler.GetName()] = struct{}{}
+	}
+	if len(handlers) > 0 {
+		return handlers, nil
+	}
+
+	config, ok := resp.GetInfo()["config"]
+	if !ok {
+		return nil, nil
+	}
+	var criConfig struct {
+		Containerd struct {
+			Runtimes map[string]json.RawMessage `json:"runtimes"`
+		} `json:"containerd"`
+	}
+	if err := json.Unmarshal([]byte(config), &criConfig); err != nil {
+		return nil, fmt.Errorf("failed to parse CRI runtime config: %w", err)
+	}
+	for name := range criConfig.Containerd.Runtimes {
+		handlers[name] = struct{}{}
+	}
+	return handlers, nil
+}
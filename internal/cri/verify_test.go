@@ -0,0 +1,109 @@
+/*
+   Copyright The KWasm Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cri_test
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spinframework/runtime-class-manager/internal/cri"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1"
+)
+
+// fakeRuntimeService is a CRI runtime service that only implements Status.
+type fakeRuntimeService struct {
+	runtimeapi.UnimplementedRuntimeServiceServer
+	status *runtimeapi.StatusResponse
+}
+
+func (f *fakeRuntimeService) Status(_ context.Context, _ *runtimeapi.StatusRequest) (*runtimeapi.StatusResponse, error) {
+	return f.status, nil
+}
+
+func serveFakeCRI(t *testing.T, status *runtimeapi.StatusResponse) string {
+	t.Helper()
+	socketPath := filepath.Join(t.TempDir(), "cri.sock")
+	listener, err := net.Listen("unix", socketPath)
+	require.NoError(t, err)
+
+	server := grpc.NewServer()
+	runtimeapi.RegisterRuntimeServiceServer(server, &fakeRuntimeService{status: status})
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	t.Cleanup(server.Stop)
+	return socketPath
+}
+
+func TestVerifyHandlers(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  *runtimeapi.StatusResponse
+		wantErr error
+	}{
+		{
+			name: "handler reported",
+			status: &runtimeapi.StatusResponse{RuntimeHandlers: []*runtimeapi.RuntimeHandler{
+				{Name: ""}, {Name: "runc"}, {Name: "spin-v1"},
+			}},
+		},
+		{
+			name: "handler missing",
+			status: &runtimeapi.StatusResponse{RuntimeHandlers: []*runtimeapi.RuntimeHandler{
+				{Name: ""}, {Name: "runc"},
+			}},
+			wantErr: cri.ErrHandlerNotRegistered,
+		},
+		{
+			name: "handler in containerd config",
+			status: &runtimeapi.StatusResponse{Info: map[string]string{
+				"config": `{"containerd":{"runtimes":{"runc":{},"spin-v1":{"runtimeType":"/opt/rcm/bin/containerd-shim-spin-v1"}}}}`,
+			}},
+		},
+		{
+			name:    "handlers not reported",
+			status:  &runtimeapi.StatusResponse{},
+			wantErr: cri.ErrHandlersNotReported,
+		},
+		{
+			name: "handler missing in containerd config",
+			status: &runtimeapi.StatusResponse{Info: map[string]string{
+				"config": `{"containerd":{"runtimes":{"runc":{}}}}`,
+			}},
+			wantErr: cri.ErrHandlerNotRegistered,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			socketPath := serveFakeCRI(t, tt.status)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+			defer cancel()
+			err := cri.VerifyHandlers(ctx, socketPath, []string{"spin-v1"}, 10*time.Millisecond)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,125 @@
+/*
+   Copyright The KWasm Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package crio
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path"
+
+	"github.com/spf13/afero"
+	"github.com/spinframework/runtime-class-manager/internal/containerd"
+	"github.com/spinframework/runtime-class-manager/internal/shim"
+)
+
+// DefaultDropInDir is the directory CRI-O reads drop-in config files from.
+const DefaultDropInDir = "/etc/crio/crio.conf.d"
+
+// Config configures shims as runtimes of CRI-O. Each runtime is configured in
+// a drop-in file of its own, so the main CRI-O config is never changed.
+type Config struct {
+	hostFs    afero.Fs
+	dropInDir string
+	restarter containerd.Restarter
+}
+
+func NewConfig(hostFs afero.Fs, dropInDir string, restarter containerd.Restarter) *Config {
+	return &Config{
+		hostFs:    hostFs,
+		dropInDir: dropInDir,
+		restarter: restarter,
+	}
+}
+
+// AddRuntime writes the drop-in configuring the runtime of a shim. It returns
+// whether the drop-in was changed.
+func (c *Config) AddRuntime(shimPath string) (bool, error) {
+	runtimeName := shim.RuntimeName(path.Base(shimPath))
+	dropInPath := c.dropInPath(runtimeName)
+	l := slog.With("runtime", runtimeName, "path", dropInPath)
+
+	want := generateConfig(runtimeName, shimPath)
+	got, err := afero.ReadFile(c.hostFs, dropInPath)
+	switch {
+	case err == nil && string(got) == want:
+		l.Info("runtime config is up to date")
+		return false, nil
+	case err != nil && !errors.Is(err, os.ErrNotExist):
+		return false, err
+	}
+
+	if err := c.hostFs.MkdirAll(c.dropInDir, 0o755); err != nil { //nolint:mnd // file permissions
+		return false, fmt.Errorf("failed to create drop-in directory %s: %w", c.dropInDir, err)
+	}
+	l.Info("writing runtime config")
+	if err := afero.WriteFile(c.hostFs, dropInPath, []byte(want), 0o644); err != nil { //nolint:mnd // file permissions
+		return false, err
+	}
+	return true, nil
+}
+
+// RemoveRuntime removes the drop-in configuring the runtime of a shim. It
+// returns whether the drop-in existed.
+func (c *Config) RemoveRuntime(shimPath string) (bool, error) {
+	runtimeName := shim.RuntimeName(path.Base(shimPath))
+	dropInPath := c.dropInPath(runtimeName)
+	l := slog.With("runtime", runtimeName, "path", dropInPath)
+
+	if err := c.hostFs.Remove(dropInPath); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			l.Warn("runtime config does not exist, skipping")
+			return false, nil
+		}
+		return false, err
+	}
+	l.Info("removed runtime config")
+	return true, nil
+}
+
+// HasRuntime returns whether the drop-in of a shim's runtime exists.
+func (c *Config) HasRuntime(shimPath string) (bool, error) {
+	return afero.Exists(c.hostFs, c.dropInPath(shim.RuntimeName(path.Base(shimPath))))
+}
+
+// ConfigFiles returns the files AddRuntime and RemoveRuntime may change for
+// the runtime of a shim.
+func (c *Config) ConfigFiles(shimPath string) []string {
+	return []string{c.dropInPath(shim.RuntimeName(path.Base(shimPath)))}
+}
+
+func (c *Config) RestartRuntime() error {
+	return c.restarter.Restart()
+}
+
+func (c *Config) dropInPath(runtimeName string) string {
+	return path.Join(c.dropInDir, "99-rcm-"+runtimeName+".conf")
+}
+
+// generateConfig generates the drop-in of a runtime. Shims implement the
+// containerd shim v2 API, which CRI-O supports with the "vm" runtime type.
+func generateConfig(runtimeName, shimPath string) string {
+	return fmt.Sprintf(`# RCM runtime config for %[1]s
+[crio.runtime.runtimes.%[1]s]
+runtime_path = "%[2]s"
+runtime_type = "vm"
+runtime_root = "/run/rcm/%[1]s"
+monitor_cgroup = "pod"
+monitor_env = ["PATH=/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin"]
+`, runtimeName, shimPath)
+}
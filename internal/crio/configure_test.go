@@ -0,0 +1,98 @@
+/*
+   Copyright The KWasm Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package crio //nolint:testpackage // whitebox test
+
+import (
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const wantDropIn = `# RCM runtime config for spin-v1
+[crio.runtime.runtimes.spin-v1]
+runtime_path = "/opt/rcm/bin/containerd-shim-spin-v1"
+runtime_type = "vm"
+runtime_root = "/run/rcm/spin-v1"
+monitor_cgroup = "pod"
+monitor_env = ["PATH=/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin"]
+`
+
+func TestConfig_AddRuntime(t *testing.T) {
+	const dropInPath = DefaultDropInDir + "/99-rcm-spin-v1.conf"
+	tests := []struct {
+		name        string
+		existing    string
+		wantChanged bool
+	}{
+		{"new runtime", "", true},
+		{"outdated runtime", `[crio.runtime.runtimes.spin-v1]
+runtime_path = "/usr/bin/containerd-shim-spin-v1"
+`, true},
+		{"up to date runtime", wantDropIn, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			if tt.existing != "" {
+				require.NoError(t, afero.WriteFile(fs, dropInPath, []byte(tt.existing), 0o644))
+			}
+			c := NewConfig(fs, DefaultDropInDir, nil)
+
+			changed, err := c.AddRuntime("/opt/rcm/bin/containerd-shim-spin-v1")
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantChanged, changed)
+
+			got, err := afero.ReadFile(fs, dropInPath)
+			require.NoError(t, err)
+			assert.Equal(t, wantDropIn, string(got))
+		})
+	}
+}
+
+func TestConfig_RemoveRuntime(t *testing.T) {
+	const dropInPath = DefaultDropInDir + "/99-rcm-spin-v1.conf"
+	const otherPath = DefaultDropInDir + "/10-crun.conf"
+	tests := []struct {
+		name        string
+		existing    bool
+		wantChanged bool
+	}{
+		{"existing runtime", true, true},
+		{"missing runtime", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			require.NoError(t, afero.WriteFile(fs, otherPath, []byte("[crio.runtime]\n"), 0o644))
+			if tt.existing {
+				require.NoError(t, afero.WriteFile(fs, dropInPath, []byte(wantDropIn), 0o644))
+			}
+			c := NewConfig(fs, DefaultDropInDir, nil)
+
+			changed, err := c.RemoveRuntime("/opt/rcm/bin/containerd-shim-spin-v1")
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantChanged, changed)
+
+			_, err = fs.Stat(dropInPath)
+			require.ErrorIs(t, err, afero.ErrFileNotFound)
+			_, err = fs.Stat(otherPath)
+			require.NoError(t, err)
+		})
+	}
+}
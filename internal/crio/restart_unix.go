@@ -0,0 +1,57 @@
+//go:build unix
+// +build unix
+
+/*
+   Copyright The KWasm Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package crio
+
+import (
+	"fmt"
+	"log/slog"
+	"syscall"
+
+	"github.com/spinframework/runtime-class-manager/internal/containerd"
+)
+
+// Restarter restarts CRI-O. It is used for CRI-O nodes, which are not
+// running containerd.
+type Restarter struct{}
+
+func (c Restarter) Restart() error {
+	// If listing systemd units succeeds, prefer systemctl restart, as CRI-O
+	// doesn't reload all runtime settings on SIGHUP; otherwise signal the pid
+	// to reload the config
+	if _, err := containerd.ListSystemdUnits(); err == nil {
+		out, err := containerd.NsenterCmd("systemctl", "restart", "crio").CombinedOutput()
+		slog.Debug(string(out))
+		if err != nil {
+			return fmt.Errorf("unable to restart crio: %w", err)
+		}
+		return nil
+	}
+
+	pid, err := containerd.GetPid("crio")
+	if err != nil {
+		return err
+	}
+	slog.Debug("found crio process", "pid", pid)
+
+	if err := syscall.Kill(pid, syscall.SIGHUP); err != nil {
+		return fmt.Errorf("failed to send SIGHUP to crio: %w", err)
+	}
+	return nil
+}
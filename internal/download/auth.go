@@ -0,0 +1,181 @@
+/*
+   Copyright The KWasm Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package download
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxRedirects is the number of redirects a download follows.
+const maxRedirects = 10
+
+// Auth holds the credentials used to download an artifact.
+type Auth struct {
+	Username string
+	Password string
+	Token    string
+	Headers  http.Header
+	// TLSConfig holds the client certificate and CA bundle, if any.
+	TLSConfig *tls.Config
+}
+
+// LoadAuth reads credentials from a directory containing the keys of the
+// Secret referenced by a Shim's httpAuth. All keys are optional.
+func LoadAuth(dir string) (*Auth, error) {
+	auth := &Auth{Headers: http.Header{}}
+
+	read := func(name string) (string, bool, error) {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if errors.Is(err, fs.ErrNotExist) {
+			return "", false, nil
+		}
+		if err != nil {
+			return "", false, err
+		}
+		return strings.TrimRight(string(data), "\r\n"), true, nil
+	}
+
+	var err error
+	if auth.Username, _, err = read("username"); err != nil {
+		return nil, err
+	}
+	if auth.Password, _, err = read("password"); err != nil {
+		return nil, err
+	}
+	if auth.Token, _, err = read("token"); err != nil {
+		return nil, err
+	}
+	headers, _, err := read("headers")
+	if err != nil {
+		return nil, err
+	}
+	if err := parseHeaders(headers, auth.Headers); err != nil {
+		return nil, err
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	useTLS := false
+	cert, hasCert, err := read("tls.crt")
+	if err != nil {
+		return nil, err
+	}
+	key, hasKey, err := read("tls.key")
+	if err != nil {
+		return nil, err
+	}
+	if hasCert && hasKey {
+		pair, err := tls.X509KeyPair([]byte(cert), []byte(key))
+		if err != nil {
+			return nil, fmt.Errorf("invalid client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{pair}
+		useTLS = true
+	}
+	ca, hasCA, err := read("ca.crt")
+	if err != nil {
+		return nil, err
+	}
+	if hasCA {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM([]byte(ca)) {
+			return nil, errors.New("no certificates found in ca.crt")
+		}
+		tlsConfig.RootCAs = pool
+		useTLS = true
+	}
+	if useTLS {
+		auth.TLSConfig = tlsConfig
+	}
+
+	return auth, nil
+}
+
+// HTTPClient returns a client that presents the configured client certificate
+// and trusts the configured CA bundle. The credentials are not sent along
+// when a request is redirected to another host.
+func (a *Auth) HTTPClient() *http.Client {
+	if a == nil {
+		return http.DefaultClient
+	}
+	client := &http.Client{CheckRedirect: a.checkRedirect}
+	if a.TLSConfig != nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone() //nolint:forcetypeassert // always an *http.Transport
+		transport.TLSClientConfig = a.TLSConfig
+		client.Transport = transport
+	}
+	return client
+}
+
+// checkRedirect removes the custom headers and the Authorization header from
+// requests that are redirected to another host. Like the default policy of
+// http.Client, it stops after 10 redirects.
+func (a *Auth) checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxRedirects {
+		return fmt.Errorf("stopped after %d redirects", maxRedirects)
+	}
+	if req.URL.Host != via[0].URL.Host {
+		for name := range a.Headers {
+			req.Header.Del(name)
+		}
+		req.Header.Del("Authorization")
+	}
+	return nil
+}
+
+func (a *Auth) apply(req *http.Request) {
+	if a == nil {
+		return
+	}
+	for name, values := range a.Headers {
+		for _, value := range values {
+			req.Header.Add(name, value)
+		}
+	}
+	if a.Username != "" {
+		req.SetBasicAuth(a.Username, a.Password)
+	}
+	if a.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+a.Token)
+	}
+}
+
+// parseHeaders parses one "Name: value" header per line.
+func parseHeaders(data string, headers http.Header) error {
+	scanner := bufio.NewScanner(bytes.NewBufferString(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(line, ":")
+		if !ok || strings.TrimSpace(name) == "" {
+			return fmt.Errorf("invalid header %q, expected \"Name: value\"", line)
+		}
+		headers.Add(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+	return scanner.Err()
+}
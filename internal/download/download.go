@@ -0,0 +1,192 @@
+/*
+   Copyright The KWasm Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package download fetches shim artifacts over HTTP(S).
+package download
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spinframework/runtime-class-manager/internal/archive"
+)
+
+// Categories of download errors. Errors returned by Downloader.Download wrap
+// exactly one of them.
+var (
+	ErrNetwork  = errors.New("network error")
+	ErrChecksum = errors.New("checksum mismatch")
+	ErrArchive  = errors.New("invalid artifact")
+)
+
+const defaultMaxDelay = time.Minute
+
+// Downloader downloads shim artifacts and extracts the shim binary from them.
+type Downloader struct {
+	// HTTPClient is used for all requests. http.DefaultClient is used if nil.
+	HTTPClient *http.Client
+	// Auth is applied to every request.
+	Auth *Auth
+	// Retries is the number of times a failed download is retried.
+	Retries int
+	// Delay is the delay before the first retry. It doubles with every
+	// further retry up to MaxDelay, and is randomized by up to 50%.
+	Delay    time.Duration
+	MaxDelay time.Duration
+}
+
+// Result describes a downloaded artifact.
+type Result struct {
+	Size   int64
+	SHA256 string
+	Format archive.Format
+}
+
+// Download fetches the artifact at location, verifies it against the expected
+// hex-encoded SHA-256 digest, if any, and writes the shim binary it contains
+// to dest. Nothing is extracted from an artifact that fails verification.
+func (d *Downloader) Download(ctx context.Context, location, expectedSHA256, dest string) (Result, error) {
+	tmp, err := os.CreateTemp(filepath.Dir(dest), ".download-*")
+	if err != nil {
+		return Result{}, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	result, err := d.fetchWithRetries(ctx, location, tmp)
+	if err != nil {
+		return result, err
+	}
+
+	if expectedSHA256 != "" && !strings.EqualFold(result.SHA256, expectedSHA256) {
+		return result, fmt.Errorf("%w: expected sha256 %s, got %s", ErrChecksum, strings.ToLower(expectedSHA256), result.SHA256)
+	}
+
+	result.Format, err = archive.ExtractShim(tmp, dest)
+	if err != nil {
+		return result, fmt.Errorf("%w: %w", ErrArchive, err)
+	}
+	return result, nil
+}
+
+func (d *Downloader) fetchWithRetries(ctx context.Context, location string, f *os.File) (Result, error) {
+	for attempt := 0; ; attempt++ {
+		result, err := d.fetch(ctx, location, f)
+		if err == nil {
+			return result, nil
+		}
+
+		var retryable *retryableError
+		if !errors.As(err, &retryable) || attempt >= d.Retries {
+			return Result{}, err
+		}
+
+		delay := d.backoff(attempt)
+		slog.Warn("download failed, retrying", "location", location, "attempt", attempt+1, "retries", d.Retries, "delay", delay, "error", err)
+		select {
+		case <-ctx.Done():
+			return Result{}, fmt.Errorf("%w: %w", ErrNetwork, ctx.Err())
+		case <-time.After(delay):
+		}
+	}
+}
+
+// fetch downloads location into f, replacing any content of a previous
+// attempt, and hashes it on the way.
+func (d *Downloader) fetch(ctx context.Context, location string, f *os.File) (Result, error) {
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return Result{}, err
+	}
+	if err := f.Truncate(0); err != nil {
+		return Result{}, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, location, nil)
+	if err != nil {
+		return Result{}, fmt.Errorf("%w: %w", ErrNetwork, err)
+	}
+	d.Auth.apply(req)
+
+	client := d.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return Result{}, &retryableError{err: fmt.Errorf("%w: %w", ErrNetwork, err)}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		err := fmt.Errorf("%w: unexpected status %s", ErrNetwork, resp.Status)
+		if isRetryableStatus(resp.StatusCode) {
+			return Result{}, &retryableError{err: err}
+		}
+		return Result{}, err
+	}
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(f, hash), resp.Body)
+	if err != nil {
+		return Result{}, &retryableError{err: fmt.Errorf("%w: %w", ErrNetwork, err)}
+	}
+
+	return Result{Size: size, SHA256: hex.EncodeToString(hash.Sum(nil))}, nil
+}
+
+// backoff returns the delay before the retry following the given attempt.
+func (d *Downloader) backoff(attempt int) time.Duration {
+	maxDelay := d.MaxDelay
+	if maxDelay <= 0 {
+		maxDelay = defaultMaxDelay
+	}
+	delay := d.Delay
+	for i := 0; i < attempt && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	delay = min(delay, maxDelay)
+	if delay <= 0 {
+		return 0
+	}
+	// Randomize the delay so that nodes don't retry in lockstep
+	return delay/2 + rand.N(delay/2+1) //nolint:gosec,mnd // jitter doesn't need a secure random number
+}
+
+func isRetryableStatus(code int) bool {
+	return code >= http.StatusInternalServerError ||
+		code == http.StatusTooManyRequests ||
+		code == http.StatusRequestTimeout
+}
+
+// retryableError marks errors after which a download is retried.
+type retryableError struct {
+	err error
+}
+
+func (e *retryableError) Error() string { return e.err.Error() }
+
+func (e *retryableError) Unwrap() error { return e.err }
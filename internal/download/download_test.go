@@ -0,0 +1,287 @@
+/*
+   Copyright The KWasm Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package download_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/spinframework/runtime-class-manager/internal/archive"
+	"github.com/spinframework/runtime-class-manager/internal/download"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func tarGz(t *testing.T, name, content string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0o755, Size: int64(len(content)), Typeflag: tar.TypeReg}))
+	_, err := tw.Write([]byte(content))
+	require.NoError(t, err)
+	require.NoError(t, tw.Close())
+	require.NoError(t, gw.Close())
+	return buf.Bytes()
+}
+
+func TestDownload(t *testing.T) {
+	artifact := tarGz(t, "containerd-shim-spin-v2", "shim binary")
+
+	tests := []struct {
+		name         string
+		statuses     []int
+		artifact     []byte
+		sha256       string
+		retries      int
+		wantErr      error
+		wantRequests int32
+		wantFormat   archive.Format
+	}{
+		{
+			name:         "verified tar.gz",
+			artifact:     artifact,
+			sha256:       sha256Hex(artifact),
+			wantRequests: 1,
+			wantFormat:   archive.FormatTarGzip,
+		},
+		{
+			name:         "without digest",
+			artifact:     artifact,
+			wantRequests: 1,
+			wantFormat:   archive.FormatTarGzip,
+		},
+		{
+			name:         "raw binary",
+			artifact:     []byte("shim binary"),
+			sha256:       sha256Hex([]byte("shim binary")),
+			wantRequests: 1,
+			wantFormat:   archive.FormatRaw,
+		},
+		{
+			name:         "checksum mismatch",
+			artifact:     artifact,
+			sha256:       sha256Hex([]byte("something else")),
+			retries:      3,
+			wantErr:      download.ErrChecksum,
+			wantRequests: 1,
+		},
+		{
+			name:         "archive without shim",
+			artifact:     tarGz(t, "README.md", "readme"),
+			wantErr:      download.ErrArchive,
+			wantRequests: 1,
+		},
+		{
+			name:         "retries server errors",
+			statuses:     []int{http.StatusServiceUnavailable, http.StatusTooManyRequests},
+			artifact:     artifact,
+			sha256:       sha256Hex(artifact),
+			retries:      2,
+			wantRequests: 3,
+			wantFormat:   archive.FormatTarGzip,
+		},
+		{
+			name:         "gives up after retries",
+			statuses:     []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway},
+			artifact:     artifact,
+			retries:      2,
+			wantErr:      download.ErrNetwork,
+			wantRequests: 3,
+		},
+		{
+			name:         "does not retry client errors",
+			statuses:     []int{http.StatusNotFound},
+			artifact:     artifact,
+			retries:      3,
+			wantErr:      download.ErrNetwork,
+			wantRequests: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				n := int(requests.Add(1))
+				if n <= len(tt.statuses) {
+					w.WriteHeader(tt.statuses[n-1])
+					return
+				}
+				_, _ = w.Write(tt.artifact)
+			}))
+			defer server.Close()
+
+			dest := filepath.Join(t.TempDir(), "containerd-shim-spin-v2")
+			downloader := &download.Downloader{Retries: tt.retries}
+			result, err := downloader.Download(t.Context(), server.URL+"/shim.tar.gz", tt.sha256, dest)
+
+			assert.Equal(t, tt.wantRequests, requests.Load())
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				assert.NoFileExists(t, dest)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantFormat, result.Format)
+			assert.Equal(t, sha256Hex(tt.artifact), result.SHA256)
+			assert.Equal(t, int64(len(tt.artifact)), result.Size)
+			content, err := os.ReadFile(dest)
+			require.NoError(t, err)
+			assert.Equal(t, "shim binary", string(content))
+		})
+	}
+}
+
+func TestDownloadWithAuth(t *testing.T) {
+	artifact := []byte("shim binary")
+
+	tests := []struct {
+		name   string
+		files  map[string]string
+		verify func(t *testing.T, r *http.Request)
+	}{
+		{
+			name:  "basic auth",
+			files: map[string]string{"username": "user\n", "password": "secret"},
+			verify: func(t *testing.T, r *http.Request) {
+				t.Helper()
+				username, password, ok := r.BasicAuth()
+				assert.True(t, ok)
+				assert.Equal(t, "user", username)
+				assert.Equal(t, "secret", password)
+			},
+		},
+		{
+			name:  "bearer token",
+			files: map[string]string{"token": "abc"},
+			verify: func(t *testing.T, r *http.Request) {
+				t.Helper()
+				assert.Equal(t, "Bearer abc", r.Header.Get("Authorization"))
+			},
+		},
+		{
+			name:  "custom headers",
+			files: map[string]string{"headers": "X-Api-Key: key\n\nPrivate-Token:  token \n"},
+			verify: func(t *testing.T, r *http.Request) {
+				t.Helper()
+				assert.Equal(t, "key", r.Header.Get("X-Api-Key"))
+				assert.Equal(t, "token", r.Header.Get("Private-Token"))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				tt.verify(t, r)
+				_, _ = w.Write(artifact)
+			}))
+			defer server.Close()
+
+			authDir := t.TempDir()
+			for name, content := range tt.files {
+				require.NoError(t, os.WriteFile(filepath.Join(authDir, name), []byte(content), 0o600))
+			}
+			// The server's certificate is trusted through the CA bundle
+			ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+			require.NoError(t, os.WriteFile(filepath.Join(authDir, "ca.crt"), ca, 0o600))
+
+			auth, err := download.LoadAuth(authDir)
+			require.NoError(t, err)
+			downloader := &download.Downloader{HTTPClient: auth.HTTPClient(), Auth: auth}
+
+			dest := filepath.Join(t.TempDir(), "containerd-shim-spin-v2")
+			_, err = downloader.Download(t.Context(), server.URL, sha256Hex(artifact), dest)
+			require.NoError(t, err)
+			assert.FileExists(t, dest)
+		})
+	}
+}
+
+func TestDownloadWithAuthRedirect(t *testing.T) {
+	artifact := []byte("shim binary")
+
+	tests := []struct {
+		name        string
+		otherHost   bool
+		wantHeaders bool
+	}{
+		{"same host keeps credentials", false, true},
+		{"other host drops credentials", true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/redirect" {
+					http.Redirect(w, r, "/shim", http.StatusFound)
+					return
+				}
+				if tt.wantHeaders {
+					assert.Equal(t, "key", r.Header.Get("X-Api-Key"))
+					assert.Equal(t, "Bearer abc", r.Header.Get("Authorization"))
+				} else {
+					assert.Empty(t, r.Header.Get("X-Api-Key"))
+					assert.Empty(t, r.Header.Get("Authorization"))
+				}
+				_, _ = w.Write(artifact)
+			}))
+			defer target.Close()
+			location := target.URL + "/redirect"
+			if tt.otherHost {
+				origin := httptest.NewServer(http.RedirectHandler(target.URL+"/shim", http.StatusFound))
+				defer origin.Close()
+				location = origin.URL
+			}
+
+			authDir := t.TempDir()
+			require.NoError(t, os.WriteFile(filepath.Join(authDir, "headers"), []byte("X-Api-Key: key\n"), 0o600))
+			require.NoError(t, os.WriteFile(filepath.Join(authDir, "token"), []byte("abc"), 0o600))
+			auth, err := download.LoadAuth(authDir)
+			require.NoError(t, err)
+			downloader := &download.Downloader{HTTPClient: auth.HTTPClient(), Auth: auth}
+
+			dest := filepath.Join(t.TempDir(), "containerd-shim-spin-v2")
+			_, err = downloader.Download(t.Context(), location, sha256Hex(artifact), dest)
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestLoadAuthInvalidHeaders(t *testing.T) {
+	authDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(authDir, "headers"), []byte("not a header"), 0o600))
+
+	_, err := download.LoadAuth(authDir)
+	require.Error(t, err)
+}
@@ -0,0 +1,152 @@
+/*
+   Copyright The KWasm Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package dryrun
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/spf13/afero"
+)
+
+// Op is the kind of change to a file.
+type Op string
+
+const (
+	OpCreate Op = "create"
+	OpUpdate Op = "update"
+	OpRemove Op = "remove"
+)
+
+// Change is a change to a file of the base filesystem.
+type Change struct {
+	Path string
+	Op   Op
+	// Before and After are the contents of the file before and after the
+	// change. Before is nil for created files, After is nil for removed files.
+	Before []byte
+	After  []byte
+}
+
+// Changes returns the changes to files, sorted by path. Files that were
+// written without changing their content are left out.
+func (f *Fs) Changes() ([]Change, error) {
+	var changes []Change
+	err := afero.Walk(f.layer, string(os.PathSeparator), func(p string, info fs.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		after, err := afero.ReadFile(f.layer, p)
+		if err != nil {
+			return err
+		}
+		before, err := afero.ReadFile(f.base, p)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			changes = append(changes, Change{Path: p, Op: OpCreate, After: after})
+		case err != nil:
+			return err
+		case !bytes.Equal(before, after):
+			changes = append(changes, Change{Path: p, Op: OpUpdate, Before: before, After: after})
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	for p := range f.removed {
+		info, err := f.base.Stat(p)
+		if err != nil {
+			return nil, err
+		}
+		if info.IsDir() {
+			continue
+		}
+		before, err := afero.ReadFile(f.base, p)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, Change{Path: p, Op: OpRemove, Before: before})
+	}
+
+	slices.SortFunc(changes, func(a, b Change) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+	return changes, nil
+}
+
+// Print writes the list of changed files to w, followed by a unified diff of
+// each changed text file.
+func Print(w io.Writer, changes []Change) error {
+	if len(changes) == 0 {
+		_, err := fmt.Fprintln(w, "no changes")
+		return err
+	}
+
+	for _, c := range changes {
+		if _, err := fmt.Fprintf(w, "%s %s\n", c.Op, c.Path); err != nil {
+			return err
+		}
+	}
+
+	for _, c := range changes {
+		if !isText(c.Before) || !isText(c.After) {
+			continue
+		}
+		diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+			A:        splitLines(c.Before),
+			B:        splitLines(c.After),
+			FromFile: "a" + c.Path,
+			ToFile:   "b" + c.Path,
+			Context:  3, //nolint:mnd // lines of context, as in diff -u
+		})
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprint(w, "\n"+diff); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isText returns whether data looks like the content of a text file rather
+// than a binary.
+func isText(data []byte) bool {
+	return utf8.Valid(data) && bytes.IndexByte(data, 0) < 0
+}
+
+// splitLines splits data into lines, keeping their line breaks.
+func splitLines(data []byte) []string {
+	if len(data) == 0 {
+		return nil
+	}
+	lines := strings.SplitAfter(string(data), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
@@ -0,0 +1,246 @@
+/*
+   Copyright The KWasm Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package dryrun records the changes the node installer would make to the
+// host, without changing anything.
+package dryrun
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"syscall"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+// Fs is a filesystem that reads from a base filesystem and keeps all writes
+// in memory. Files of the base that are removed are hidden, but stay in the
+// base filesystem.
+type Fs struct {
+	*afero.CopyOnWriteFs
+	base    afero.Fs
+	layer   afero.Fs
+	removed map[string]bool
+}
+
+var _ afero.Lstater = (*Fs)(nil)
+
+// New returns an Fs on top of base, which is never written to.
+func New(base afero.Fs) *Fs {
+	base = afero.NewReadOnlyFs(base)
+	layer := afero.NewMemMapFs()
+	return &Fs{
+		CopyOnWriteFs: afero.NewCopyOnWriteFs(base, layer).(*afero.CopyOnWriteFs), //nolint:forcetypeassert // always a *CopyOnWriteFs
+		base:          base,
+		layer:         layer,
+		removed:       make(map[string]bool),
+	}
+}
+
+func (f *Fs) Name() string {
+	return "DryRunFs"
+}
+
+func (f *Fs) isRemoved(name string) bool {
+	return f.removed[filepath.Clean(name)]
+}
+
+// restore makes name and its parent directories visible again after they
+// have been removed.
+func (f *Fs) restore(name string) {
+	for name = filepath.Clean(name); ; name = filepath.Dir(name) {
+		delete(f.removed, name)
+		if name == filepath.Dir(name) {
+			return
+		}
+	}
+}
+
+func (f *Fs) Create(name string) (afero.File, error) {
+	return f.OpenFile(name, os.O_CREATE|os.O_TRUNC|os.O_RDWR, 0o666) //nolint:mnd // file permissions
+}
+
+func (f *Fs) Mkdir(name string, perm os.FileMode) error {
+	f.restore(filepath.Dir(name))
+	if f.isRemoved(name) {
+		delete(f.removed, filepath.Clean(name))
+		return f.layer.MkdirAll(name, perm)
+	}
+	return f.CopyOnWriteFs.Mkdir(name, perm)
+}
+
+func (f *Fs) MkdirAll(name string, perm os.FileMode) error {
+	f.restore(name)
+	return f.CopyOnWriteFs.MkdirAll(name, perm)
+}
+
+func (f *Fs) Open(name string) (afero.File, error) {
+	return f.OpenFile(name, os.O_RDONLY, 0)
+}
+
+func (f *Fs) OpenFile(name string, flag int, perm os.FileMode) (afero.File, error) {
+	if f.isRemoved(name) {
+		if flag&os.O_CREATE == 0 {
+			return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+		}
+		// The file is created again, so it must not have the content of the
+		// base
+		f.restore(name)
+		if err := f.layer.MkdirAll(filepath.Dir(name), 0o755); err != nil { //nolint:mnd // file permissions
+			return nil, err
+		}
+		return f.layer.OpenFile(name, flag|os.O_TRUNC, perm)
+	}
+
+	file, err := f.CopyOnWriteFs.OpenFile(name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	if info, err := file.Stat(); err == nil && info.IsDir() {
+		return &dir{File: file, fs: f, name: name}, nil
+	}
+	return file, nil
+}
+
+func (f *Fs) Remove(name string) error {
+	if f.isRemoved(name) {
+		return &os.PathError{Op: "remove", Path: name, Err: os.ErrNotExist}
+	}
+
+	_, layerErr := f.layer.Stat(name)
+	_, baseErr := f.base.Stat(name)
+	if layerErr != nil && baseErr != nil {
+		return &os.PathError{Op: "remove", Path: name, Err: os.ErrNotExist}
+	}
+	if layerErr == nil {
+		if err := f.layer.Remove(name); err != nil {
+			return err
+		}
+	}
+	if baseErr == nil {
+		f.removed[filepath.Clean(name)] = true
+	}
+	return nil
+}
+
+func (f *Fs) RemoveAll(name string) error {
+	var paths []string
+	err := afero.Walk(f, name, func(p string, _ fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		paths = append(paths, p)
+		return nil
+	})
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// Remove the contents of directories first
+	slices.Reverse(paths)
+	for _, p := range paths {
+		if err := f.Remove(p); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Rename copies the file oldname to newname and removes oldname. Directories
+// cannot be renamed.
+func (f *Fs) Rename(oldname, newname string) error {
+	info, err := f.Stat(oldname)
+	if err != nil {
+		return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: err}
+	}
+	if info.IsDir() {
+		return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: syscall.EPERM}
+	}
+
+	data, err := afero.ReadFile(f, oldname)
+	if err != nil {
+		return err
+	}
+	if err := afero.WriteFile(f, newname, data, info.Mode()); err != nil {
+		return err
+	}
+	return f.Remove(oldname)
+}
+
+func (f *Fs) Stat(name string) (os.FileInfo, error) {
+	if f.isRemoved(name) {
+		return nil, &os.PathError{Op: "stat", Path: name, Err: os.ErrNotExist}
+	}
+	return f.CopyOnWriteFs.Stat(name)
+}
+
+func (f *Fs) LstatIfPossible(name string) (os.FileInfo, bool, error) {
+	if f.isRemoved(name) {
+		return nil, false, &os.PathError{Op: "lstat", Path: name, Err: os.ErrNotExist}
+	}
+	return f.CopyOnWriteFs.LstatIfPossible(name)
+}
+
+func (f *Fs) Chmod(name string, mode os.FileMode) error {
+	if f.isRemoved(name) {
+		return &os.PathError{Op: "chmod", Path: name, Err: os.ErrNotExist}
+	}
+	return f.CopyOnWriteFs.Chmod(name, mode)
+}
+
+func (f *Fs) Chown(name string, uid, gid int) error {
+	if f.isRemoved(name) {
+		return &os.PathError{Op: "chown", Path: name, Err: os.ErrNotExist}
+	}
+	return f.CopyOnWriteFs.Chown(name, uid, gid)
+}
+
+func (f *Fs) Chtimes(name string, atime, mtime time.Time) error {
+	if f.isRemoved(name) {
+		return &os.PathError{Op: "chtimes", Path: name, Err: os.ErrNotExist}
+	}
+	return f.CopyOnWriteFs.Chtimes(name, atime, mtime)
+}
+
+// dir is a directory of an Fs, which hides the files that have been removed.
+type dir struct {
+	afero.File
+	fs   *Fs
+	name string
+}
+
+func (d *dir) Readdir(count int) ([]os.FileInfo, error) {
+	infos, err := d.File.Readdir(count)
+	return slices.DeleteFunc(infos, func(info os.FileInfo) bool {
+		return d.fs.isRemoved(filepath.Join(d.name, info.Name()))
+	}), err
+}
+
+func (d *dir) Readdirnames(count int) ([]string, error) {
+	infos, err := d.Readdir(count)
+	names := make([]string, len(infos))
+	for i, info := range infos {
+		names[i] = info.Name()
+	}
+	return names, err
+}
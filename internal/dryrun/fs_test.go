@@ -0,0 +1,115 @@
+/*
+   Copyright The KWasm Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package dryrun_test
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/spinframework/runtime-class-manager/internal/dryrun"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFs(t *testing.T) {
+	base := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(base, "/etc/containerd/config.toml", []byte("version = 2\n"), 0o644))
+	require.NoError(t, afero.WriteFile(base, "/opt/rcm/bin/containerd-shim-spin-v1", []byte("v1\x00"), 0o755))
+	require.NoError(t, afero.WriteFile(base, "/opt/rcm/bin/containerd-shim-slight-v1", []byte("v1\x00"), 0o755))
+	require.NoError(t, afero.WriteFile(base, "/opt/rcm/rcm-lock.json", []byte("{}"), 0o644))
+
+	fs := dryrun.New(base)
+	require.NoError(t, afero.WriteFile(fs, "/etc/containerd/config.toml", []byte("version = 2\n\n[plugins]\n"), 0o644))
+	require.NoError(t, afero.WriteFile(fs, "/opt/rcm/bin/containerd-shim-wws-v1", []byte("v1\x00"), 0o755))
+	require.NoError(t, fs.Remove("/opt/rcm/bin/containerd-shim-spin-v1"))
+	require.NoError(t, fs.Rename("/opt/rcm/bin/containerd-shim-slight-v1", "/opt/rcm/bin/containerd-shim-slight-v2"))
+	// Writing a file without changing it is no change
+	require.NoError(t, afero.WriteFile(fs, "/opt/rcm/rcm-lock.json", []byte("{}"), 0o644))
+
+	// Removed files are hidden
+	_, err := fs.Stat("/opt/rcm/bin/containerd-shim-spin-v1")
+	require.ErrorIs(t, err, afero.ErrFileNotFound)
+	names, err := afero.ReadDir(fs, "/opt/rcm/bin")
+	require.NoError(t, err)
+	require.Len(t, names, 2)
+	assert.Equal(t, "containerd-shim-slight-v2", names[0].Name())
+	assert.Equal(t, "containerd-shim-wws-v1", names[1].Name())
+
+	// Nothing is written to the base
+	config, err := afero.ReadFile(base, "/etc/containerd/config.toml")
+	require.NoError(t, err)
+	assert.Equal(t, "version = 2\n", string(config))
+	_, err = base.Stat("/opt/rcm/bin/containerd-shim-spin-v1")
+	require.NoError(t, err)
+	_, err = base.Stat("/opt/rcm/bin/containerd-shim-wws-v1")
+	require.ErrorIs(t, err, afero.ErrFileNotFound)
+
+	changes, err := fs.Changes()
+	require.NoError(t, err)
+	assert.Equal(t, []dryrun.Change{
+		{Path: "/etc/containerd/config.toml", Op: dryrun.OpUpdate, Before: []byte("version = 2\n"), After: []byte("version = 2\n\n[plugins]\n")},
+		{Path: "/opt/rcm/bin/containerd-shim-slight-v1", Op: dryrun.OpRemove, Before: []byte("v1\x00")},
+		{Path: "/opt/rcm/bin/containerd-shim-slight-v2", Op: dryrun.OpCreate, After: []byte("v1\x00")},
+		{Path: "/opt/rcm/bin/containerd-shim-spin-v1", Op: dryrun.OpRemove, Before: []byte("v1\x00")},
+		{Path: "/opt/rcm/bin/containerd-shim-wws-v1", Op: dryrun.OpCreate, After: []byte("v1\x00")},
+	}, changes)
+
+	var out bytes.Buffer
+	require.NoError(t, dryrun.Print(&out, changes))
+	assert.Equal(t, `update /etc/containerd/config.toml
+remove /opt/rcm/bin/containerd-shim-slight-v1
+create /opt/rcm/bin/containerd-shim-slight-v2
+remove /opt/rcm/bin/containerd-shim-spin-v1
+create /opt/rcm/bin/containerd-shim-wws-v1
+
+--- a/etc/containerd/config.toml
++++ b/etc/containerd/config.toml
+@@ -1 +1,3 @@
+ version = 2
++
++[plugins]
+`, out.String())
+}
+
+func TestFs_RemoveAndCreate(t *testing.T) {
+	base := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(base, "/etc/crio/crio.conf.d/99-rcm-spin-v1.conf", []byte("old"), 0o644))
+
+	fs := dryrun.New(base)
+	require.NoError(t, fs.RemoveAll("/etc/crio"))
+	_, err := fs.Stat("/etc/crio/crio.conf.d")
+	require.ErrorIs(t, err, afero.ErrFileNotFound)
+
+	// A file created again doesn't have its previous content
+	f, err := fs.OpenFile("/etc/crio/crio.conf.d/99-rcm-spin-v1.conf", os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	require.NoError(t, err)
+	_, err = f.WriteString("new")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	changes, err := fs.Changes()
+	require.NoError(t, err)
+	assert.Equal(t, []dryrun.Change{
+		{Path: "/etc/crio/crio.conf.d/99-rcm-spin-v1.conf", Op: dryrun.OpUpdate, Before: []byte("old"), After: []byte("new")},
+	}, changes)
+
+	var out bytes.Buffer
+	require.NoError(t, dryrun.Print(&out, nil))
+	assert.Equal(t, "no changes\n", out.String())
+}
@@ -0,0 +1,69 @@
+/*
+   Copyright The KWasm Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package health probes whether the container runtime of a node is serving.
+package health
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"time"
+)
+
+// DefaultInterval is the default interval between probes.
+const DefaultInterval = time.Second
+
+// healthyProbes is the number of consecutive successful probes after which a
+// socket is considered healthy. A single successful probe is not enough, as a
+// runtime failing to load its config may crash shortly after it started.
+const healthyProbes = 3
+
+// WaitForSocket probes a unix socket until it accepts connections, or ctx is
+// done.
+func WaitForSocket(ctx context.Context, socketPath string, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var dialer net.Dialer
+	successes := 0
+	var lastErr error
+	for {
+		conn, err := dialer.DialContext(ctx, "unix", socketPath)
+		if err == nil {
+			conn.Close()
+			successes++
+			if successes >= healthyProbes {
+				slog.Info("container runtime is healthy", "socket", socketPath)
+				return nil
+			}
+		} else {
+			slog.Debug("container runtime is not healthy yet", "socket", socketPath, "error", err)
+			successes = 0
+			lastErr = err
+		}
+
+		select {
+		case <-ctx.Done():
+			if lastErr == nil {
+				lastErr = ctx.Err()
+			}
+			return fmt.Errorf("socket %s is not healthy: %w", socketPath, lastErr)
+		case <-ticker.C:
+		}
+	}
+}
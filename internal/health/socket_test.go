@@ -0,0 +1,58 @@
+/*
+   Copyright The KWasm Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package health_test
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spinframework/runtime-class-manager/internal/health"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWaitForSocket(t *testing.T) {
+	t.Run("serving socket is healthy", func(t *testing.T) {
+		socketPath := filepath.Join(t.TempDir(), "containerd.sock")
+		listener, err := net.Listen("unix", socketPath)
+		require.NoError(t, err)
+		defer listener.Close()
+		go func() {
+			for {
+				conn, err := listener.Accept()
+				if err != nil {
+					return
+				}
+				conn.Close()
+			}
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		require.NoError(t, health.WaitForSocket(ctx, socketPath, 10*time.Millisecond))
+	})
+
+	t.Run("missing socket times out", func(t *testing.T) {
+		socketPath := filepath.Join(t.TempDir(), "containerd.sock")
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		require.Error(t, health.WaitForSocket(ctx, socketPath, 10*time.Millisecond))
+	})
+}
@@ -0,0 +1,291 @@
+/*
+   Copyright The KWasm Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package oci
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// Errors returned when the content of a reference can't be used. They are
+// wrapped by the errors returned from Client methods.
+var (
+	ErrNoPlatformMatch = errors.New("no matching platform")
+	ErrDigestMismatch  = errors.New("digest mismatch")
+	ErrInvalidLayer    = errors.New("invalid shim layer")
+)
+
+// maxManifestSize limits the size of manifests and indexes read into memory.
+const maxManifestSize = 4 << 20
+
+const (
+	mediaTypeDockerManifest     = "application/vnd.docker.distribution.manifest.v2+json"
+	mediaTypeDockerManifestList = "application/vnd.docker.distribution.manifest.list.v2+json"
+)
+
+var manifestMediaTypes = []string{
+	ocispec.MediaTypeImageIndex,
+	ocispec.MediaTypeImageManifest,
+	mediaTypeDockerManifestList,
+	mediaTypeDockerManifest,
+}
+
+// Client fetches content from OCI registries using the distribution API.
+// Registries requiring authentication are accessed with anonymous bearer tokens.
+type Client struct {
+	// HTTPClient is used for all requests. Defaults to http.DefaultClient.
+	HTTPClient *http.Client
+	// PlainHTTP connects to registries over HTTP instead of HTTPS.
+	PlainHTTP bool
+
+	token string
+}
+
+// ResolveManifest fetches the manifest of a reference. If the reference points
+// to an index, the manifest for the given platform is selected from it. The
+// content of each fetched manifest is verified against its digest.
+func (c *Client) ResolveManifest(ctx context.Context, ref Reference, platform ocispec.Platform) (ocispec.Manifest, error) {
+	mediaType, content, err := c.fetchManifest(ctx, ref, ref.manifestReference(), ref.Digest)
+	if err != nil {
+		return ocispec.Manifest{}, err
+	}
+
+	if mediaType == ocispec.MediaTypeImageIndex || mediaType == mediaTypeDockerManifestList {
+		var index ocispec.Index
+		if err := json.Unmarshal(content, &index); err != nil {
+			return ocispec.Manifest{}, fmt.Errorf("failed to decode index of %s: %w", ref, err)
+		}
+		desc, err := selectPlatform(index, platform)
+		if err != nil {
+			return ocispec.Manifest{}, fmt.Errorf("failed to resolve %s: %w", ref, err)
+		}
+		if _, content, err = c.fetchManifest(ctx, ref, desc.Digest.String(), desc.Digest); err != nil {
+			return ocispec.Manifest{}, err
+		}
+	}
+
+	var manifest ocispec.Manifest
+	if err := json.Unmarshal(content, &manifest); err != nil {
+		return ocispec.Manifest{}, fmt.Errorf("failed to decode manifest of %s: %w", ref, err)
+	}
+	return manifest, nil
+}
+
+// FetchBlob writes the content of a blob to w and verifies it against the
+// digest and size of its descriptor. Content written to w must not be used
+// if an error is returned.
+func (c *Client) FetchBlob(ctx context.Context, ref Reference, desc ocispec.Descriptor, w io.Writer) error {
+	resp, err := c.get(ctx, ref, "blobs/"+desc.Digest.String(), nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	verifier := desc.Digest.Verifier()
+	n, err := io.Copy(io.MultiWriter(w, verifier), io.LimitReader(resp.Body, desc.Size+1))
+	if err != nil {
+		return fmt.Errorf("failed to read blob %s: %w", desc.Digest, err)
+	}
+	if n != desc.Size {
+		return fmt.Errorf("%w: blob %s has size %d, expected %d", ErrDigestMismatch, desc.Digest, n, desc.Size)
+	}
+	if !verifier.Verified() {
+		return fmt.Errorf("%w: blob %s does not match its digest", ErrDigestMismatch, desc.Digest)
+	}
+	return nil
+}
+
+// fetchManifest fetches a manifest or index by tag or digest and returns its
+// media type and content. If expected is set, the content is verified against it.
+func (c *Client) fetchManifest(ctx context.Context, ref Reference, tagOrDigest string, expected digest.Digest) (string, []byte, error) {
+	resp, err := c.get(ctx, ref, "manifests/"+tagOrDigest, manifestMediaTypes)
+	if err != nil {
+		return "", nil, err
+	}
+	defer resp.Body.Close()
+
+	content, err := io.ReadAll(io.LimitReader(resp.Body, maxManifestSize+1))
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read manifest %s: %w", tagOrDigest, err)
+	}
+	if len(content) > maxManifestSize {
+		return "", nil, fmt.Errorf("manifest %s exceeds %d bytes", tagOrDigest, maxManifestSize)
+	}
+	if expected != "" && expected.Algorithm().FromBytes(content) != expected {
+		return "", nil, fmt.Errorf("%w: manifest %s does not match digest %s", ErrDigestMismatch, tagOrDigest, expected)
+	}
+
+	// Registries are not required to return the media type in the header,
+	// but manifests and indexes carry it in their content.
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if !isManifestMediaType(mediaType) {
+		var versioned struct {
+			MediaType string `json:"mediaType"`
+		}
+		if err := json.Unmarshal(content, &versioned); err != nil {
+			return "", nil, fmt.Errorf("failed to decode manifest %s: %w", tagOrDigest, err)
+		}
+		mediaType = versioned.MediaType
+	}
+	return mediaType, content, nil
+}
+
+// get sends a GET request for a path below /v2/<repository>/. If the registry
+// asks for a bearer token, an anonymous token is requested and the request
+// is retried once.
+func (c *Client) get(ctx context.Context, ref Reference, path string, accept []string) (*http.Response, error) {
+	scheme := "https"
+	if c.PlainHTTP {
+		scheme = "http"
+	}
+	endpoint := fmt.Sprintf("%s://%s/v2/%s/%s", scheme, ref.host(), ref.Repository, path)
+
+	resp, err := c.do(ctx, endpoint, accept)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		challenge := resp.Header.Get("WWW-Authenticate")
+		resp.Body.Close()
+		if err := c.fetchToken(ctx, ref, challenge); err != nil {
+			return nil, err
+		}
+		if resp, err = c.do(ctx, endpoint, accept); err != nil {
+			return nil, err
+		}
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("GET %s: unexpected status %s", endpoint, resp.Status)
+	}
+	return resp, nil
+}
+
+func (c *Client) do(ctx context.Context, endpoint string, accept []string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(accept) > 0 {
+		req.Header.Set("Accept", strings.Join(accept, ", "))
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("GET %s: %w", endpoint, err)
+	}
+	return resp, nil
+}
+
+// fetchToken requests an anonymous bearer token as described by a
+// WWW-Authenticate challenge of a registry.
+func (c *Client) fetchToken(ctx context.Context, ref Reference, challenge string) error {
+	scheme, params := parseChallenge(challenge)
+	if !strings.EqualFold(scheme, "bearer") || params["realm"] == "" {
+		return fmt.Errorf("registry %s requires unsupported authentication %q", ref.Registry, challenge)
+	}
+
+	realm, err := url.Parse(params["realm"])
+	if err != nil {
+		return fmt.Errorf("invalid token realm %q: %w", params["realm"], err)
+	}
+	query := realm.Query()
+	if service := params["service"]; service != "" {
+		query.Set("service", service)
+	}
+	scope := params["scope"]
+	if scope == "" {
+		scope = "repository:" + ref.Repository + ":pull"
+	}
+	query.Set("scope", scope)
+	realm.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch token from %s: %w", realm.Host, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to fetch token from %s: unexpected status %s", realm.Host, resp.Status)
+	}
+
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return fmt.Errorf("failed to decode token: %w", err)
+	}
+	c.token = token.Token
+	if c.token == "" {
+		c.token = token.AccessToken
+	}
+	if c.token == "" {
+		return errors.New("token endpoint returned no token")
+	}
+	return nil
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+// parseChallenge parses a WWW-Authenticate header of the form
+// `Bearer realm="...",service="...",scope="..."`.
+func parseChallenge(challenge string) (string, map[string]string) {
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(challenge), " ")
+	params := map[string]string{}
+	for rest != "" {
+		var key, value string
+		key, rest, _ = strings.Cut(strings.TrimLeft(rest, ", "), "=")
+		if strings.HasPrefix(rest, `"`) {
+			value, rest, _ = strings.Cut(rest[1:], `"`)
+		} else {
+			value, rest, _ = strings.Cut(rest, ",")
+		}
+		params[strings.ToLower(strings.TrimSpace(key))] = value
+	}
+	return scheme, params
+}
+
+func isManifestMediaType(mediaType string) bool {
+	for _, t := range manifestMediaTypes {
+		if mediaType == t {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,100 @@
+/*
+   Copyright The KWasm Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package oci
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/spinframework/runtime-class-manager/internal/archive"
+)
+
+const shimPrefix = "containerd-shim-"
+
+// Pull fetches the shim binary of a reference for a platform and writes it to
+// dest. The binary is taken from the layer annotated with a title starting with
+// "containerd-shim-", or from the only layer of the manifest. Layers that are
+// archives are searched for the binary. The layer is verified against its
+// digest before dest is written.
+func (c *Client) Pull(ctx context.Context, ref Reference, platform ocispec.Platform, dest string) (ocispec.Descriptor, error) {
+	manifest, err := c.ResolveManifest(ctx, ref, platform)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	binaryName := filepath.Base(dest)
+	layer, err := selectLayer(manifest, binaryName)
+	if err != nil {
+		return ocispec.Descriptor{}, fmt.Errorf("%w: failed to select shim layer of %s: %w", ErrInvalidLayer, ref, err)
+	}
+	slog.Info("fetching layer", "reference", ref.String(), "digest", layer.Digest, "mediaType", layer.MediaType, "size", layer.Size)
+
+	blob, err := os.CreateTemp(filepath.Dir(dest), ".blob-*")
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	defer os.Remove(blob.Name())
+	defer blob.Close()
+
+	if err := c.FetchBlob(ctx, ref, layer, blob); err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	if _, err := archive.ExtractShim(blob, dest); err != nil {
+		return ocispec.Descriptor{}, fmt.Errorf("%w: %w", ErrInvalidLayer, err)
+	}
+	return layer, nil
+}
+
+// selectPlatform selects the manifest of an index matching a platform.
+// Variants are ignored, as nodes report only their OS and architecture.
+func selectPlatform(index ocispec.Index, platform ocispec.Platform) (ocispec.Descriptor, error) {
+	for _, desc := range index.Manifests {
+		if desc.Platform != nil && desc.Platform.OS == platform.OS && desc.Platform.Architecture == platform.Architecture {
+			return desc, nil
+		}
+	}
+	return ocispec.Descriptor{}, fmt.Errorf("%w: no manifest for platform %s/%s", ErrNoPlatformMatch, platform.OS, platform.Architecture)
+}
+
+// selectLayer selects the layer containing the shim binary.
+func selectLayer(manifest ocispec.Manifest, binaryName string) (ocispec.Descriptor, error) {
+	var titled []ocispec.Descriptor
+	for _, layer := range manifest.Layers {
+		title := layer.Annotations[ocispec.AnnotationTitle]
+		if title == binaryName {
+			return layer, nil
+		}
+		if strings.HasPrefix(title, shimPrefix) {
+			titled = append(titled, layer)
+		}
+	}
+
+	switch {
+	case len(titled) == 1:
+		return titled[0], nil
+	case len(titled) > 1:
+		return ocispec.Descriptor{}, fmt.Errorf("found %d layers titled %s*, expected %s", len(titled), shimPrefix, binaryName)
+	case len(manifest.Layers) == 1:
+		return manifest.Layers[0], nil
+	default:
+		return ocispec.Descriptor{}, fmt.Errorf("manifest has %d layers, annotate the shim layer with %s", len(manifest.Layers), ocispec.AnnotationTitle)
+	}
+}
@@ -0,0 +1,189 @@
+/*
+   Copyright The KWasm Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package oci //nolint:testpackage // whitebox test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	linuxAmd64 = ocispec.Platform{OS: "linux", Architecture: "amd64"}
+	linuxArm64 = ocispec.Platform{OS: "linux", Architecture: "arm64"}
+)
+
+// pushMultiArchShim stores an index with a raw shim binary per platform.
+func pushMultiArchShim(t *testing.T, registry *testRegistry, tag string) ocispec.Descriptor {
+	t.Helper()
+	var manifests []ocispec.Descriptor
+	for _, platform := range []ocispec.Platform{linuxAmd64, linuxArm64} {
+		layer := registry.pushBlob("application/vnd.spinkube.shim.binary", []byte("shim for "+platform.Architecture),
+			map[string]string{ocispec.AnnotationTitle: "containerd-shim-spin-v2"})
+		desc := registry.pushImage(t, []ocispec.Descriptor{layer})
+		desc.Platform = &platform
+		manifests = append(manifests, desc)
+	}
+	index := ocispec.Index{MediaType: ocispec.MediaTypeImageIndex, Manifests: manifests}
+	index.SchemaVersion = 2
+	return registry.pushManifest(t, ocispec.MediaTypeImageIndex, index, tag)
+}
+
+func TestClient_Pull(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("selects the platform from an index", func(t *testing.T) {
+		registry := newTestRegistry(t, "shims/spin")
+		pushMultiArchShim(t, registry, "v1")
+		dest := filepath.Join(t.TempDir(), "containerd-shim-spin-v2")
+
+		client := &Client{PlainHTTP: true}
+		_, err := client.Pull(ctx, registry.reference("v1"), linuxArm64, dest)
+		require.NoError(t, err)
+
+		content, err := os.ReadFile(dest)
+		require.NoError(t, err)
+		assert.Equal(t, "shim for arm64", string(content))
+		info, err := os.Stat(dest)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0o755), info.Mode().Perm())
+	})
+
+	t.Run("pulls by digest", func(t *testing.T) {
+		registry := newTestRegistry(t, "shims/spin")
+		index := pushMultiArchShim(t, registry, "v1")
+		dest := filepath.Join(t.TempDir(), "containerd-shim-spin-v2")
+
+		client := &Client{PlainHTTP: true}
+		_, err := client.Pull(ctx, registry.reference(index.Digest.String()), linuxAmd64, dest)
+		require.NoError(t, err)
+
+		content, err := os.ReadFile(dest)
+		require.NoError(t, err)
+		assert.Equal(t, "shim for amd64", string(content))
+	})
+
+	t.Run("extracts the shim from an image layer", func(t *testing.T) {
+		registry := newTestRegistry(t, "shims/spin")
+		layer := registry.pushBlob(ocispec.MediaTypeImageLayerGzip, tarGz(t, map[string]string{
+			"README.md":                   "readme",
+			"bin/containerd-shim-spin-v2": "shim binary",
+		}), nil)
+		registry.pushImage(t, []ocispec.Descriptor{layer}, "v1")
+		dest := filepath.Join(t.TempDir(), "containerd-shim-spin-v2")
+
+		client := &Client{PlainHTTP: true}
+		_, err := client.Pull(ctx, registry.reference("v1"), linuxAmd64, dest)
+		require.NoError(t, err)
+
+		content, err := os.ReadFile(dest)
+		require.NoError(t, err)
+		assert.Equal(t, "shim binary", string(content))
+	})
+
+	t.Run("authenticates with an anonymous token", func(t *testing.T) {
+		registry := newTestRegistry(t, "shims/spin")
+		registry.token = "secret"
+		pushMultiArchShim(t, registry, "v1")
+		dest := filepath.Join(t.TempDir(), "containerd-shim-spin-v2")
+
+		client := &Client{PlainHTTP: true}
+		_, err := client.Pull(ctx, registry.reference("v1"), linuxAmd64, dest)
+		require.NoError(t, err)
+	})
+
+	t.Run("rejects a manifest not matching the pinned digest", func(t *testing.T) {
+		registry := newTestRegistry(t, "shims/spin")
+		index := pushMultiArchShim(t, registry, "v1")
+		other := digest.FromString("other")
+		registry.manifests[other.String()] = registry.manifests[index.Digest.String()]
+		registry.mediaTypes[other.String()] = ocispec.MediaTypeImageIndex
+		dest := filepath.Join(t.TempDir(), "containerd-shim-spin-v2")
+
+		client := &Client{PlainHTTP: true}
+		_, err := client.Pull(ctx, registry.reference(other.String()), linuxAmd64, dest)
+		require.ErrorContains(t, err, "does not match digest")
+		require.ErrorIs(t, err, ErrDigestMismatch)
+		assert.NoFileExists(t, dest)
+	})
+
+	t.Run("rejects a layer not matching its digest", func(t *testing.T) {
+		registry := newTestRegistry(t, "shims/spin")
+		layer := registry.pushBlob("application/octet-stream", []byte("shim binary"), nil)
+		registry.blobs[layer.Digest] = []byte("evil binary")
+		registry.pushImage(t, []ocispec.Descriptor{layer}, "v1")
+		dest := filepath.Join(t.TempDir(), "containerd-shim-spin-v2")
+
+		client := &Client{PlainHTTP: true}
+		_, err := client.Pull(ctx, registry.reference("v1"), linuxAmd64, dest)
+		require.ErrorContains(t, err, "does not match its digest")
+		require.ErrorIs(t, err, ErrDigestMismatch)
+		assert.NoFileExists(t, dest)
+	})
+
+	t.Run("fails without a matching platform", func(t *testing.T) {
+		registry := newTestRegistry(t, "shims/spin")
+		pushMultiArchShim(t, registry, "v1")
+		dest := filepath.Join(t.TempDir(), "containerd-shim-spin-v2")
+
+		client := &Client{PlainHTTP: true}
+		_, err := client.Pull(ctx, registry.reference("v1"), ocispec.Platform{OS: "linux", Architecture: "riscv64"}, dest)
+		require.ErrorContains(t, err, "no manifest for platform linux/riscv64")
+		require.ErrorIs(t, err, ErrNoPlatformMatch)
+	})
+}
+
+func TestSelectLayer(t *testing.T) {
+	raw := func(title string) ocispec.Descriptor {
+		desc := ocispec.Descriptor{Digest: digest.FromString(title)}
+		if title != "" {
+			desc.Annotations = map[string]string{ocispec.AnnotationTitle: title}
+		}
+		return desc
+	}
+
+	tests := []struct {
+		name    string
+		layers  []ocispec.Descriptor
+		want    ocispec.Descriptor
+		wantErr bool
+	}{
+		{"single layer", []ocispec.Descriptor{raw("")}, raw(""), false},
+		{"exact title", []ocispec.Descriptor{raw("containerd-shim-other"), raw("containerd-shim-spin-v2")}, raw("containerd-shim-spin-v2"), false},
+		{"single shim title", []ocispec.Descriptor{raw("README.md"), raw("containerd-shim-spin")}, raw("containerd-shim-spin"), false},
+		{"ambiguous titles", []ocispec.Descriptor{raw("containerd-shim-a"), raw("containerd-shim-b")}, ocispec.Descriptor{}, true},
+		{"multiple untitled layers", []ocispec.Descriptor{raw(""), raw("README.md")}, ocispec.Descriptor{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := selectLayer(ocispec.Manifest{Layers: tt.layers}, "containerd-shim-spin-v2")
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want.Digest, got.Digest)
+		})
+	}
+}
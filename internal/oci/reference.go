@@ -0,0 +1,123 @@
+/*
+   Copyright The KWasm Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package oci
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/opencontainers/go-digest"
+)
+
+const (
+	defaultRegistry = "docker.io"
+	// dockerHubRegistry is the host serving the registry API for docker.io.
+	dockerHubRegistry = "registry-1.docker.io"
+	defaultTag        = "latest"
+)
+
+var (
+	repositoryRegexp = regexp.MustCompile(`^[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*(?:/[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*)*$`)
+	tagRegexp        = regexp.MustCompile(`^\w[\w.-]{0,127}$`)
+)
+
+// Reference identifies an image or artifact in an OCI registry.
+type Reference struct {
+	// Registry is the host (and optional port) of the registry.
+	Registry string
+	// Repository is the name of the repository within the registry.
+	Repository string
+	// Tag is the tag of the manifest. It is ignored if Digest is set.
+	Tag string
+	// Digest pins the reference to a manifest or index with this digest.
+	Digest digest.Digest
+}
+
+// ParseReference parses references of the form
+// [registry/]repository[:tag][@digest]. References without a registry refer
+// to Docker Hub, references without tag and digest to the latest tag.
+func ParseReference(s string) (Reference, error) {
+	var ref Reference
+
+	name := s
+	if i := strings.Index(name, "@"); i >= 0 {
+		dgst, err := digest.Parse(name[i+1:])
+		if err != nil {
+			return Reference{}, fmt.Errorf("invalid digest in reference %q: %w", s, err)
+		}
+		ref.Digest = dgst
+		name = name[:i]
+	}
+
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		ref.Tag = name[i+1:]
+		name = name[:i]
+		if !tagRegexp.MatchString(ref.Tag) {
+			return Reference{}, fmt.Errorf("invalid tag %q in reference %q", ref.Tag, s)
+		}
+	}
+
+	// The first path component is a registry if it looks like a host name.
+	ref.Registry = defaultRegistry
+	if i := strings.Index(name, "/"); i >= 0 {
+		if host := name[:i]; strings.ContainsAny(host, ".:") || host == "localhost" {
+			ref.Registry = host
+			name = name[i+1:]
+		}
+	}
+	if ref.Registry == defaultRegistry && !strings.Contains(name, "/") {
+		name = "library/" + name
+	}
+	ref.Repository = name
+	if !repositoryRegexp.MatchString(ref.Repository) {
+		return Reference{}, fmt.Errorf("invalid repository %q in reference %q", ref.Repository, s)
+	}
+
+	if ref.Tag == "" && ref.Digest == "" {
+		ref.Tag = defaultTag
+	}
+	return ref, nil
+}
+
+// String returns the reference in the form registry/repository[:tag][@digest].
+func (r Reference) String() string {
+	s := r.Registry + "/" + r.Repository
+	if r.Tag != "" {
+		s += ":" + r.Tag
+	}
+	if r.Digest != "" {
+		s += "@" + r.Digest.String()
+	}
+	return s
+}
+
+// manifestReference returns the tag or digest used to fetch the manifest.
+func (r Reference) manifestReference() string {
+	if r.Digest != "" {
+		return r.Digest.String()
+	}
+	return r.Tag
+}
+
+// host returns the host serving the registry API.
+func (r Reference) host() string {
+	if r.Registry == defaultRegistry {
+		return dockerHubRegistry
+	}
+	return r.Registry
+}
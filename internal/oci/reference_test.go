@@ -0,0 +1,66 @@
+/*
+   Copyright The KWasm Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package oci //nolint:testpackage // whitebox test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseReference(t *testing.T) {
+	const dgst = "sha256:eec8df96fa3a4fe5dbc57ffb706461692c055299dd6d22dff9444863a2a12281"
+
+	tests := []struct {
+		ref     string
+		want    Reference
+		wantErr bool
+	}{
+		{"registry.example.com/shims/spin:v1", Reference{Registry: "registry.example.com", Repository: "shims/spin", Tag: "v1"}, false},
+		{"localhost:5000/spin", Reference{Registry: "localhost:5000", Repository: "spin", Tag: "latest"}, false},
+		{"registry.example.com/spin@" + dgst, Reference{Registry: "registry.example.com", Repository: "spin", Digest: dgst}, false},
+		{"registry.example.com/spin:v1@" + dgst, Reference{Registry: "registry.example.com", Repository: "spin", Tag: "v1", Digest: dgst}, false},
+		{"spin", Reference{Registry: "docker.io", Repository: "library/spin", Tag: "latest"}, false},
+		{"spinframework/spin:v1", Reference{Registry: "docker.io", Repository: "spinframework/spin", Tag: "v1"}, false},
+		{"registry.example.com/Spin:v1", Reference{}, true},
+		{"registry.example.com/spin@sha256:abc", Reference{}, true},
+		{"registry.example.com/spin:", Reference{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			got, err := ParseReference(tt.ref)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseChallenge(t *testing.T) {
+	scheme, params := parseChallenge(`Bearer realm="https://auth.example.com/token",service="registry.example.com",scope="repository:shims/spin:pull,push"`)
+	assert.Equal(t, "Bearer", scheme)
+	assert.Equal(t, map[string]string{
+		"realm":   "https://auth.example.com/token",
+		"service": "registry.example.com",
+		"scope":   "repository:shims/spin:pull,push",
+	}, params)
+}
@@ -0,0 +1,158 @@
+/*
+   Copyright The KWasm Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package oci //nolint:testpackage // whitebox test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/require"
+)
+
+// testRegistry is a minimal stand-in for an OCI registry serving manifests
+// and blobs of a single repository from memory.
+type testRegistry struct {
+	repository string
+	manifests  map[string][]byte
+	mediaTypes map[string]string
+	blobs      map[digest.Digest][]byte
+	// token enables bearer authentication if set.
+	token string
+
+	server *httptest.Server
+}
+
+func newTestRegistry(t *testing.T, repository string) *testRegistry {
+	t.Helper()
+	r := &testRegistry{
+		repository: repository,
+		manifests:  map[string][]byte{},
+		mediaTypes: map[string]string{},
+		blobs:      map[digest.Digest][]byte{},
+	}
+	r.server = httptest.NewServer(http.HandlerFunc(r.serve))
+	t.Cleanup(r.server.Close)
+	return r
+}
+
+// reference returns a reference to the repository of the registry.
+func (r *testRegistry) reference(tagOrDigest string) Reference {
+	ref := Reference{Registry: strings.TrimPrefix(r.server.URL, "http://"), Repository: r.repository}
+	if dgst, err := digest.Parse(tagOrDigest); err == nil {
+		ref.Digest = dgst
+	} else {
+		ref.Tag = tagOrDigest
+	}
+	return ref
+}
+
+func (r *testRegistry) serve(w http.ResponseWriter, req *http.Request) {
+	if req.URL.Path == "/token" {
+		_ = json.NewEncoder(w).Encode(map[string]string{"token": r.token})
+		return
+	}
+	if r.token != "" && req.Header.Get("Authorization") != "Bearer "+r.token {
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="test"`, r.server.URL))
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	prefix := "/v2/" + r.repository + "/"
+	path, ok := strings.CutPrefix(req.URL.Path, prefix)
+	if !ok {
+		http.NotFound(w, req)
+		return
+	}
+	if ref, ok := strings.CutPrefix(path, "manifests/"); ok {
+		content, ok := r.manifests[ref]
+		if !ok {
+			http.NotFound(w, req)
+			return
+		}
+		w.Header().Set("Content-Type", r.mediaTypes[ref])
+		_, _ = w.Write(content)
+		return
+	}
+	if dgst, ok := strings.CutPrefix(path, "blobs/"); ok {
+		content, ok := r.blobs[digest.Digest(dgst)]
+		if !ok {
+			http.NotFound(w, req)
+			return
+		}
+		_, _ = w.Write(content)
+		return
+	}
+	http.NotFound(w, req)
+}
+
+// pushBlob stores a blob and returns its descriptor.
+func (r *testRegistry) pushBlob(mediaType string, content []byte, annotations map[string]string) ocispec.Descriptor {
+	dgst := digest.FromBytes(content)
+	r.blobs[dgst] = content
+	return ocispec.Descriptor{MediaType: mediaType, Digest: dgst, Size: int64(len(content)), Annotations: annotations}
+}
+
+// pushManifest stores a manifest or index under its digest and the given tags.
+func (r *testRegistry) pushManifest(t *testing.T, mediaType string, v any, tags ...string) ocispec.Descriptor {
+	t.Helper()
+	content, err := json.Marshal(v)
+	require.NoError(t, err)
+	dgst := digest.FromBytes(content)
+	for _, ref := range append(tags, dgst.String()) {
+		r.manifests[ref] = content
+		r.mediaTypes[ref] = mediaType
+	}
+	return ocispec.Descriptor{MediaType: mediaType, Digest: dgst, Size: int64(len(content))}
+}
+
+// pushImage stores a manifest with the given layers.
+func (r *testRegistry) pushImage(t *testing.T, layers []ocispec.Descriptor, tags ...string) ocispec.Descriptor {
+	t.Helper()
+	config := r.pushBlob(ocispec.MediaTypeImageConfig, []byte("{}"), nil)
+	manifest := ocispec.Manifest{
+		MediaType: ocispec.MediaTypeImageManifest,
+		Config:    config,
+		Layers:    layers,
+	}
+	manifest.SchemaVersion = 2
+	return r.pushManifest(t, ocispec.MediaTypeImageManifest, manifest, tags...)
+}
+
+// tarGz returns a gzip-compressed tar archive of the given files.
+func tarGz(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0o755, Size: int64(len(content)), Typeflag: tar.TypeReg}))
+		_, err := tw.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+	return buf.Bytes()
+}
@@ -8,10 +8,25 @@ import (
 
 	"github.com/spf13/afero"
 	"github.com/spinframework/runtime-class-manager/internal/containerd"
+	"github.com/spinframework/runtime-class-manager/internal/crio"
+)
+
+// Container runtimes shims can be configured for.
+const (
+	ContainerRuntimeContainerd = "containerd"
+	ContainerRuntimeCRIO       = "cri-o"
 )
 
 type Settings struct {
+	// Name identifies the distro the settings are for.
+	Name string
+	// ContainerRuntime is the container runtime running on the node.
+	ContainerRuntime string
+	// ConfigPath is the path of the containerd config, or the drop-in
+	// directory of CRI-O.
 	ConfigPath string
+	// SocketPath is the path of the socket the container runtime serves on.
+	SocketPath string
 	Setup      func(Env) error
 	Restarter  containerd.Restarter
 }
@@ -22,9 +37,17 @@ type Env struct {
 }
 
 var Default = Settings{
-	ConfigPath: "/etc/containerd/config.toml",
-	Setup:      func(_ Env) error { return nil },
-	Restarter:  containerd.NewDefaultRestarter(),
+	Name:             "default",
+	ContainerRuntime: ContainerRuntimeContainerd,
+	ConfigPath:       "/etc/containerd/config.toml",
+	SocketPath:       "/run/containerd/containerd.sock",
+	Setup:            func(_ Env) error { return nil },
+	Restarter:        containerd.NewDefaultRestarter(),
+}
+
+func (s Settings) WithName(name string) Settings {
+	s.Name = name
+	return s
 }
 
 func (s Settings) WithConfigPath(path string) Settings {
@@ -32,6 +55,11 @@ func (s Settings) WithConfigPath(path string) Settings {
 	return s
 }
 
+func (s Settings) WithSocketPath(path string) Settings {
+	s.SocketPath = path
+	return s
+}
+
 func (s Settings) WithSetup(setup func(env Env) error) Settings {
 	s.Setup = setup
 	return s
@@ -42,10 +70,12 @@ func (s Settings) WithRestarter(restarter containerd.Restarter) Settings {
 	return s
 }
 
-var MicroK8s = Default.WithConfigPath("/var/snap/microk8s/current/args/containerd-template.toml").
+var MicroK8s = Default.WithName("microk8s").WithConfigPath("/var/snap/microk8s/current/args/containerd-template.toml").
+	WithSocketPath("/var/snap/microk8s/common/run/containerd.sock").
 	WithRestarter(containerd.MicroK8sRestarter{})
 
-var RKE2 = Default.WithConfigPath("/var/lib/rancher/rke2/agent/etc/containerd/config.toml.tmpl").
+var RKE2 = Default.WithName("rke2").WithConfigPath("/var/lib/rancher/rke2/agent/etc/containerd/config.toml.tmpl").
+	WithSocketPath("/run/k3s/containerd/containerd.sock").
 	WithRestarter(containerd.RKE2Restarter{}).
 	WithSetup(func(env Env) error {
 		_, err := env.HostFs.Stat(env.ConfigPath)
@@ -82,10 +112,11 @@ var RKE2 = Default.WithConfigPath("/var/lib/rancher/rke2/agent/etc/containerd/co
 		return err
 	})
 
-var K3s = RKE2.WithConfigPath("/var/lib/rancher/k3s/agent/etc/containerd/config.toml.tmpl").
+var K3s = RKE2.WithName("k3s").WithConfigPath("/var/lib/rancher/k3s/agent/etc/containerd/config.toml.tmpl").
 	WithRestarter(containerd.K3sRestarter{})
 
-var K0s = Default.WithConfigPath("/etc/k0s/containerd.d/config.toml").
+var K0s = Default.WithName("k0s").WithConfigPath("/etc/k0s/containerd.d/config.toml").
+	WithSocketPath("/run/k0s/containerd.sock").
 	WithRestarter(containerd.K0sRestarter{}).
 	WithSetup(func(env Env) error {
 		_, err := env.HostFs.Stat(env.ConfigPath)
@@ -103,3 +134,15 @@ var K0s = Default.WithConfigPath("/etc/k0s/containerd.d/config.toml").
 
 		return err
 	})
+
+// CRIO configures shims as runtimes of CRI-O in drop-in files.
+var CRIO = Settings{
+	Name:             "cri-o",
+	ContainerRuntime: ContainerRuntimeCRIO,
+	ConfigPath:       crio.DefaultDropInDir,
+	SocketPath:       "/var/run/crio/crio.sock",
+	Setup: func(env Env) error {
+		return env.HostFs.MkdirAll(env.ConfigPath, 0o755) //nolint:mnd // file permissions
+	},
+	Restarter: crio.Restarter{},
+}
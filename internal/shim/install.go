@@ -17,51 +17,101 @@
 package shim
 
 import (
+	"bytes"
 	"crypto/sha256"
+	"fmt"
 	"io"
 	"os"
 	"path"
 	"path/filepath"
+	"time"
 
+	"github.com/spf13/afero"
 	"github.com/spinframework/runtime-class-manager/internal/state"
 )
 
+// Install copies a shim binary from the asset path to the host and records it
+// in the lock file. It returns the path of the binary and whether the
+// installed version of the shim changed.
 func (c *Config) Install(shimName string) (filePath string, changed bool, err error) {
-	shimPath := filepath.Join(c.assetPath, shimName)
-	srcFile, err := c.rootFs.OpenFile(shimPath, os.O_RDONLY, 0o000) //nolint:mnd // file permissions
+	shimSha256, err := c.AssetDigest(shimName)
 	if err != nil {
 		return "", false, err
 	}
-	dstFilePath := path.Join(c.rcmPath, "bin", shimName)
+	dstFilePath := c.BinPath(shimName, shimSha256)
 
-	err = c.hostFs.MkdirAll(path.Dir(dstFilePath), 0o775) //nolint:mnd // file permissions
-	if err != nil {
-		return dstFilePath, false, err
+	// A binary that is installed already is left untouched, as running pods
+	// may execute it
+	if digest, err := fileDigest(c.hostFs, dstFilePath); err != nil || !bytes.Equal(digest, shimSha256) {
+		if err := c.copyAsset(shimName, dstFilePath, shimSha256); err != nil {
+			return dstFilePath, false, err
+		}
 	}
 
-	dstFile, err := c.hostFs.OpenFile(dstFilePath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o755) //nolint:mnd // file permissions
+	unlock, err := state.Lock(c.hostFs, c.rcmPath)
 	if err != nil {
 		return "", false, err
 	}
+	defer unlock() //nolint:errcheck // the lock is released when the file is closed in any case
 
 	st, err := state.Get(c.hostFs, c.rcmPath)
 	if err != nil {
 		return "", false, err
 	}
-	shimSha256 := sha256.New()
-
-	_, err = io.Copy(io.MultiWriter(dstFile, shimSha256), srcFile)
 	runtimeName := RuntimeName(shimName)
-	changed = st.ShimChanged(runtimeName, shimSha256.Sum(nil), dstFilePath)
+	changed = st.ShimChanged(runtimeName, shimSha256, dstFilePath)
 	if changed {
 		st.UpdateShim(runtimeName, state.Shim{
 			Path:   dstFilePath,
-			Sha256: shimSha256.Sum(nil),
-		})
+			Sha256: shimSha256,
+		}, time.Now())
 		if err := st.Write(); err != nil {
 			return "", false, err
 		}
 	}
 
-	return dstFilePath, changed, err
+	return dstFilePath, changed, nil
+}
+
+// copyAsset copies a shim binary to dstFilePath. It is copied to a temporary
+// file in the same directory first, which replaces dstFilePath once it is
+// complete and matches the digest want, so that the binary is never executed while it
+// is half-written.
+func (c *Config) copyAsset(shimName, dstFilePath string, want []byte) error {
+	srcFile, err := c.rootFs.OpenFile(filepath.Join(c.assetPath, shimName), os.O_RDONLY, 0o000) //nolint:mnd // file permissions
+	if err != nil {
+		return err
+	}
+	defer srcFile.Close()
+
+	err = c.hostFs.MkdirAll(path.Dir(dstFilePath), 0o775) //nolint:mnd // file permissions
+	if err != nil {
+		return err
+	}
+
+	tmpFile, err := afero.TempFile(c.hostFs, path.Dir(dstFilePath), "."+shimName+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer c.hostFs.Remove(tmpFile.Name()) //nolint:errcheck // the file is gone after it has been renamed
+
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(tmpFile, h), srcFile); err != nil {
+		tmpFile.Close()
+		return err
+	}
+	if err := tmpFile.Sync(); err != nil {
+		tmpFile.Close()
+		return err
+	}
+	if err := tmpFile.Close(); err != nil {
+		return err
+	}
+	if digest := h.Sum(nil); !bytes.Equal(digest, want) {
+		return fmt.Errorf("digest of copied shim binary is %x, expected %x", digest, want)
+	}
+	if err := c.hostFs.Chmod(tmpFile.Name(), 0o755); err != nil { //nolint:mnd // file permissions
+		return err
+	}
+	return c.hostFs.Rename(tmpFile.Name(), dstFilePath)
 }
@@ -17,7 +17,9 @@
 package shim //nolint:testpackage // whitebox test
 
 import (
+	"encoding/hex"
 	"testing"
+	"time"
 
 	"github.com/spf13/afero"
 	tests "github.com/spinframework/runtime-class-manager/tests/node-installer"
@@ -56,7 +58,7 @@ func TestConfig_Install(t *testing.T) {
 			},
 			args{"containerd-shim-wasmtime-v1"},
 			wants{
-				"/opt/rcm/bin/containerd-shim-wasmtime-v1",
+				"/opt/rcm/bin/wasmtime-v1/5064503673b33772f86aa61d085e01aabcebf319cff1697a8e0c968d8ef35934/containerd-shim-wasmtime-v1",
 				true,
 			},
 			false,
@@ -65,17 +67,47 @@ func TestConfig_Install(t *testing.T) {
 			"no changes to shim",
 			fields{
 				tests.FixtureFs("../../testdata/node-installer"),
-				tests.FixtureFs("../../testdata/node-installer/shim"),
+				tests.FixtureFs("../../testdata/node-installer/shim-versioned"),
 				"/assets",
 				"/opt/rcm",
 			},
 			args{"containerd-shim-spin-v1"},
 			wants{
-				"/opt/rcm/bin/containerd-shim-spin-v1",
+				"/opt/rcm/bin/spin-v1/6da5e8f17a9bfa9cb04cf22c87b6475394ecec3af4fdc337f72d6dbf3319ea52/containerd-shim-spin-v1",
 				false,
 			},
 			false,
 		},
+		{
+			"move installed shim from legacy path",
+			fields{
+				tests.FixtureFs("../../testdata/node-installer"),
+				tests.FixtureFs("../../testdata/node-installer/shim"),
+				"/assets",
+				"/opt/rcm",
+			},
+			args{"containerd-shim-spin-v1"},
+			wants{
+				"/opt/rcm/bin/spin-v1/6da5e8f17a9bfa9cb04cf22c87b6475394ecec3af4fdc337f72d6dbf3319ea52/containerd-shim-spin-v1",
+				true,
+			},
+			false,
+		},
+		{
+			"move shim from legacy path",
+			fields{
+				tests.FixtureFs("../../testdata/node-installer"),
+				tests.FixtureFs("../../testdata/node-installer/shim-missing-binary"),
+				"/assets",
+				"/opt/rcm",
+			},
+			args{"containerd-shim-spin-v1"},
+			wants{
+				"/opt/rcm/bin/spin-v1/6da5e8f17a9bfa9cb04cf22c87b6475394ecec3af4fdc337f72d6dbf3319ea52/containerd-shim-spin-v1",
+				true,
+			},
+			false,
+		},
 		{
 			"install new shim over old",
 			fields{
@@ -86,7 +118,7 @@ func TestConfig_Install(t *testing.T) {
 			},
 			args{"containerd-shim-wasmtime-v1"},
 			wants{
-				"/opt/rcm/bin/containerd-shim-wasmtime-v1",
+				"/opt/rcm/bin/wasmtime-v1/5064503673b33772f86aa61d085e01aabcebf319cff1697a8e0c968d8ef35934/containerd-shim-wasmtime-v1",
 				true,
 			},
 			false,
@@ -116,7 +148,7 @@ func TestConfig_Install(t *testing.T) {
 			},
 			args{"containerd-shim-spin-v1"},
 			wants{
-				"/opt/rcm/bin/containerd-shim-spin-v1",
+				"/opt/rcm/bin/spin-v1/6da5e8f17a9bfa9cb04cf22c87b6475394ecec3af4fdc337f72d6dbf3319ea52/containerd-shim-spin-v1",
 				false,
 			},
 			true,
@@ -144,3 +176,48 @@ func TestConfig_Install(t *testing.T) {
 		})
 	}
 }
+
+func TestConfig_InstallKeepsUnchangedBinary(t *testing.T) {
+	hostFs := afero.NewMemMapFs()
+	c := NewConfig(tests.FixtureFs("../../testdata/node-installer"), hostFs, "/assets", "/opt/rcm")
+	const binPath = "/opt/rcm/bin/spin-v1/6da5e8f17a9bfa9cb04cf22c87b6475394ecec3af4fdc337f72d6dbf3319ea52/containerd-shim-spin-v1"
+
+	_, changed, err := c.Install("containerd-shim-spin-v1")
+	require.NoError(t, err)
+	assert.True(t, changed)
+	installedAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, hostFs.Chtimes(binPath, installedAt, installedAt))
+
+	// An unchanged binary is not written again
+	_, changed, err = c.Install("containerd-shim-spin-v1")
+	require.NoError(t, err)
+	assert.False(t, changed)
+	info, err := hostFs.Stat(binPath)
+	require.NoError(t, err)
+	assert.Equal(t, installedAt, info.ModTime().UTC())
+
+	// A binary that doesn't match its digest is replaced
+	require.NoError(t, afero.WriteFile(hostFs, binPath, []byte("broken"), 0o644))
+	_, changed, err = c.Install("containerd-shim-spin-v1")
+	require.NoError(t, err)
+	assert.False(t, changed)
+	digest, err := fileDigest(hostFs, binPath)
+	require.NoError(t, err)
+	assert.Equal(t, "6da5e8f17a9bfa9cb04cf22c87b6475394ecec3af4fdc337f72d6dbf3319ea52", hex.EncodeToString(digest))
+	info, err = hostFs.Stat(binPath)
+	require.NoError(t, err)
+	assert.Equal(t, "-rwxr-xr-x", info.Mode().String())
+}
+
+func TestConfig_copyAssetDigestMismatch(t *testing.T) {
+	hostFs := afero.NewMemMapFs()
+	c := NewConfig(tests.FixtureFs("../../testdata/node-installer"), hostFs, "/assets", "/opt/rcm")
+
+	err := c.copyAsset("containerd-shim-spin-v1", "/opt/rcm/bin/spin-v1/00/containerd-shim-spin-v1", []byte{0})
+	require.ErrorContains(t, err, "digest of copied shim binary")
+
+	// Neither the binary nor the temporary file are left behind
+	files, err := afero.ReadDir(hostFs, "/opt/rcm/bin/spin-v1/00")
+	require.NoError(t, err)
+	assert.Empty(t, files)
+}
@@ -17,6 +17,10 @@
 package shim
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"path"
 	"strings"
 
 	"github.com/spf13/afero"
@@ -38,6 +42,31 @@ func NewConfig(rootFs afero.Fs, hostFs afero.Fs, assetPath string, rcmPath strin
 	}
 }
 
+// BinPath returns the path a version of a shim binary is installed to. Each
+// version is kept in a directory of its own, named after its SHA-256 digest.
+func (c *Config) BinPath(shimName string, sha256 []byte) string {
+	return path.Join(c.rcmPath, "bin", RuntimeName(shimName), hex.EncodeToString(sha256), shimName)
+}
+
+// AssetDigest returns the SHA-256 digest of a shim binary in the asset path.
+func (c *Config) AssetDigest(shimName string) ([]byte, error) {
+	return fileDigest(c.rootFs, path.Join(c.assetPath, shimName))
+}
+
+func fileDigest(fs afero.Fs, filePath string) ([]byte, error) {
+	f, err := fs.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
 func RuntimeName(bin string) string {
 	return strings.TrimPrefix(bin, "containerd-shim-")
 }
@@ -1,14 +1,24 @@
 package shim
 
 import (
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"os"
+	"path"
+
+	"github.com/spf13/afero"
 
 	"github.com/spinframework/runtime-class-manager/internal/state"
 )
 
 func (c *Config) Uninstall(shimName string) (string, error) {
+	unlock, err := state.Lock(c.hostFs, c.rcmPath)
+	if err != nil {
+		return "", err
+	}
+	defer unlock() //nolint:errcheck // the lock is released when the file is closed in any case
+
 	st, err := state.Get(c.hostFs, c.rcmPath)
 	if err != nil {
 		return "", err
@@ -19,7 +29,7 @@ func (c *Config) Uninstall(shimName string) (string, error) {
 	}
 	filePath := s.Path
 
-	err = c.hostFs.Remove(filePath)
+	err = c.removeBinary(filePath, s.Sha256)
 	if err != nil {
 		if !errors.Is(err, os.ErrNotExist) {
 			return "", fmt.Errorf("shim binary at %s does not exist, nothing to delete", filePath)
@@ -31,3 +41,55 @@ func (c *Config) Uninstall(shimName string) (string, error) {
 	}
 	return filePath, err
 }
+
+// GarbageCollect removes the binaries of the versions of a shim that are not
+// installed anymore, but have been kept for rollbacks. It returns the paths
+// of the removed binaries.
+func (c *Config) GarbageCollect(shimName string) ([]string, error) {
+	unlock, err := state.Lock(c.hostFs, c.rcmPath)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock() //nolint:errcheck // the lock is released when the file is closed in any case
+
+	st, err := state.Get(c.hostFs, c.rcmPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var removed []string
+	for _, v := range st.OldVersions(shimName) {
+		if err := c.removeBinary(v.Path, v.Sha256); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return removed, fmt.Errorf("failed to remove shim binary at %s: %w", v.Path, err)
+		}
+		st.RemoveVersion(shimName, v.Path)
+		removed = append(removed, v.Path)
+	}
+	if len(removed) == 0 {
+		return nil, nil
+	}
+	return removed, st.Write()
+}
+
+// removeBinary removes a shim binary, and the directories of its version and
+// the shim if they are empty afterwards. Binaries installed before they were
+// kept in a directory per version are removed on their own.
+func (c *Config) removeBinary(filePath string, sha256 []byte) error {
+	if err := c.hostFs.Remove(filePath); err != nil {
+		return err
+	}
+
+	versionDir := path.Dir(filePath)
+	if path.Base(versionDir) != hex.EncodeToString(sha256) {
+		return nil
+	}
+	for _, dir := range []string{versionDir, path.Dir(versionDir)} {
+		if empty, err := afero.IsEmpty(c.hostFs, dir); err != nil || !empty {
+			return nil //nolint:nilerr // the binary is removed, leftover directories don't matter
+		}
+		if err := c.hostFs.Remove(dir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
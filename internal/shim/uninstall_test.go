@@ -21,6 +21,8 @@ import (
 
 	"github.com/spf13/afero"
 	tests "github.com/spinframework/runtime-class-manager/tests/node-installer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestConfig_Uninstall(t *testing.T) {
@@ -60,6 +62,16 @@ func TestConfig_Uninstall(t *testing.T) {
 		},
 		{
 			"successful shim uninstallation",
+			fields{
+				tests.FixtureFs("../../testdata/node-installer/shim-versioned"),
+				"/opt/rcm",
+			},
+			args{"spin-v1"},
+			"/opt/rcm/bin/spin-v1/6da5e8f17a9bfa9cb04cf22c87b6475394ecec3af4fdc337f72d6dbf3319ea52/containerd-shim-spin-v1",
+			false,
+		},
+		{
+			"successful uninstallation of shim at legacy path",
 			fields{
 				tests.FixtureFs("../../testdata/node-installer/shim"),
 				"/opt/rcm",
@@ -85,6 +97,60 @@ func TestConfig_Uninstall(t *testing.T) {
 			if got != tt.want {
 				t.Errorf("Config.Uninstall() = %v, want %v", got, tt.want)
 			}
+			if got != "" {
+				_, err := tt.fields.hostFs.Stat(got)
+				require.ErrorIs(t, err, afero.ErrFileNotFound)
+			}
 		})
 	}
 }
+
+func TestConfig_GarbageCollect(t *testing.T) {
+	hostFs := afero.NewMemMapFs()
+	lockFile := `{
+ "shims": {
+  "spin-v1": {"sha256": "03", "path": "/opt/rcm/bin/spin-v1/03/containerd-shim-spin-v1"}
+ },
+ "history": {
+  "spin-v1": [
+   {"sha256": "01", "path": "/opt/rcm/bin/containerd-shim-spin-v1"},
+   {"sha256": "02", "path": "/opt/rcm/bin/spin-v1/02/containerd-shim-spin-v1", "installedAt": "2024-01-01T00:00:00Z"},
+   {"sha256": "03", "path": "/opt/rcm/bin/spin-v1/03/containerd-shim-spin-v1", "installedAt": "2024-01-02T00:00:00Z"}
+  ]
+ }
+}`
+	require.NoError(t, afero.WriteFile(hostFs, "/opt/rcm/rcm-lock.json", []byte(lockFile), 0o644))
+	for _, p := range []string{
+		"/opt/rcm/bin/containerd-shim-spin-v1",
+		"/opt/rcm/bin/containerd-shim-wasmtime-v1",
+		"/opt/rcm/bin/spin-v1/02/containerd-shim-spin-v1",
+		"/opt/rcm/bin/spin-v1/03/containerd-shim-spin-v1",
+	} {
+		require.NoError(t, afero.WriteFile(hostFs, p, []byte("shim"), 0o755))
+	}
+	c := NewConfig(afero.NewMemMapFs(), hostFs, "/assets", "/opt/rcm")
+
+	removed, err := c.GarbageCollect("spin-v1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"/opt/rcm/bin/containerd-shim-spin-v1",
+		"/opt/rcm/bin/spin-v1/02/containerd-shim-spin-v1",
+	}, removed)
+
+	for _, p := range []string{"/opt/rcm/bin/containerd-shim-spin-v1", "/opt/rcm/bin/spin-v1/02"} {
+		_, err := hostFs.Stat(p)
+		require.ErrorIs(t, err, afero.ErrFileNotFound, p)
+	}
+	for _, p := range []string{"/opt/rcm/bin/containerd-shim-wasmtime-v1", "/opt/rcm/bin/spin-v1/03/containerd-shim-spin-v1"} {
+		_, err := hostFs.Stat(p)
+		require.NoError(t, err, p)
+	}
+
+	// Uninstalling the last version removes the directory of the shim
+	_, err = c.Uninstall("spin-v1")
+	require.NoError(t, err)
+	_, err = hostFs.Stat("/opt/rcm/bin/spin-v1")
+	require.ErrorIs(t, err, afero.ErrFileNotFound)
+	_, err = hostFs.Stat("/opt/rcm/bin")
+	require.NoError(t, err)
+}
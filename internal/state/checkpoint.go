@@ -0,0 +1,81 @@
+package state
+
+import (
+	"slices"
+
+	"github.com/spf13/afero"
+)
+
+// Checkpoint records the entries of shims in the lock file, so that the
+// changes a failed installation made to them can be undone. Only the entries
+// of the recorded shims are restored, changes concurrent installers made to
+// other shims in the meantime are kept.
+type Checkpoint struct {
+	fs      afero.Fs
+	rcmPath string
+	// shims holds the recorded entries, nil for shims that weren't installed.
+	shims   map[string]*Shim
+	history map[string][]Version
+}
+
+// NewCheckpoint returns a checkpoint that doesn't record any shim yet.
+func NewCheckpoint(fs afero.Fs, rcmPath string) *Checkpoint {
+	return &Checkpoint{
+		fs:      fs,
+		rcmPath: rcmPath,
+		shims:   make(map[string]*Shim),
+		history: make(map[string][]Version),
+	}
+}
+
+// Add records the current entry of a shim, unless it has been recorded before.
+func (c *Checkpoint) Add(shimName string) error {
+	if _, ok := c.shims[shimName]; ok {
+		return nil
+	}
+
+	unlock, err := Lock(c.fs, c.rcmPath)
+	if err != nil {
+		return err
+	}
+	defer unlock() //nolint:errcheck // the lock is released when the file is closed in any case
+
+	st, err := Get(c.fs, c.rcmPath)
+	if err != nil {
+		return err
+	}
+	c.shims[shimName] = st.Shims[shimName]
+	c.history[shimName] = slices.Clone(st.History[shimName])
+	return nil
+}
+
+// Restore resets the entries of the recorded shims in the lock file.
+func (c *Checkpoint) Restore() error {
+	if len(c.shims) == 0 {
+		return nil
+	}
+
+	unlock, err := Lock(c.fs, c.rcmPath)
+	if err != nil {
+		return err
+	}
+	defer unlock() //nolint:errcheck // the lock is released when the file is closed in any case
+
+	st, err := Get(c.fs, c.rcmPath)
+	if err != nil {
+		return err
+	}
+	for shimName, shim := range c.shims {
+		if shim == nil {
+			delete(st.Shims, shimName)
+		} else {
+			st.Shims[shimName] = shim
+		}
+		if history := c.history[shimName]; len(history) > 0 {
+			st.History[shimName] = history
+		} else {
+			delete(st.History, shimName)
+		}
+	}
+	return st.Write()
+}
@@ -0,0 +1,65 @@
+package state
+
+import (
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/spf13/afero"
+)
+
+// Lock takes an exclusive advisory lock on the working directory of the
+// RuntimeClassManager, so that installers running at the same time don't
+// overwrite each other's changes to the lock file. It waits until the lock is
+// released by other installers, and returns a function to release it. Files
+// that are not backed by the OS, as in tests and dry runs, are not locked.
+func Lock(fs afero.Fs, rcmPath string) (unlock func() error, err error) {
+	return lockDir(fs, rcmPath)
+}
+
+// LockRuntimeConfig takes an exclusive advisory lock on the backup directory
+// of the RuntimeClassManager, so that installers running at the same time
+// change the container runtime config, restart the runtime and restore their
+// backups one after the other. It is separate from Lock, which is taken and
+// released repeatedly while it is held.
+func LockRuntimeConfig(fs afero.Fs, rcmPath string) (unlock func() error, err error) {
+	return lockDir(fs, BackupPath(rcmPath))
+}
+
+// BackupPath returns the directory below which installers keep backups of
+// the files they change.
+func BackupPath(rcmPath string) string {
+	return path.Join(rcmPath, "backup")
+}
+
+// lockDir takes an exclusive advisory lock on dir, creating it if needed.
+func lockDir(fs afero.Fs, dir string) (unlock func() error, err error) {
+	if err := fs.MkdirAll(dir, 0o755); err != nil { //nolint:mnd // file permissions
+		return nil, err
+	}
+	f, err := fs.Open(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	if osf, ok := osFile(f); ok {
+		if err := flock(osf); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("failed to lock %s: %w", dir, err)
+		}
+	}
+	// Closing the directory releases the lock
+	return f.Close, nil
+}
+
+// osFile returns the OS file underlying f, if any.
+func osFile(f afero.File) (*os.File, bool) {
+	switch f := f.(type) {
+	case *os.File:
+		return f, true
+	case *afero.BasePathFile:
+		return osFile(f.File)
+	default:
+		return nil, false
+	}
+}
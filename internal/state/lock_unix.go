@@ -0,0 +1,21 @@
+//go:build unix
+// +build unix
+
+package state
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// flock takes an exclusive advisory lock on f, which is released when f is
+// closed.
+func flock(f *os.File) error {
+	for {
+		err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX) //nolint:gosec // file descriptors fit into int
+		if !errors.Is(err, syscall.EINTR) {
+			return err
+		}
+	}
+}
@@ -0,0 +1,11 @@
+//go:build windows
+// +build windows
+
+package state
+
+import "os"
+
+// flock is a no-op, as the node installer only runs on Linux nodes.
+func flock(_ *os.File) error {
+	return nil
+}
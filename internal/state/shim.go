@@ -3,6 +3,7 @@ package state
 import (
 	"encoding/hex"
 	"encoding/json"
+	"time"
 )
 
 type Shim struct {
@@ -36,3 +37,42 @@ func (s *Shim) UnmarshalJSON(data []byte) error {
 	s.Sha256 = sha256
 	return nil
 }
+
+// Version is a version of a shim that has been installed.
+type Version struct {
+	Sha256 []byte
+	Path   string
+	// InstalledAt is when the version was installed, or zero if unknown.
+	InstalledAt time.Time
+}
+
+func (v *Version) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&struct {
+		Sha256      string    `json:"sha256"`
+		Path        string    `json:"path"`
+		InstalledAt time.Time `json:"installedAt,omitzero"`
+	}{
+		Sha256:      hex.EncodeToString(v.Sha256),
+		Path:        v.Path,
+		InstalledAt: v.InstalledAt,
+	})
+}
+
+func (v *Version) UnmarshalJSON(data []byte) error {
+	var aux struct {
+		Sha256      string    `json:"sha256"`
+		Path        string    `json:"path"`
+		InstalledAt time.Time `json:"installedAt"`
+	}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	sha256, err := hex.DecodeString(aux.Sha256)
+	if err != nil {
+		return err
+	}
+	v.Sha256 = sha256
+	v.Path = aux.Path
+	v.InstalledAt = aux.InstalledAt
+	return nil
+}
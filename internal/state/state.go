@@ -4,29 +4,58 @@ import (
 	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
+	"time"
 
 	"github.com/spf13/afero"
 )
 
+// SchemaVersion is the version of the format of the lock file. It must be
+// increased whenever the format changes in a way older installers can't read.
+const SchemaVersion = 1
+
 type State struct {
-	Shims        map[string]*Shim `json:"shims"`
+	// SchemaVersion is the format version of the lock file, 0 for lock files
+	// written before it was recorded.
+	SchemaVersion int              `json:"schemaVersion"`
+	Shims         map[string]*Shim `json:"shims"`
+	// History lists the versions of each shim that have been installed,
+	// oldest first. Their binaries are kept for rollbacks until they are
+	// garbage-collected.
+	History      map[string][]Version `json:"history,omitempty"`
 	fs           afero.Fs
 	lockFilePath string
 }
 
+// LockFilePath returns the path of the lock file in the working directory of
+// the RuntimeClassManager.
+func LockFilePath(rcmPath string) string {
+	return filepath.Join(rcmPath, "rcm-lock.json")
+}
+
 func Get(fs afero.Fs, rcmPath string) (*State, error) {
 	out := State{
 		Shims:        make(map[string]*Shim),
-		lockFilePath: filepath.Join(rcmPath, "rcm-lock.json"),
+		History:      make(map[string][]Version),
+		lockFilePath: LockFilePath(rcmPath),
 		fs:           fs,
 	}
 	content, err := afero.ReadFile(fs, out.lockFilePath)
 	if err == nil {
-		err := json.Unmarshal(content, &out)
-		return &out, err
+		if err := json.Unmarshal(content, &out); err != nil {
+			return &out, err
+		}
+		if out.SchemaVersion > SchemaVersion {
+			return nil, fmt.Errorf("lock file %s has schema version %d, only versions up to %d are supported", out.lockFilePath, out.SchemaVersion, SchemaVersion)
+		}
+		if out.History == nil {
+			out.History = make(map[string][]Version)
+		}
+		return &out, nil
 	}
 	if !errors.Is(err, os.ErrNotExist) {
 		return nil, err
@@ -44,15 +73,64 @@ func (l *State) ShimChanged(shimName string, sha256 []byte, path string) bool {
 	return !bytes.Equal(shim.Sha256, sha256) || shim.Path != path
 }
 
-func (l *State) UpdateShim(shimName string, shim Shim) {
+// UpdateShim sets the installed version of a shim and adds it to the
+// history. A version that had been installed before moves to the end of the
+// history.
+func (l *State) UpdateShim(shimName string, shim Shim, installedAt time.Time) {
+	if previous, ok := l.Shims[shimName]; ok && !l.inHistory(shimName, previous.Path) {
+		// Lock files written before the history was kept only know the
+		// installed version
+		l.History[shimName] = append(l.History[shimName], Version{Sha256: previous.Sha256, Path: previous.Path})
+	}
+	l.RemoveVersion(shimName, shim.Path)
+	l.History[shimName] = append(l.History[shimName], Version{Sha256: shim.Sha256, Path: shim.Path, InstalledAt: installedAt})
 	l.Shims[shimName] = &shim
 }
 
+// RemoveShim removes the installed version of a shim. Its previous versions
+// are kept in the history.
 func (l *State) RemoveShim(shimName string) {
+	if shim, ok := l.Shims[shimName]; ok {
+		l.RemoveVersion(shimName, shim.Path)
+	}
 	delete(l.Shims, shimName)
 }
 
+// OldVersions returns the versions of a shim in the history that are not
+// installed, oldest first.
+func (l *State) OldVersions(shimName string) []Version {
+	var versions []Version
+	for _, v := range l.History[shimName] {
+		if shim, ok := l.Shims[shimName]; ok && shim.Path == v.Path {
+			continue
+		}
+		versions = append(versions, v)
+	}
+	return versions
+}
+
+// RemoveVersion removes the version of a shim at path from the history.
+func (l *State) RemoveVersion(shimName string, path string) {
+	versions := slices.DeleteFunc(l.History[shimName], func(v Version) bool {
+		return v.Path == path
+	})
+	if len(versions) == 0 {
+		delete(l.History, shimName)
+		return
+	}
+	l.History[shimName] = versions
+}
+
+func (l *State) inHistory(shimName string, path string) bool {
+	return slices.ContainsFunc(l.History[shimName], func(v Version) bool {
+		return v.Path == path
+	})
+}
+
+// Write writes the lock file. It is written to a temporary file first, which
+// replaces the lock file, so that it is never left half-written.
 func (l *State) Write() error {
+	l.SchemaVersion = SchemaVersion
 	out, err := json.MarshalIndent(l, "", " ")
 	if err != nil {
 		return err
@@ -60,5 +138,25 @@ func (l *State) Write() error {
 
 	slog.Debug("writing lock file", "content", string(out))
 
-	return afero.WriteFile(l.fs, l.lockFilePath, out, 0644) //nolint:mnd // file permissions
+	tmp, err := afero.TempFile(l.fs, filepath.Dir(l.lockFilePath), filepath.Base(l.lockFilePath)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer l.fs.Remove(tmp.Name()) //nolint:errcheck // the file is gone after it has been renamed
+
+	if _, err := tmp.Write(out); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := l.fs.Chmod(tmp.Name(), 0o644); err != nil { //nolint:mnd // file permissions
+		return err
+	}
+	return l.fs.Rename(tmp.Name(), l.lockFilePath)
 }
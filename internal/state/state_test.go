@@ -1,7 +1,12 @@
 package state_test
 
 import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/spf13/afero"
 	"github.com/spinframework/runtime-class-manager/internal/state"
@@ -127,3 +132,181 @@ func TestShimChanged(t *testing.T) {
 		})
 	}
 }
+
+func TestHistory(t *testing.T) {
+	legacy := state.Shim{Sha256: []byte{1}, Path: "/opt/rcm/bin/containerd-shim-spin-v1"}
+	v1 := state.Shim{Sha256: []byte{2}, Path: "/opt/rcm/bin/spin-v1/02/containerd-shim-spin-v1"}
+	v2 := state.Shim{Sha256: []byte{3}, Path: "/opt/rcm/bin/spin-v1/03/containerd-shim-spin-v1"}
+	t1 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	t2 := t1.Add(time.Hour)
+	t3 := t2.Add(time.Hour)
+
+	fs := afero.NewMemMapFs()
+	st, err := state.Get(fs, "/opt/rcm")
+	require.NoError(t, err)
+	// A lock file without history
+	st.Shims["spin-v1"] = &legacy
+
+	st.UpdateShim("spin-v1", v1, t1)
+	st.UpdateShim("spin-v1", v2, t2)
+	// Installing a previous version again moves it to the end
+	st.UpdateShim("spin-v1", v1, t3)
+	require.NoError(t, st.Write())
+
+	st, err = state.Get(fs, "/opt/rcm")
+	require.NoError(t, err)
+	assert.Equal(t, &v1, st.Shims["spin-v1"])
+	assert.Equal(t, []state.Version{
+		{Sha256: legacy.Sha256, Path: legacy.Path},
+		{Sha256: v2.Sha256, Path: v2.Path, InstalledAt: t2},
+		{Sha256: v1.Sha256, Path: v1.Path, InstalledAt: t3},
+	}, st.History["spin-v1"])
+	assert.Equal(t, []state.Version{
+		{Sha256: legacy.Sha256, Path: legacy.Path},
+		{Sha256: v2.Sha256, Path: v2.Path, InstalledAt: t2},
+	}, st.OldVersions("spin-v1"))
+
+	// Previous versions are kept after the shim has been removed
+	st.RemoveShim("spin-v1")
+	assert.Empty(t, st.Shims)
+	assert.Len(t, st.OldVersions("spin-v1"), 2)
+
+	st.RemoveVersion("spin-v1", legacy.Path)
+	st.RemoveVersion("spin-v1", v2.Path)
+	assert.Empty(t, st.History)
+}
+
+func TestWrite(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	require.NoError(t, fs.MkdirAll("/opt/rcm", 0o755))
+	st, err := state.Get(fs, "/opt/rcm")
+	require.NoError(t, err)
+	st.UpdateShim("spin-v1", state.Shim{Sha256: []byte{1}, Path: "/opt/rcm/bin/spin-v1/01/containerd-shim-spin-v1"}, time.Time{})
+	require.NoError(t, st.Write())
+
+	// Only the lock file is left behind
+	files, err := afero.ReadDir(fs, "/opt/rcm")
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.Equal(t, "rcm-lock.json", files[0].Name())
+	assert.Equal(t, "-rw-r--r--", files[0].Mode().String())
+
+	content, err := afero.ReadFile(fs, "/opt/rcm/rcm-lock.json")
+	require.NoError(t, err)
+	assert.Contains(t, string(content), `"schemaVersion": 1,`)
+}
+
+func TestGet_NewerSchemaVersion(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "/opt/rcm/rcm-lock.json", []byte(`{"schemaVersion": 99, "shims": {}}`), 0o644))
+
+	_, err := state.Get(fs, "/opt/rcm")
+	require.ErrorContains(t, err, "schema version 99")
+}
+
+func TestLock(t *testing.T) {
+	fs := afero.NewBasePathFs(afero.NewOsFs(), t.TempDir())
+	const installers = 20
+
+	var wg sync.WaitGroup
+	errs := make(chan error, installers)
+	for i := range installers {
+		wg.Go(func() {
+			unlock, err := state.Lock(fs, "/opt/rcm")
+			if err != nil {
+				errs <- err
+				return
+			}
+			defer unlock() //nolint:errcheck // test
+
+			st, err := state.Get(fs, "/opt/rcm")
+			if err != nil {
+				errs <- err
+				return
+			}
+			name := fmt.Sprintf("shim-%d", i)
+			st.UpdateShim(name, state.Shim{Sha256: []byte{byte(i)}, Path: "/opt/rcm/bin/" + name}, time.Now())
+			errs <- st.Write()
+		})
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	// No installer overwrote the shims of another one
+	st, err := state.Get(fs, "/opt/rcm")
+	require.NoError(t, err)
+	assert.Len(t, st.Shims, installers)
+}
+
+func TestLockRuntimeConfig(t *testing.T) {
+	fs := afero.NewBasePathFs(afero.NewOsFs(), t.TempDir())
+	const installers = 10
+
+	var wg sync.WaitGroup
+	var holders atomic.Int32
+	errs := make(chan error, installers)
+	for range installers {
+		wg.Go(func() {
+			unlock, err := state.LockRuntimeConfig(fs, "/opt/rcm")
+			if err != nil {
+				errs <- err
+				return
+			}
+			defer unlock() //nolint:errcheck // test
+
+			if holders.Add(1) > 1 {
+				errs <- errors.New("runtime config lock is held twice")
+			}
+			defer holders.Add(-1)
+
+			// The lock file can be locked while the runtime config is locked
+			unlockState, err := state.Lock(fs, "/opt/rcm")
+			if err != nil {
+				errs <- err
+				return
+			}
+			time.Sleep(time.Millisecond)
+			errs <- unlockState()
+		})
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+}
+
+func TestCheckpoint(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	st, err := state.Get(fs, "/opt/rcm")
+	require.NoError(t, err)
+	st.UpdateShim("spin-v1", state.Shim{Sha256: []byte{1}, Path: "/opt/rcm/bin/spin-v1/01/containerd-shim-spin-v1"}, time.Time{})
+	require.NoError(t, st.Write())
+
+	checkpoint := state.NewCheckpoint(fs, "/opt/rcm")
+	require.NoError(t, checkpoint.Add("spin-v1"))
+	require.NoError(t, checkpoint.Add("wasmtime-v1"))
+
+	// This installation changes both of its shims, while a concurrent
+	// installer installs another one
+	st, err = state.Get(fs, "/opt/rcm")
+	require.NoError(t, err)
+	st.UpdateShim("spin-v1", state.Shim{Sha256: []byte{2}, Path: "/opt/rcm/bin/spin-v1/02/containerd-shim-spin-v1"}, time.Time{})
+	st.UpdateShim("wasmtime-v1", state.Shim{Sha256: []byte{3}, Path: "/opt/rcm/bin/wasmtime-v1/03/containerd-shim-wasmtime-v1"}, time.Time{})
+	st.UpdateShim("lunatic-v1", state.Shim{Sha256: []byte{4}, Path: "/opt/rcm/bin/lunatic-v1/04/containerd-shim-lunatic-v1"}, time.Time{})
+	require.NoError(t, st.Write())
+	require.NoError(t, checkpoint.Add("spin-v1"))
+
+	require.NoError(t, checkpoint.Restore())
+
+	st, err = state.Get(fs, "/opt/rcm")
+	require.NoError(t, err)
+	assert.Equal(t, "/opt/rcm/bin/spin-v1/01/containerd-shim-spin-v1", st.Shims["spin-v1"].Path)
+	assert.Len(t, st.History["spin-v1"], 1)
+	assert.NotContains(t, st.Shims, "wasmtime-v1")
+	assert.NotContains(t, st.History, "wasmtime-v1")
+	assert.Equal(t, "/opt/rcm/bin/lunatic-v1/04/containerd-shim-lunatic-v1", st.Shims["lunatic-v1"].Path)
+}
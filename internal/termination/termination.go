@@ -0,0 +1,128 @@
+/*
+   Copyright The KWasm Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package termination passes machine-readable failure reasons from the
+// containers of install and uninstall Jobs to the controller through the
+// container termination message.
+package termination
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+)
+
+// Reason is a machine-readable reason why a Job failed.
+type Reason string
+
+const (
+	// ReasonDownloadFailed means the artifact could not be fetched.
+	ReasonDownloadFailed Reason = "DownloadFailed"
+	// ReasonChecksumMismatch means the artifact did not match its digest.
+	ReasonChecksumMismatch Reason = "ChecksumMismatch"
+	// ReasonInvalidArtifact means the artifact does not contain a shim binary.
+	ReasonInvalidArtifact Reason = "InvalidArtifact"
+	// ReasonNoPlatformMatch means no artifact matches the platform of the node.
+	ReasonNoPlatformMatch Reason = "NoPlatformMatch"
+	// ReasonConfigNotFound means the container runtime config was not found on the node.
+	ReasonConfigNotFound Reason = "ConfigNotFound"
+	// ReasonInvalidConfig means the configuration passed to the Job is invalid.
+	ReasonInvalidConfig Reason = "InvalidConfig"
+	// ReasonConfigUpdateFailed means the container runtime config could not be updated.
+	ReasonConfigUpdateFailed Reason = "ConfigUpdateFailed"
+	// ReasonShimInstallFailed means the shim binary could not be copied to the node.
+	ReasonShimInstallFailed Reason = "ShimInstallFailed"
+	// ReasonShimUninstallFailed means the shim binary could not be removed from the node.
+	ReasonShimUninstallFailed Reason = "ShimUninstallFailed"
+	// ReasonRestartFailed means the container runtime could not be restarted.
+	ReasonRestartFailed Reason = "RestartFailed"
+	// ReasonRuntimeUnhealthy means the container runtime didn't become healthy
+	// after it was restarted, and the previous config and shim were restored.
+	ReasonRuntimeUnhealthy Reason = "RuntimeUnhealthy"
+	// ReasonHandlerNotRegistered means the container runtime didn't register
+	// the runtime handler of the shim after it was restarted.
+	ReasonHandlerNotRegistered Reason = "HandlerNotRegistered"
+	// ReasonUnknown is used for failures without a more specific reason.
+	ReasonUnknown Reason = "Unknown"
+)
+
+// DefaultPath is the default termination message path of Kubernetes containers.
+const DefaultPath = "/dev/termination-log"
+
+// maxMessageLength is the size limit of termination messages imposed by the kubelet.
+const maxMessageLength = 4096
+
+// Message is the termination message written by a failed container.
+type Message struct {
+	Reason  Reason `json:"reason"`
+	Message string `json:"message"`
+}
+
+// Error attaches a Reason to an error.
+type Error struct {
+	Reason Reason
+	Err    error
+}
+
+func (e *Error) Error() string { return e.Err.Error() }
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// WithReason attaches a reason to err. It returns nil if err is nil.
+func WithReason(reason Reason, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Reason: reason, Err: err}
+}
+
+// ReasonOf returns the reason attached to err, or ReasonUnknown.
+func ReasonOf(err error) Reason {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Reason
+	}
+	return ReasonUnknown
+}
+
+// Write writes a termination message for err to path. The message is
+// truncated to the size limit of termination messages.
+func Write(path string, reason Reason, err error) error {
+	msg := Message{Reason: reason, Message: err.Error()}
+	data, jsonErr := json.Marshal(msg)
+	if jsonErr != nil {
+		return jsonErr
+	}
+	for len(data) > maxMessageLength {
+		cut := len(msg.Message) - (len(data) - maxMessageLength) - len("...")
+		msg.Message = strings.ToValidUTF8(msg.Message[:max(cut, 0)], "") + "..."
+		if data, jsonErr = json.Marshal(msg); jsonErr != nil {
+			return jsonErr
+		}
+	}
+	return os.WriteFile(path, data, 0o644) //nolint:gosec,mnd // termination messages are read by the kubelet
+}
+
+// Parse parses a termination message written by Write. It returns false for
+// messages in any other format, e.g. from containers that failed unexpectedly.
+func Parse(s string) (Message, bool) {
+	var msg Message
+	if err := json.Unmarshal([]byte(strings.TrimSpace(s)), &msg); err != nil || msg.Reason == "" {
+		return Message{}, false
+	}
+	return msg, true
+}
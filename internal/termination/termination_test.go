@@ -0,0 +1,87 @@
+/*
+   Copyright The KWasm Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package termination_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spinframework/runtime-class-manager/internal/termination"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteAndParse(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{"short message", errors.New("failed to restart containerd")},
+		{"long message", errors.New(strings.Repeat("é", 5000))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "termination-log")
+			require.NoError(t, termination.Write(path, termination.ReasonRestartFailed, tt.err))
+
+			data, err := os.ReadFile(path)
+			require.NoError(t, err)
+			assert.LessOrEqual(t, len(data), 4096)
+
+			msg, ok := termination.Parse(string(data))
+			require.True(t, ok)
+			assert.Equal(t, termination.ReasonRestartFailed, msg.Reason)
+			assert.True(t, strings.HasPrefix(tt.err.Error(), strings.TrimSuffix(msg.Message, "...")))
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		want    termination.Message
+		wantOK  bool
+	}{
+		{"structured", `{"reason":"ChecksumMismatch","message":"expected sha256 abc"}` + "\n", termination.Message{Reason: termination.ReasonChecksumMismatch, Message: "expected sha256 abc"}, true},
+		{"plain logs", "time=2024-01-01 level=ERROR msg=failed", termination.Message{}, false},
+		{"json without reason", `{"message":"failed"}`, termination.Message{}, false},
+		{"empty", "", termination.Message{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := termination.Parse(tt.message)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestReasonOf(t *testing.T) {
+	err := termination.WithReason(termination.ReasonConfigNotFound, errors.New("no config"))
+
+	assert.Equal(t, termination.ReasonConfigNotFound, termination.ReasonOf(err))
+	assert.Equal(t, termination.ReasonConfigNotFound, termination.ReasonOf(fmt.Errorf("install: %w", err)))
+	assert.Equal(t, termination.ReasonUnknown, termination.ReasonOf(errors.New("other")))
+	assert.NoError(t, termination.WithReason(termination.ReasonUnknown, nil))
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	rcmv1 "github.com/spinframework/runtime-class-manager/api/v1alpha1"
+	"github.com/spinframework/runtime-class-manager/internal/controller"
+)
+
+// SetupShimWebhookWithManager registers the validating webhook for Shims with the manager.
+func SetupShimWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr, &rcmv1.Shim{}).
+		WithValidator(&ShimCustomValidator{Client: mgr.GetClient()}).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/validate-runtime-spinkube-dev-v1alpha1-shim,mutating=false,failurePolicy=fail,sideEffects=None,groups=runtime.spinkube.dev,resources=shims,verbs=create;update,versions=v1alpha1,name=vshim-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// ShimCustomValidator rejects invalid Shims when they are created or updated.
+type ShimCustomValidator struct {
+	Client client.Reader
+}
+
+var _ admission.Validator[*rcmv1.Shim] = &ShimCustomValidator{}
+
+// ValidateCreate validates a Shim on creation.
+func (v *ShimCustomValidator) ValidateCreate(ctx context.Context, shim *rcmv1.Shim) (admission.Warnings, error) {
+	return nil, v.validate(ctx, shim)
+}
+
+// ValidateUpdate validates a Shim on update.
+func (v *ShimCustomValidator) ValidateUpdate(ctx context.Context, oldShim, shim *rcmv1.Shim) (admission.Warnings, error) {
+	// Allow removing the finalizer of Shims that are being deleted, and
+	// updates that leave the spec alone, e.g. of the finalizers or
+	// annotations, even if the Shim would no longer pass validation.
+	if !shim.DeletionTimestamp.IsZero() || equality.Semantic.DeepEqual(oldShim.Spec, shim.Spec) {
+		return nil, nil
+	}
+	return nil, v.validate(ctx, shim)
+}
+
+// ValidateDelete allows all deletions.
+func (v *ShimCustomValidator) ValidateDelete(_ context.Context, _ *rcmv1.Shim) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func (v *ShimCustomValidator) validate(ctx context.Context, shim *rcmv1.Shim) error {
+	errs := controller.ValidateShim(shim)
+
+	var shims rcmv1.ShimList
+	if err := v.Client.List(ctx, &shims); err != nil {
+		return fmt.Errorf("failed to list shims: %w", err)
+	}
+	errs = append(errs, controller.ValidateRuntimeClassUnique(shim, shims.Items)...)
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(rcmv1.GroupVersion.WithKind("Shim").GroupKind(), shim.Name, errs)
+}
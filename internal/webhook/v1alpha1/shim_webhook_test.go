@@ -0,0 +1,110 @@
+package v1alpha1 //nolint:testpackage // whitebox test
+
+import (
+	"context"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	rcmv1 "github.com/spinframework/runtime-class-manager/api/v1alpha1"
+)
+
+func makeShim(name, runtimeClass string) *rcmv1.Shim {
+	return &rcmv1.Shim{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: rcmv1.ShimSpec{
+			FetchStrategy: rcmv1.FetchStrategy{
+				AnonHTTP: &rcmv1.AnonHTTPSpec{Location: "https://example.com/shim.tar.gz"},
+			},
+			RuntimeClass:    rcmv1.RuntimeClassSpec{Name: runtimeClass, Handler: runtimeClass},
+			RolloutStrategy: rcmv1.RolloutStrategy{Type: rcmv1.RolloutStrategyTypeRecreate},
+		},
+	}
+}
+
+func newValidator(t *testing.T, shims ...*rcmv1.Shim) *ShimCustomValidator {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := rcmv1.AddToScheme(scheme); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	builder := fake.NewClientBuilder().WithScheme(scheme)
+	for _, shim := range shims {
+		builder = builder.WithObjects(shim)
+	}
+	return &ShimCustomValidator{Client: builder.Build()}
+}
+
+func TestShimCustomValidator(t *testing.T) {
+	ctx := context.Background()
+	existing := makeShim("spin", "wasmtime-spin-v2")
+
+	t.Run("accepts a valid shim", func(t *testing.T) {
+		validator := newValidator(t, existing)
+		if _, err := validator.ValidateCreate(ctx, makeShim("lunatic", "lunatic-v1")); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("accepts updates of the shim claiming the runtime class", func(t *testing.T) {
+		validator := newValidator(t, existing)
+		updated := existing.DeepCopy()
+		updated.Spec.ContainerdRuntimeOptions = map[string]string{"SystemdCgroup": "true"}
+		if _, err := validator.ValidateUpdate(ctx, existing, updated); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("rejects a shim claiming a runtime class of another shim", func(t *testing.T) {
+		validator := newValidator(t, existing)
+		_, err := validator.ValidateCreate(ctx, makeShim("spin-copy", "wasmtime-spin-v2"))
+		if !apierrors.IsInvalid(err) {
+			t.Errorf("error = %v, want an Invalid error", err)
+		}
+	})
+
+	t.Run("rejects an invalid shim", func(t *testing.T) {
+		validator := newValidator(t)
+		shim := makeShim("spin", "wasmtime-spin-v2")
+		shim.Spec.FetchStrategy.AnonHTTP = nil
+		_, err := validator.ValidateCreate(ctx, shim)
+		if !apierrors.IsInvalid(err) {
+			t.Errorf("error = %v, want an Invalid error", err)
+		}
+	})
+
+	t.Run("allows finalizer removal on deleted shims", func(t *testing.T) {
+		validator := newValidator(t)
+		shim := makeShim("spin", "Invalid_Name")
+		now := metav1.Now()
+		shim.DeletionTimestamp = &now
+		if _, err := validator.ValidateUpdate(ctx, shim, shim); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("allows metadata updates of invalid shims", func(t *testing.T) {
+		validator := newValidator(t)
+		shim := makeShim("spin", "Invalid_Name")
+		updated := shim.DeepCopy()
+		updated.Finalizers = []string{"rcm.spinkube.dev/finalizer"}
+		updated.Annotations = map[string]string{"rcm.spinkube.dev/force-delete": "true"}
+		if _, err := validator.ValidateUpdate(ctx, shim, updated); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("rejects spec updates of invalid shims", func(t *testing.T) {
+		validator := newValidator(t)
+		shim := makeShim("spin", "Invalid_Name")
+		updated := shim.DeepCopy()
+		updated.Spec.ContainerdRuntimeOptions = map[string]string{"SystemdCgroup": "true"}
+		_, err := validator.ValidateUpdate(ctx, shim, updated)
+		if !apierrors.IsInvalid(err) {
+			t.Errorf("error = %v, want an Invalid error", err)
+		}
+	})
+}